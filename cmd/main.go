@@ -2,13 +2,23 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
 
 	"task-herald/internal/app"
+	"task-herald/internal/config"
+	"task-herald/internal/hook"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "hook" {
+		os.Exit(runHook(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:]))
+	}
+
 	cfgPath := flag.String("config", "", "Path to config.yaml (overrides env/ defaults)")
 	flag.Parse()
 
@@ -17,3 +27,90 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// runHook implements `task-herald hook on-add|on-modify` (run by Taskwarrior)
+// and `task-herald hook install`
+func runHook(args []string) int {
+	fs := flag.NewFlagSet("hook", flag.ExitOnError)
+	socket := fs.String("socket", "", "Unix socket of the running daemon (default $TASK_HERALD_SOCKET or "+hook.DefaultSocketPath()+")")
+	hooksDir := fs.String("hooks-dir", hook.DefaultHooksDir(), "Taskwarrior hooks directory (install only)")
+	force := fs.Bool("force", false, "Replace existing hook files (install only)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: task-herald hook [flags] on-add|on-modify|install")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *socket == "" {
+		*socket = os.Getenv("TASK_HERALD_SOCKET")
+	}
+	if *socket == "" {
+		*socket = hook.DefaultSocketPath()
+	}
+	// Taskwarrior appends its own arguments (api:2 args:... rc:...) after the event
+	switch event := fs.Arg(0); event {
+	case "install":
+		binary, err := os.Executable()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "task-herald:", err)
+			return 1
+		}
+		paths, err := hook.Install(*hooksDir, binary, *socket, *force)
+		for _, p := range paths {
+			fmt.Println("Installed", p)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "task-herald:", err)
+			return 1
+		}
+		return 0
+	case hook.EventAdd, hook.EventModify:
+		if err := hook.Run(event, os.Stdin, os.Stdout, *socket); err != nil {
+			// Taskwarrior shows hook output as feedback and rejects the change
+			fmt.Println("task-herald:", err)
+			return 1
+		}
+		return 0
+	default:
+		fs.Usage()
+		return 2
+	}
+}
+
+// runConfig implements `task-herald config check`, which prints every problem
+// in the config file with its line number
+func runConfig(args []string) int {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	cfgPath := fs.String("config", "", "Path to config.yaml (overrides env/ defaults)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: task-herald config [flags] check")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.Arg(0) != "check" {
+		fs.Usage()
+		return 2
+	}
+	// Flags may also follow the command
+	fs.Parse(fs.Args()[1:])
+	// Only warnings from building the config are of interest here
+	config.SetLogLevelFromConfig(&config.Config{LogLevel: "warn"})
+	path, problems, err := app.CheckConfig(*cfgPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "task-herald:", err)
+		return 1
+	}
+	for _, p := range problems {
+		if line := p.Line; line > 0 {
+			p.Line = 0
+			fmt.Printf("%s:%d: %s\n", path, line, p)
+		} else {
+			fmt.Printf("%s: %s\n", path, p)
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d problem(s) found\n", len(problems))
+		return 1
+	}
+	fmt.Printf("%s: OK\n", path)
+	return 0
+}
@@ -225,6 +225,7 @@
               };
               Service = {
                 ExecStart = "${taskHeraldPkg}/bin/task-herald --config %h/${config.services.task-herald.configFile}";
+                ExecReload = "${pkgs.coreutils}/bin/kill -HUP $MAINPID";
                 Restart = "always";
                 WorkingDirectory = "%h/.local/state/task-herald";
                 StateDirectory = "task-herald";
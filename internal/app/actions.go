@@ -0,0 +1,186 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+const (
+	// actionLinkTTL is how long signed action links in a notification stay valid
+	actionLinkTTL = 7 * 24 * time.Hour
+	// defaultSnoozeDelay is used by the Snooze action when ntfy.snooze_delay is unset
+	defaultSnoozeDelay = "15m"
+	// actionSecretFileName is the generated signing key kept in the state dir
+	actionSecretFileName = "action_secret"
+)
+
+// completeTaskFunc is overridable for testing
+var completeTaskFunc = taskwarrior.CompleteTask
+
+// ntfyAction is a single ntfy action button (see https://docs.ntfy.sh/publish/#action-buttons)
+type ntfyAction struct {
+	Action string `json:"action"`
+	Label  string `json:"label"`
+	URL    string `json:"url"`
+	Method string `json:"method,omitempty"`
+	Clear  bool   `json:"clear,omitempty"`
+}
+
+// actionBaseURL returns the public base URL of this server built from
+// http.domain, or "" if no domain is configured
+func actionBaseURL(cfg *config.Config) string {
+	if cfg == nil || cfg.HTTP.Domain == "" {
+		return ""
+	}
+	domain := strings.TrimRight(cfg.HTTP.Domain, "/")
+	if strings.Contains(domain, "://") {
+		return domain
+	}
+	scheme := "http"
+	if c, k := resolveTLSPaths(cfg); c != "" && k != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + domain
+}
+
+// resolveActionSecret returns the key used to sign action links. Prefer
+// http.action_secret(_file); otherwise derive it from the auth token, else
+// use a key generated once and kept in the state dir. Only if that fails, or
+// the state is kept in memory, is a random key used (links then stop working
+// after a restart).
+func resolveActionSecret(cfg *config.Config) []byte {
+	if cfg != nil {
+		if cfg.HTTP.ActionSecret != "" {
+			return []byte(cfg.HTTP.ActionSecret)
+		}
+		if cfg.HTTP.ActionSecretFile != "" {
+			if b, err := os.ReadFile(cfg.HTTP.ActionSecretFile); err == nil {
+				if s := strings.TrimSpace(string(b)); s != "" {
+					return []byte(s)
+				}
+			} else {
+				httpLog.Error("Failed to read action secret file", "error", err)
+			}
+		}
+		if token, err := resolveHTTPAuthToken(cfg); err == nil && token != "" {
+			sum := sha256.Sum256([]byte("task-herald actions:" + token))
+			return sum[:]
+		}
+		if cfg.State.Backend != "memory" {
+			secret, err := stateActionSecret(resolveStateDir(cfg))
+			if err == nil {
+				return secret
+			}
+			httpLog.Error("Failed to keep the action secret in the state dir; action links stop working after a restart", "error", err)
+		}
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		httpLog.Error("Failed to generate action secret", "error", err)
+		return nil
+	}
+	return secret
+}
+
+// stateActionSecret reads the signing key from the state dir, generating and
+// saving it on first use
+func stateActionSecret(dir string) ([]byte, error) {
+	if dir == "" {
+		return nil, errors.New("no state dir")
+	}
+	path := filepath.Join(dir, actionSecretFileName)
+	b, err := os.ReadFile(path)
+	if err == nil {
+		if s := strings.TrimSpace(string(b)); s != "" {
+			return []byte(s), nil
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	secret := hex.EncodeToString(raw)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0o600); err != nil {
+		return nil, err
+	}
+	httpLog.Info("Generated action secret", "path", path)
+	return []byte(secret), nil
+}
+
+// buildActions returns the ntfy X-Actions header value with Open, Done and
+// Snooze buttons for a task, or "" if actions cannot be built. ntfy allows
+// three buttons, so Acknowledge is on the page Open shows.
+func buildActions(cfg *config.Config, secret []byte, uuid string, now time.Time) string {
+	base := actionBaseURL(cfg)
+	if base == "" || len(secret) == 0 {
+		return ""
+	}
+	snooze := cfg.Ntfy.SnoozeDelay
+	if snooze == "" {
+		snooze = defaultSnoozeDelay
+	}
+	exp := now.Add(actionLinkTTL)
+	actions := []ntfyAction{
+		{Action: "view", Label: "Open", URL: web.ActionURL(base, secret, web.ActionView, uuid, "", exp)},
+		{Action: "http", Label: "Done", URL: web.ActionURL(base, secret, web.ActionComplete, uuid, "", exp), Method: "POST", Clear: true},
+		{Action: "http", Label: "Snooze " + snooze, URL: web.ActionURL(base, secret, web.ActionSnooze, uuid, snooze, exp), Method: "POST", Clear: true},
+	}
+	b, err := json.Marshal(actions)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// viewTask implements web.ViewTaskFunc with the task from the snapshot
+func (s *scheduler) viewTask(uuid string) (web.TaskView, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	task, ok := s.task(uuid)
+	if !ok {
+		return web.TaskView{}, false
+	}
+	v := web.TaskView{
+		UUID:        task.UUID,
+		Description: task.Description,
+		Project:     task.Project,
+		Priority:    task.Priority,
+		Status:      task.Status,
+		Tags:        task.Tags,
+	}
+	if t := parseTaskDate(task.Due); t != nil {
+		v.Due = t.Format("2006-01-02 15:04 MST")
+	}
+	if at, _ := nextNotification(task); !at.IsZero() {
+		v.NotificationDate = at.In(util.Location()).Format("2006-01-02 15:04 MST")
+	}
+	for _, a := range task.Annotations {
+		v.Annotations = append(v.Annotations, a.Description)
+	}
+	return v, true
+}
+
+// completeTask implements web.CompleteTaskFunc: it acknowledges any pending
+// repeats and marks the task done in Taskwarrior
+func (s *scheduler) completeTask(uuid string) error {
+	// acknowledging an unknown task is not an error when completing it
+	_ = s.acknowledge(uuid, "")
+	return completeTaskFunc(uuid)
+}
@@ -0,0 +1,166 @@
+package app
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+func TestActionBaseURL(t *testing.T) {
+	cases := []struct {
+		domain string
+		want   string
+	}{
+		{"", ""},
+		{"herald.example.com", "http://herald.example.com"},
+		{"https://herald.example.com/", "https://herald.example.com"},
+	}
+	for _, tc := range cases {
+		cfg := &config.Config{HTTP: config.HTTPConfig{Domain: tc.domain}}
+		if got := actionBaseURL(cfg); got != tc.want {
+			t.Errorf("actionBaseURL(%q) = %q, want %q", tc.domain, got, tc.want)
+		}
+	}
+}
+
+func TestResolveActionSecret(t *testing.T) {
+	cfg := &config.Config{HTTP: config.HTTPConfig{ActionSecret: "explicit"}}
+	if string(resolveActionSecret(cfg)) != "explicit" {
+		t.Fatalf("expected explicit secret")
+	}
+	// derived from the token but never the token itself
+	cfg = &config.Config{HTTP: config.HTTPConfig{AuthToken: "tok"}}
+	a, b := resolveActionSecret(cfg), resolveActionSecret(cfg)
+	if string(a) != string(b) || strings.Contains(string(a), "tok") {
+		t.Fatalf("expected stable secret derived from token")
+	}
+	// generated once and kept in the state dir
+	cfg = &config.Config{State: config.StateConfig{Dir: t.TempDir()}}
+	a = resolveActionSecret(cfg)
+	if len(a) == 0 || string(resolveActionSecret(cfg)) != string(a) {
+		t.Fatalf("expected the generated secret to be reused")
+	}
+	info, err := os.Stat(filepath.Join(cfg.State.Dir, actionSecretFileName))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected a private action secret file: %v %v", info, err)
+	}
+	cfg = &config.Config{State: config.StateConfig{Backend: "memory"}}
+	if a, b := resolveActionSecret(cfg), resolveActionSecret(cfg); len(a) == 0 || string(a) == string(b) {
+		t.Fatalf("expected a random secret per call with in-memory state")
+	}
+}
+
+func TestBuildActions(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{Domain: "herald.example.com", AuthToken: "bearer-token"},
+		Ntfy: config.NtfyConfig{ActionsEnabled: true, SnoozeDelay: "1h"},
+	}
+	raw := buildActions(cfg, []byte("k"), "u1", time.Now())
+	var actions []ntfyAction
+	if err := json.Unmarshal([]byte(raw), &actions); err != nil {
+		t.Fatalf("invalid X-Actions JSON %q: %v", raw, err)
+	}
+	if len(actions) != 3 {
+		t.Fatalf("expected 3 actions, got %d", len(actions))
+	}
+	wantPaths := []string{"/api/action/view?", "/api/action/complete?", "/api/action/snooze?"}
+	for i, a := range actions {
+		if i == 0 && (a.Action != "view" || a.Label != "Open" || a.Method != "") {
+			t.Errorf("unexpected view action %+v", a)
+		}
+		if i > 0 && (a.Action != "http" || a.Method != "POST") {
+			t.Errorf("unexpected action %+v", a)
+		}
+		if !strings.HasPrefix(a.URL, "http://herald.example.com"+wantPaths[i]) {
+			t.Errorf("unexpected url %q", a.URL)
+		}
+		if strings.Contains(a.URL, "bearer-token") {
+			t.Errorf("action url leaks bearer token: %q", a.URL)
+		}
+	}
+	if !strings.Contains(actions[2].URL, "delay=1h") || actions[2].Label != "Snooze 1h" {
+		t.Errorf("unexpected snooze action %+v", actions[2])
+	}
+
+	if buildActions(&config.Config{}, []byte("k"), "u1", time.Now()) != "" {
+		t.Errorf("expected no actions without a domain")
+	}
+}
+
+func TestScheduler_SendAddsActions(t *testing.T) {
+	cfg := &config.Config{
+		HTTP: config.HTTPConfig{Domain: "herald.example.com"},
+		Ntfy: config.NtfyConfig{ActionsEnabled: true},
+	}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+	s.actionSecret = []byte("k")
+
+	if err := s.send(taskwarrior.Task{UUID: "u1", Description: "d"}, time.Now()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if !strings.Contains(fn.lastHeader["X-Actions"], "/api/action/complete") {
+		t.Fatalf("expected generated X-Actions header, got %q", fn.lastHeader["X-Actions"])
+	}
+
+	// an explicitly configured X-Actions header wins
+	cfg.Ntfy.Headers = map[string]string{"X-Actions": "view, Open, https://example.com"}
+	if err := s.send(taskwarrior.Task{UUID: "u1", Description: "d"}, time.Now()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if fn.lastHeader["X-Actions"] != "view, Open, https://example.com" {
+		t.Fatalf("expected configured X-Actions header, got %q", fn.lastHeader["X-Actions"])
+	}
+}
+
+func TestScheduler_CompleteTask(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	s := newScheduler(cfg, &fakeNotifier{})
+
+	orig := completeTaskFunc
+	defer func() { completeTaskFunc = orig }()
+	var got string
+	completeTaskFunc = func(uuid string) error {
+		got = uuid
+		return nil
+	}
+	if err := s.completeTask("u9"); err != nil {
+		t.Fatalf("completeTask: %v", err)
+	}
+	if got != "u9" {
+		t.Fatalf("expected task u9 completed, got %q", got)
+	}
+}
+
+func TestScheduler_ViewTask(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	s := newScheduler(cfg, &fakeNotifier{})
+	s.setTasks([]taskwarrior.Task{{
+		UUID: "u1", Description: "water plants", Project: "home", Status: "pending",
+		Due: "20250904T090000Z", NotificationDate: "20250904T083000Z",
+		Annotations: []taskwarrior.Annotation{{Description: "balcony too"}},
+	}})
+
+	v, ok := s.viewTask("u1")
+	if !ok {
+		t.Fatal("expected u1 to be found")
+	}
+	if v.Description != "water plants" || v.Due != "2025-09-04 09:00 UTC" || v.NotificationDate != "2025-09-04 08:30 UTC" || len(v.Annotations) != 1 {
+		t.Errorf("unexpected view %+v", v)
+	}
+	if _, ok := s.viewTask("u2"); ok {
+		t.Error("expected unknown task not to be found")
+	}
+}
@@ -0,0 +1,40 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+// addTaskFunc is overridable for testing
+var addTaskFunc = taskwarrior.AddTask
+
+// createTask implements web.CreateTaskFunc by adding the task to Taskwarrior.
+// The notification date may be any date util.ParseDate accepts, e.g.
+// "tomorrow 09:00"; it is passed to Taskwarrior in UTC. A task created with
+// failed annotations is reported as web.ErrPartiallyCreated.
+func createTask(req web.CreateTaskRequest) (string, error) {
+	nt := taskwarrior.NewTask{
+		Description: req.Description,
+		Project:     req.Project,
+		Tags:        req.Tags,
+		Annotations: req.Annotations,
+	}
+	if req.NotificationDate != "" {
+		at, err := util.ParseDate(req.NotificationDate, time.Now(), util.Location())
+		if err != nil {
+			return "", fmt.Errorf("%w: notification_date: %v", web.ErrBadRequest, err)
+		}
+		nt.UDAs = map[string]string{udaName(config.Get(), "notification_date"): at.UTC().Format("20060102T150405Z")}
+	}
+	uuid, err := addTaskFunc(nt)
+	if errors.Is(err, taskwarrior.ErrAnnotate) && uuid != "" {
+		return uuid, fmt.Errorf("%w: %v", web.ErrPartiallyCreated, err)
+	}
+	return uuid, err
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+func TestCreateTask_MapsRequest(t *testing.T) {
+	config.Set(&config.Config{UDAMap: config.UDAMap{NotificationDate: "remind_at"}})
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+
+	orig := addTaskFunc
+	defer func() { addTaskFunc = orig }()
+	var got taskwarrior.NewTask
+	addTaskFunc = func(nt taskwarrior.NewTask) (string, error) {
+		got = nt
+		return "uuid-1", nil
+	}
+
+	uuid, err := createTask(web.CreateTaskRequest{
+		Description:      "buy milk",
+		Project:          "home",
+		Tags:             []string{"errand"},
+		Annotations:      []string{"2 litres"},
+		NotificationDate: "2025-08-31T14:30:00",
+	})
+	if err != nil {
+		t.Fatalf("createTask: %v", err)
+	}
+	if uuid != "uuid-1" {
+		t.Fatalf("unexpected uuid: %q", uuid)
+	}
+	if got.Description != "buy milk" || got.Project != "home" || len(got.Tags) != 1 || len(got.Annotations) != 1 {
+		t.Fatalf("unexpected task: %+v", got)
+	}
+	if got.UDAs["remind_at"] != "20250831T143000Z" {
+		t.Fatalf("expected notification date under configured UDA, got %v", got.UDAs)
+	}
+}
+
+func TestCreateTask_NoNotificationDate(t *testing.T) {
+	config.Set(&config.Config{})
+
+	orig := addTaskFunc
+	defer func() { addTaskFunc = orig }()
+	var got taskwarrior.NewTask
+	addTaskFunc = func(nt taskwarrior.NewTask) (string, error) {
+		got = nt
+		return "uuid-2", nil
+	}
+
+	if _, err := createTask(web.CreateTaskRequest{Description: "x"}); err != nil {
+		t.Fatalf("createTask: %v", err)
+	}
+	if len(got.UDAs) != 0 {
+		t.Fatalf("expected no UDAs, got %v", got.UDAs)
+	}
+}
+
+func TestCreateTask_NaturalLanguageDate(t *testing.T) {
+	config.Set(&config.Config{})
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+
+	orig := addTaskFunc
+	defer func() { addTaskFunc = orig }()
+	var got taskwarrior.NewTask
+	addTaskFunc = func(nt taskwarrior.NewTask) (string, error) {
+		got = nt
+		return "uuid-3", nil
+	}
+
+	before := time.Now()
+	if _, err := createTask(web.CreateTaskRequest{Description: "x", NotificationDate: "now+2h"}); err != nil {
+		t.Fatalf("createTask: %v", err)
+	}
+	at, err := time.Parse("20060102T150405Z", got.UDAs["notification_date"])
+	if err != nil {
+		t.Fatalf("expected a UTC notification date, got %v", got.UDAs)
+	}
+	if at.Before(before.Add(2*time.Hour).Add(-time.Second)) || at.After(time.Now().Add(2*time.Hour)) {
+		t.Fatalf("notification date %v not 2h from now", at)
+	}
+
+	got = taskwarrior.NewTask{}
+	_, err = createTask(web.CreateTaskRequest{Description: "x", NotificationDate: "someday soon"})
+	if !errors.Is(err, web.ErrBadRequest) {
+		t.Fatalf("expected bad request for an invalid date, got %v", err)
+	}
+	if got.Description != "" {
+		t.Fatal("task was created despite an invalid date")
+	}
+}
+
+func TestCreateTask_AnnotationFailure(t *testing.T) {
+	config.Set(&config.Config{})
+	orig := addTaskFunc
+	defer func() { addTaskFunc = orig }()
+	addTaskFunc = func(nt taskwarrior.NewTask) (string, error) {
+		return "uuid-4", fmt.Errorf("%w: 1 of 1 annotations not added", taskwarrior.ErrAnnotate)
+	}
+
+	uuid, err := createTask(web.CreateTaskRequest{Description: "x", Annotations: []string{"a"}})
+	if uuid != "uuid-4" || !errors.Is(err, web.ErrPartiallyCreated) {
+		t.Fatalf("expected uuid-4 reported as partially created, got %q %v", uuid, err)
+	}
+}
@@ -6,36 +6,66 @@ import (
 	"net"
 	"net/http"
 	"os"
-	"reflect"
+	"slices"
 	"strings"
-	"sync"
-	"text/template"
-	"net/url"
+	"syscall"
 	"task-herald/internal/config"
+	"task-herald/internal/hook"
 	"task-herald/internal/notify"
+	"task-herald/internal/state"
 	"task-herald/internal/taskwarrior"
 	"task-herald/internal/util"
 	"task-herald/internal/web"
 	"time"
 )
 
+// Loggers of the components that log from this package
+var (
+	appLog    = config.Logger(config.LogApp)
+	notifyLog = config.Logger(config.LogNotify)
+	pollLog   = config.Logger(config.LogPoller)
+	hookLog   = config.Logger(config.LogHook)
+	httpLog   = config.Logger(config.LogHTTP)
+)
+
 // Overridable hooks for testing
 var (
 	syncOnceFunc = func() { taskwarrior.SyncOnce() }
-	pollerFunc   = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
-		taskwarrior.Poller(interval, out, stop)
+	pollerFunc   = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+		taskwarrior.Poller(src, interval, out, stop)
 	}
 	syncTaskwarriorFunc = func(stop <-chan struct{}) { taskwarrior.SyncTaskwarrior(stop) }
-	runSigCh            = func() <-chan struct{} { return make(chan struct{}) }
+	// runSigCh fires on SIGINT or SIGTERM, reloadSigCh on SIGHUP
+	runSigCh        = func() <-chan struct{} { return notifySignals(syscall.SIGINT, syscall.SIGTERM) }
+	reloadSigCh     = func() <-chan struct{} { return notifySignals(syscall.SIGHUP) }
+	watchConfigFunc = watchConfig
 )
 
 // Additional overridable hooks for testing
 var (
-	loadConfigFunc = config.LoadConfig
+	loadConfigFunc  = config.LoadConfig
 	newNotifierFunc = func(cfg config.NtfyConfig, logger func(format string, v ...interface{})) typeNotifier {
 		return notify.NewNotifier(cfg, logger)
 	}
-	notifySleepDuration = 5 * time.Second
+	// newBackendFunc builds the default notifier backend selected by cfg.Notifier
+	newBackendFunc = func(cfg *config.Config, logger func(format string, v ...interface{})) (typeNotifier, error) {
+		if cfg.Notifier == "" || cfg.Notifier == "ntfy" {
+			return newNotifierFunc(cfg.Ntfy, logger), nil
+		}
+		return notify.New(cfg.Notifier, cfg, logger)
+	}
+	// notifySleepDuration is the longest the scheduler sleeps between checks;
+	// it normally wakes earlier, exactly when the next notification is due
+	notifySleepDuration = time.Minute
+	listenHookFunc      = hook.Listen
+	// taskDataLocationFunc reports where the task command reads its data
+	taskDataLocationFunc = taskwarrior.DataLocation
+	newTaskSourceFunc    = func(cfg *config.Config) (taskwarrior.TaskSource, error) {
+		return taskwarrior.NewSource(cfg.Source, dateUDAs(cfg))
+	}
+	openStateStoreFunc = func(cfg *config.Config) (state.Store, error) {
+		return state.Open(cfg.State.Backend, resolveStateDir(cfg))
+	}
 	// Hook to start HTTP server; returns a shutdown function, the address started on, and an error
 	startHTTPServerFunc = func(handler http.Handler) (func() error, string, error) {
 		cfg := config.Get()
@@ -79,6 +109,7 @@ var (
 		if token != "" {
 			handler = web.AuthMiddleware(handler, token)
 		}
+		handler = web.AccessLog(handler, httpLog)
 		// listen on given address (support :0)
 		ln, err := net.Listen("tcp", addr)
 		if err != nil {
@@ -101,296 +132,264 @@ type typeNotifier interface {
 	Send(ctx context.Context, message string, headers map[string]string) error
 }
 
-func Run(configOverride string) error {
-	config.Log(config.INFO, "Taskwarrior Notifications service starting...")
-
-	// Precedence: CLI override -> TASK_HERALD_CONFIG env -> ./config.yaml -> /var/lib/task-herald/config.yaml
-	cfgPath := configOverride
-	if cfgPath == "" {
-		cfgPath = os.Getenv("TASK_HERALD_CONFIG")
-	}
-	if cfgPath == "" {
-		if _, err := os.Stat("./config.yaml"); err == nil {
-			cfgPath = "./config.yaml"
-		} else {
-			cfgPath = "/var/lib/task-herald/config.yaml"
-		}
-	}
+// backendFunc builds the notifier backend selected by cfg.Notifier, see
+// newBackendFunc and checkBackend
+type backendFunc func(cfg *config.Config, logger func(format string, v ...interface{})) (typeNotifier, error)
 
+func Run(configOverride string) error {
+	cfgPath := configPath(configOverride)
 	cfg, err := loadConfigFunc(cfgPath)
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 	config.Set(cfg)
+	if err := config.ConfigureLogging(cfg); err != nil {
+		return fmt.Errorf("failed to configure logging: %w", err)
+	}
+	appLog.Info("Taskwarrior Notifications service starting")
 
 	// DEBUG: Log parsed config struct
-	config.Log(config.DEBUG, "Loaded config: %+v", *cfg)
+	appLog.Debug("Loaded config", "config", fmt.Sprintf("%+v", *cfg))
 
 	// DEBUG: Log relevant environment variables
-	config.Log(config.DEBUG, "TASK_HERALD_CONFIG env: %s", os.Getenv("TASK_HERALD_CONFIG"))
+	appLog.Debug("TASK_HERALD_CONFIG env", "value", os.Getenv("TASK_HERALD_CONFIG"))
 
 	// INFO: Log config.yaml location
-	config.Log(config.INFO, "Loaded config.yaml from: %s", cfgPath)
+	appLog.Info("Loaded config", "path", cfgPath)
 
-	// INFO: Log ntfy.sh server and endpoint
-	config.Log(config.INFO, "ntfy.sh server: %s, topic: %s", cfg.Ntfy.URL, cfg.Ntfy.GetTopic())
+	// INFO: Log notifier backend (and ntfy.sh server and endpoint)
+	if cfg.Notifier == "" || cfg.Notifier == "ntfy" {
+		appLog.Info("Notifier backend", "backend", "ntfy", "url", cfg.Ntfy.URL, "topic", cfg.Ntfy.GetTopic())
+	} else {
+		appLog.Info("Notifier backend", "backend", cfg.Notifier)
+	}
 
-	// INFO: Log Taskwarrior config/data location (hardcoded for now, could be improved)
-	twConfigLoc := "/home/justin/.local/share/task"
-	config.Log(config.INFO, "Taskwarrior data/config location: %s", twConfigLoc)
+	comp, err := buildComponents(cfg)
+	if err != nil {
+		return err
+	}
+	util.SetLocation(comp.loc)
+	appLog.Info("Timezone", "timezone", comp.loc.String())
+	comp.logSummary()
 
-	// Shared state for polled tasks
-	var (
-		mu               sync.RWMutex
-		tasks            []taskwarrior.Task
-		notified         = make(map[string]struct{}) // Key: UUID|notification_date
-		lastNotifiedDate = make(map[string]string)   // Key: UUID, Value: last seen notification_date
-	)
+	sched := newScheduler(cfg, comp.notifier)
+	sched.reconfigure(comp)
+	sched.actionSecret = resolveActionSecret(cfg)
+	store, err := openStateStoreFunc(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to open state store: %w", err)
+	}
+	if err := sched.useStore(store); err != nil {
+		return fmt.Errorf("failed to load notification state: %w", err)
+	}
+	if fs, ok := store.(*state.FileStore); ok {
+		appLog.Info("Notification state file", "path", fs.Path())
+	} else {
+		appLog.Warn("Notification state is kept in memory only and is lost on restart (state.backend is memory)")
+	}
+	if cfg.Ntfy.ActionsEnabled && actionBaseURL(cfg) == "" {
+		appLog.Warn("ntfy.actions_enabled is set but http.domain is empty; notifications will have no action buttons")
+	}
 
-	// Set log level from config
-	config.SetLogLevelFromConfig(cfg)
+	switch src := comp.baseSource.(type) {
+	case taskwarrior.FileSource, taskwarrior.DirSource:
+		appLog.Info("Reading tasks from a snapshot; task sync disabled", "source", cfg.Source.Type, "path", cfg.Source.Path)
+	case *taskwarrior.TaskChampionSource:
+		appLog.Info("Reading tasks from TaskChampion database", "path", src.Path)
+	case taskwarrior.CLISource:
+		appLog.Info("Taskwarrior data location", "path", taskDataLocationFunc())
+		if cfg.Taskwarrior.TaskRC != "" {
+			appLog.Info("Taskwarrior config", "taskrc", cfg.Taskwarrior.TaskRC)
+		}
+		if cfg.Taskwarrior.Filter != "" {
+			appLog.Info("Exporting tasks", "filter", cfg.Taskwarrior.Filter)
+		}
+	}
+	if comp.sync {
+		// Run 'task sync' immediately at startup
+		// Use overridable functions to allow tests to mock behavior
+		syncOnceFunc()
+	}
 
-	// Set up polling and syncing
+	// Start polling, syncing and digests; a reload restarts them
 	taskCh := make(chan []taskwarrior.Task)
-	stopCh := make(chan struct{})
-	// Run 'task sync' immediately at startup
-	// Use overridable functions to allow tests to mock behavior
-	syncOnceFunc()
-	go pollerFunc(cfg.PollInterval, taskCh, stopCh)
-	go syncTaskwarriorFunc(stopCh)
+	r := &reloader{path: cfgPath, sched: sched, taskCh: taskCh, current: comp}
+	r.stop = comp.start(sched, taskCh)
 
 	// Update tasks on poll
 	go func() {
 		for t := range taskCh {
-			mu.Lock()
-			tasks = t
-
-			// INFO: Log total number of available tasks
-			totalTasks := len(t)
-			config.Log(config.INFO, "Total available tasks from taskwarrior: %d", totalTasks)
-
-			// INFO: Log all tasks with a future notification_date
-			futureTasks := 0
-			for _, task := range t {
-				if task.NotificationDate == "" {
-					continue
-				}
-				prev := lastNotifiedDate[task.UUID]
-				if prev != task.NotificationDate {
-					lastNotifiedDate[task.UUID] = task.NotificationDate
-				}
-				notifyAt, err := util.ParseNotificationDate(task.NotificationDate)
-				if err == nil && notifyAt.After(time.Now()) {
-					config.Log(config.INFO, "Task with future notification_date: UUID=%s, ID=%d, Desc=\"%s\", Date=%s", task.UUID, task.ID, task.Description, notifyAt.Format("2006-01-02 15:04:05 MST"))
-					futureTasks++
-				}
-			}
-			config.Log(config.INFO, "Total tasks with future notification_date: %d", futureTasks)
-
-			// VERBOSE: Log all tasks returned by task export
-			if config.ParseLogLevel(cfg.LogLevel) >= config.VERBOSE {
-				for _, task := range t {
-					config.Log(config.VERBOSE, "VERBOSE: Task: %+v", task)
-				}
-			}
-
-			// DEBUG: Log state of internal maps
-			config.Log(config.DEBUG, "DEBUG: notified map: %+v", notified)
-			config.Log(config.DEBUG, "DEBUG: lastNotifiedDate map: %+v", lastNotifiedDate)
-
-			mu.Unlock()
+			sched.setTasks(t)
 		}
 	}()
 
-	// Notification scheduler (ntfy-based)
-	go func() {
-		// Use a logger function that wraps config.Log at INFO level
-		loggerFunc := func(format string, v ...interface{}) {
-			config.Log(config.INFO, format, v...)
-		}
-		notifier := newNotifierFunc(cfg.Ntfy, loggerFunc)
-		for {
-			time.Sleep(notifySleepDuration)
-			mu.RLock()
-			now := time.Now()
-			for _, task := range tasks {
-				// Skip if already acknowledged (web interface removed)
-				ndates := []string{task.NotificationDate}
-				if v, ok := getUDA(task, "taskherald.notification_date"); ok && v != "" {
-					ndates = append(ndates, v)
-				}
-				var notifyAt time.Time
-				var notifyDateStr string
-				for _, nd := range ndates {
-					if nd == "" {
-						continue
-					}
-					t, err := util.ParseNotificationDate(nd)
-					if err == nil && (notifyAt.IsZero() || t.Before(notifyAt)) {
-						notifyAt = t
-						notifyDateStr = nd
-					}
-				}
-				if notifyAt.IsZero() {
-					continue
-				}
-				// Only process notifications that are due right now (within the last 5 minutes)
-				nowLocal := now.In(time.Local)
-				notifyLocal := notifyAt.In(time.Local)
+	// Notification scheduler
+	go sched.run()
 
-				// Skip if notification time is in the future
-				if notifyLocal.After(nowLocal) {
-					continue
-				}
-
-				// Only process if notification is due within the last 5 minutes (to catch notifications that were missed)
-				fiveMinutesAgo := nowLocal.Add(-5 * time.Minute)
-				if notifyLocal.Before(fiveMinutesAgo) {
-					continue
-				}
-				// Use UUID|notification_date as the key
-				notifyKey := fmt.Sprintf("%s|%s", task.UUID, notifyDateStr)
-				if _, already := notified[notifyKey]; already {
-					continue
-				}
-				// Log the notification time in both UTC and local
-				config.Log(config.INFO, "[notify] Task %s will be notified at local: %s (UTC: %s)", task.UUID, notifyAt.In(time.Local).Format("2006-01-02 15:04:05 MST"), notifyAt.UTC().Format("2006-01-02 15:04:05 UTC"))
-				// Prepare message
-				msgTmpl := cfg.NotificationMessage
-				info := notify.TaskInfo{
-					ID:               fmt.Sprintf("%d", task.ID),
-					UUID:             task.UUID,
-					Description:      task.Description,
-					Tags:             task.Tags,
-					Project:          task.Project,
-					Priority:         task.Priority,
-					NotificationDate: &notifyAt,
-				}
-				msg, err := notify.RenderMessage(info, msgTmpl)
-				if err != nil {
-					msg = fmt.Sprintf("Task %d: %s", task.ID, task.Description)
-				}
-				// Prepare dynamic headers (e.g., X-Title, X-Click, X-Actions)
-				headers := map[string]string{}
-				// Render configured headers as templates against TaskInfo (allow urlquery func)
-				funcMap := template.FuncMap{"urlquery": url.QueryEscape}
-				for k, v := range cfg.Ntfy.Headers {
-					// try to render template; if fails, fall back to raw value
-					t, err := template.New(k).Funcs(funcMap).Parse(v)
-					if err != nil {
-						headers[k] = v
-						continue
-					}
-					var buf strings.Builder
-					if err := t.Execute(&buf, info); err != nil {
-						headers[k] = v
-						continue
-					}
-					headers[k] = buf.String()
-				}
-				// Set X-Title to project
-				if task.Project != "" {
-					headers["X-Title"] = task.Project
-				}
-				// Map Taskwarrior priority to ntfy priority for X-Default
-				var ntfyPriority string
-				switch task.Priority {
-				case "H", "h":
-					ntfyPriority = "max"
-				case "M", "m":
-					ntfyPriority = "high"
-				case "L", "l":
-					ntfyPriority = "default"
-				default:
-					ntfyPriority = "default"
-				}
-				headers["X-Default"] = ntfyPriority
-				// Send notification
-				err = notifier.Send(context.Background(), msg, headers)
-				nowLocalMsg := time.Now().In(time.Local)
-				if err == nil {
-					notified[notifyKey] = struct{}{}
-					config.Log(config.INFO, "[notify] Notification sent for task %s at %s", task.UUID, nowLocalMsg.Format("2006-01-02 15:04:05 MST"))
-				} else if err != nil {
-					config.Log(config.ERROR, "[notify] Failed to send notification for task %s: %v", task.UUID, err)
-				}
-			}
-			mu.RUnlock()
+	// Apply task changes forwarded by the Taskwarrior hook immediately
+	closeHook := func() error { return nil }
+	if cfg.Hooks.Enabled {
+		socket := cfg.Hooks.Socket
+		if socket == "" {
+			socket = hook.DefaultSocketPath()
 		}
-	}()
+		if fn, err := listenHookFunc(socket, sched.upsertTask); err != nil {
+			hookLog.Error("Failed to listen for Taskwarrior hook updates", "error", err)
+		} else {
+			closeHook = fn
+			hookLog.Info("Listening for Taskwarrior hook updates", "socket", socket)
+		}
+	}
+
+	// Wire API hooks to Taskwarrior
+	web.CreateTaskFunc = createTask
+	web.AcknowledgeFunc = sched.acknowledge
+	web.CompleteTaskFunc = sched.completeTask
+	web.ViewTaskFunc = sched.viewTask
+	web.ActionSecret = sched.actionSecret
+	web.DNDStatusFunc = sched.dndStatus
+	web.SetDNDFunc = sched.setDND
 
-	// Web server removed
 	// Start HTTP server if configured via env var
 	shutdownHTTP := func() error { return nil }
 	if fn, addr, err := startHTTPServerFunc(web.NewRouter()); err != nil {
-		config.Log(config.ERROR, "failed to start http server: %v", err)
+		httpLog.Error("Failed to start HTTP server", "error", err)
 	} else if fn != nil {
 		shutdownHTTP = fn
-		config.Log(config.INFO, "HTTP server listening on %s", addr)
+		httpLog.Info("HTTP server listening", "addr", addr)
 	}
 
+	// Reload the config on SIGHUP and, if reload.watch is set, when the file changes
+	reloadCh := make(chan struct{}, 1)
+	stopWatch := make(chan struct{})
+	go watchConfigFunc(cfgPath, reloadCh, stopWatch)
+	hupCh := reloadSigCh()
+
 	// Block until interrupted (SIGINT/SIGTERM)
 	sigCh := runSigCh()
-	select {
-	case <-sigCh:
+	for running := true; running; {
+		select {
+		case <-sigCh:
+			running = false
+		case <-hupCh:
+			appLog.Info("SIGHUP received, reloading", "path", cfgPath)
+			r.reload()
+		case <-reloadCh:
+			appLog.Info("Config file changed, reloading", "path", cfgPath)
+			r.reload()
+		}
 	}
+	close(stopWatch)
+	close(r.stop)
 	// Shutdown http server if running
 	shutdownHTTP()
+	closeHook()
 	return nil
 }
 
-// getUDA returns the value of a UDA if present in the task struct (by field name)
-func getUDA(task taskwarrior.Task, field string) (string, bool) {
-	cfg := config.Get()
-	var fieldName string
+// configPath returns the config file to use.
+// Precedence: CLI override -> TASK_HERALD_CONFIG env -> ./config.yaml -> /var/lib/task-herald/config.yaml
+func configPath(override string) string {
+	if override != "" {
+		return override
+	}
+	if p := os.Getenv("TASK_HERALD_CONFIG"); p != "" {
+		return p
+	}
+	if _, err := os.Stat("./config.yaml"); err == nil {
+		return "./config.yaml"
+	}
+	return "/var/lib/task-herald/config.yaml"
+}
+
+// CheckConfig validates the config file Run would load without starting
+// anything. It returns the file checked and every problem found in it; err
+// is set if the file can't be read.
+func CheckConfig(configOverride string) (string, config.Problems, error) {
+	path := configPath(configOverride)
+	cfg, problems, err := config.ParseFile(path)
+	if err != nil {
+		return path, nil, err
+	}
+	// Settings checked while building, such as route filters and quiet
+	// hours; nothing is started, run or connected to
+	if len(problems) == 0 {
+		if _, err := compileComponents(cfg, checkBackend); err != nil {
+			problems = append(problems, config.Problem{Msg: err.Error()})
+		}
+	}
+	return path, problems, nil
+}
+
+// checkBackend replaces newBackendFunc for CheckConfig: it checks the
+// settings of the selected backend without building it
+func checkBackend(cfg *config.Config, _ func(format string, v ...interface{})) (typeNotifier, error) {
+	if cfg.Notifier != "" && cfg.Notifier != "ntfy" {
+		if err := notify.Check(cfg.Notifier, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return checkedNotifier{}, nil
+}
+
+// checkedNotifier stands in for a backend checked by checkBackend; it is
+// never sent to
+type checkedNotifier struct{}
+
+func (checkedNotifier) Send(ctx context.Context, message string, headers map[string]string) error {
+	return nil
+}
+
+// udaName returns the Taskwarrior UDA name mapped for a notification
+// feature, falling back to the documented default names
+func udaName(cfg *config.Config, field string) string {
+	var name string
+	var fallback string
 	switch field {
 	case "notification_date":
-		fieldName = cfg.UDAMap.NotificationDate
+		fallback = "notification_date"
+		if cfg != nil {
+			name = cfg.UDAMap.NotificationDate
+		}
 	case "repeat_enable":
-		fieldName = cfg.UDAMap.RepeatEnable
+		fallback = "notification_repeat_enable"
+		if cfg != nil {
+			name = cfg.UDAMap.RepeatEnable
+		}
 	case "repeat_delay":
-		fieldName = cfg.UDAMap.RepeatDelay
+		fallback = "notification_repeat_delay"
+		if cfg != nil {
+			name = cfg.UDAMap.RepeatDelay
+		}
 	default:
-		fieldName = field
+		return field
 	}
-	// Direct struct field for notification_date
-	if fieldName == "notification_date" {
-		return task.NotificationDate, task.NotificationDate != ""
+	if name == "" {
+		return fallback
 	}
-	// For other UDAs, try to get from map (future extensibility)
-	v, ok := getTaskField(&task, fieldName)
-	return v, ok && v != ""
+	return name
 }
 
-// getTaskField uses reflection to get a field by name from Task struct or its map (if present)
-func getTaskField(task *taskwarrior.Task, field string) (string, bool) {
-	// Use reflection to get a field by name (case-insensitive) from Task struct
-	// Supported fields: ID, UUID, Description, NotificationDate, Tags, Priority, Project, Status
-	v := reflect.ValueOf(task).Elem()
-	t := v.Type()
-	for i := 0; i < t.NumField(); i++ {
-		f := t.Field(i)
-		if strings.EqualFold(f.Name, field) || strings.EqualFold(f.Tag.Get("json"), field) {
-			fv := v.Field(i)
-			// Handle string fields
-			switch fv.Kind() {
-			case reflect.String:
-				return fv.String(), fv.String() != ""
-			case reflect.Int, reflect.Int64:
-				return fmt.Sprintf("%d", fv.Int()), true
-			case reflect.Slice:
-				// only handle []string
-				if fv.Type().Elem().Kind() == reflect.String {
-					ss := make([]string, fv.Len())
-					for j := 0; j < fv.Len(); j++ {
-						ss[j] = fv.Index(j).String()
-					}
-					return strings.Join(ss, ","), fv.Len() > 0
-				}
-			}
+// dateUDAs returns the UDAs that hold dates: the notification dates and the
+// UDAs reminders are anchored on
+func dateUDAs(cfg *config.Config) []string {
+	udas := []string{udaName(cfg, "notification_date"), "taskherald.notification_date"}
+	if cfg == nil {
+		return udas
+	}
+	for _, rc := range cfg.Reminders {
+		// Anchors are matched in lower case, see buildReminders
+		anchor := strings.ToLower(rc.Anchor)
+		if anchor == "" || taskwarrior.IsTaskField(anchor) || slices.Contains(udas, anchor) {
+			continue
 		}
+		udas = append(udas, anchor)
 	}
-	return "", false
+	return udas
 }
 
-// parseNotificationDate parses a date string in common Taskwarrior formats
+// getUDA returns the value of a task attribute or UDA, after mapping
+// notification feature names through the configured UDA map
+func getUDA(task taskwarrior.Task, field string) (string, bool) {
+	return task.Attribute(udaName(config.Get(), field))
+}
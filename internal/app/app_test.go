@@ -32,13 +32,24 @@ func TestGetUDA_ReturnsFalseForEmptyNotificationDate(t *testing.T) {
 }
 
 func TestGetUDA_CustomMappingFallback(t *testing.T) {
-	// If UDAMap maps to a custom field, getUDA should attempt getTaskField and currently return false
+	// If UDAMap maps to a custom UDA the task doesn't have, getUDA returns false
 	cfg := &config.Config{UDAMap: config.UDAMap{NotificationDate: "taskherald.notification_date"}}
 	config.Set(cfg)
 
 	task := taskwarrior.Task{NotificationDate: "2025-08-31 14:30:00"}
 	v, ok := getUDA(task, "taskherald.notification_date")
 	if ok {
-		t.Fatalf("expected ok=false for custom UDA mapping (not implemented), got true with value %q", v)
+		t.Fatalf("expected ok=false for missing custom UDA, got true with value %q", v)
+	}
+}
+
+func TestGetUDA_CustomMappingFound(t *testing.T) {
+	cfg := &config.Config{UDAMap: config.UDAMap{RepeatDelay: "nag_every"}}
+	config.Set(cfg)
+
+	task := taskwarrior.Task{UDAs: map[string]interface{}{"nag_every": "PT5M"}}
+	v, ok := getUDA(task, "repeat_delay")
+	if !ok || v != "PT5M" {
+		t.Fatalf("expected mapped UDA value PT5M, got %q (ok=%v)", v, ok)
 	}
 }
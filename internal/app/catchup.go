@@ -0,0 +1,127 @@
+package app
+
+import (
+	"sort"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/notify"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+const (
+	// defaultCatchUpGrace is how late a notification may be and still be sent normally
+	defaultCatchUpGrace = 5 * time.Minute
+	// defaultCatchUpMaxAge is the age after which missed notifications are always dropped
+	defaultCatchUpMaxAge = 24 * time.Hour
+)
+
+// missedNotification is a notification that was due longer ago than the
+// grace window and never sent
+type missedNotification struct {
+	task     taskwarrior.Task
+	notifyAt time.Time
+	key      string
+}
+
+func catchUpGrace(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.CatchUp.Grace > 0 {
+		return cfg.CatchUp.Grace
+	}
+	return defaultCatchUpGrace
+}
+
+func catchUpMaxAge(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.CatchUp.MaxAge > 0 {
+		return cfg.CatchUp.MaxAge
+	}
+	return defaultCatchUpMaxAge
+}
+
+// catchUp applies the configured catch-up mode to missed notifications,
+// rendering the ones to send into out, and records the dropped ones in the
+// state. Callers hold s.mu. It reports whether the state changed.
+func (s *scheduler) catchUp(missed []missedNotification, now time.Time, out *[]outgoing) bool {
+	mode := s.cfg.CatchUp.Mode
+	if mode == "" {
+		mode = config.CatchUpDrop
+	}
+	maxAge := catchUpMaxAge(s.cfg)
+
+	// oldest first, so digests read chronologically and the latest is last
+	sort.Slice(missed, func(i, j int) bool { return missed[i].notifyAt.Before(missed[j].notifyAt) })
+	var pending []missedNotification
+	for _, m := range missed {
+		if mode == config.CatchUpDrop || now.Sub(m.notifyAt) > maxAge {
+			notifyLog.Info("Dropping missed notification", "task_uuid", m.task.UUID, "at", m.notifyAt.In(util.Location()))
+			s.state.MarkDropped(m.key, m.task.UUID, now)
+			continue
+		}
+		pending = append(pending, m)
+	}
+
+	switch mode {
+	case config.CatchUpSendLatestOnly:
+		if len(pending) > 1 {
+			for _, m := range pending[:len(pending)-1] {
+				notifyLog.Info("Dropping missed notification, only the latest is sent", "task_uuid", m.task.UUID)
+				s.state.MarkDropped(m.key, m.task.UUID, now)
+			}
+			pending = pending[len(pending)-1:]
+		}
+		fallthrough
+	case config.CatchUpSendAll:
+		for _, m := range pending {
+			msgs := s.messages(s.targets(m.task), m.task, m.notifyAt, "")
+			*out = append(*out, outgoing{
+				send: func() error { return deliver(m.task.UUID, msgs) },
+				done: func(err error) bool {
+					if err != nil {
+						notifyLog.Error("Failed to send missed notification", "task_uuid", m.task.UUID, "error", err)
+						return false
+					}
+					notifyLog.Info("Sent missed notification", "task_uuid", m.task.UUID)
+					s.state.MarkSent(m.key, m.task.UUID, now)
+					return true
+				},
+			})
+		}
+	case config.CatchUpDigest:
+		if len(pending) == 0 {
+			break
+		}
+		digest := notify.Digest{Title: "Missed notifications"}
+		for _, m := range pending {
+			digest.Tasks = append(digest.Tasks, taskInfo(m.task, m.notifyAt))
+		}
+		msg, err := notify.RenderDigest(digest, s.cfg.CatchUp.DigestMessage)
+		if err != nil {
+			notifyLog.Error("Failed to render catch-up digest, using default", "error", err)
+			msg, _ = notify.RenderDigest(digest, "")
+		}
+		headers := map[string]string{"X-Title": digest.Title}
+		target := s.defaultTarget()
+		*out = append(*out, outgoing{
+			send: func() error { return target.send(msg, headers) },
+			done: func(err error) bool {
+				if err != nil {
+					notifyLog.Error("Failed to send digest of missed notifications", "count", len(pending), "error", err)
+					return false
+				}
+				notifyLog.Info("Sent digest of missed notifications", "count", len(pending))
+				for _, m := range pending {
+					s.state.MarkSent(m.key, m.task.UUID, now)
+				}
+				return true
+			},
+		})
+	case config.CatchUpDrop:
+	default:
+		notifyLog.Warn("Unknown catch_up.mode, dropping missed notifications", "mode", mode, "count", len(pending))
+		for _, m := range pending {
+			s.state.MarkDropped(m.key, m.task.UUID, now)
+		}
+	}
+	return true
+}
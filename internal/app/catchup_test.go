@@ -0,0 +1,105 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+// recordingNotifier keeps every message sent
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []string
+	headers  []map[string]string
+}
+
+func (r *recordingNotifier) Send(ctx context.Context, message string, headers map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, message)
+	r.headers = append(r.headers, headers)
+	return nil
+}
+
+// missedTasks returns three tasks due 3h, 2h and 1h before now
+func missedTasks(now time.Time) []taskwarrior.Task {
+	var tasks []taskwarrior.Task
+	for i, desc := range []string{"oldest", "middle", "latest"} {
+		due := now.Add(-time.Duration(3-i) * time.Hour)
+		tasks = append(tasks, taskwarrior.Task{
+			ID:               i + 1,
+			UUID:             "u" + desc,
+			Description:      desc,
+			NotificationDate: due.UTC().Format("20060102T150405Z"),
+		})
+	}
+	return tasks
+}
+
+func runCatchUp(t *testing.T, catchUp config.CatchUpConfig) *recordingNotifier {
+	t.Helper()
+	cfg := &config.Config{NotificationMessage: "{{.Description}}", CatchUp: catchUp}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	now := time.Now().Truncate(time.Second)
+	s.setTasks(missedTasks(now))
+	s.notifyDue(now)
+	// missed notifications are handled once
+	s.notifyDue(now.Add(time.Minute))
+	return rn
+}
+
+func TestCatchUp_DropByDefault(t *testing.T) {
+	rn := runCatchUp(t, config.CatchUpConfig{})
+	if len(rn.messages) != 0 {
+		t.Fatalf("expected missed notifications to be dropped, got %v", rn.messages)
+	}
+}
+
+func TestCatchUp_SendAll(t *testing.T) {
+	rn := runCatchUp(t, config.CatchUpConfig{Mode: config.CatchUpSendAll})
+	if strings.Join(rn.messages, ",") != "oldest,middle,latest" {
+		t.Fatalf("unexpected messages: %v", rn.messages)
+	}
+}
+
+func TestCatchUp_SendLatestOnly(t *testing.T) {
+	rn := runCatchUp(t, config.CatchUpConfig{Mode: config.CatchUpSendLatestOnly})
+	if strings.Join(rn.messages, ",") != "latest" {
+		t.Fatalf("unexpected messages: %v", rn.messages)
+	}
+}
+
+func TestCatchUp_Digest(t *testing.T) {
+	rn := runCatchUp(t, config.CatchUpConfig{Mode: config.CatchUpDigest})
+	if len(rn.messages) != 1 {
+		t.Fatalf("expected a single digest, got %v", rn.messages)
+	}
+	msg := rn.messages[0]
+	if !strings.Contains(msg, "(3)") || strings.Index(msg, "oldest") > strings.Index(msg, "latest") {
+		t.Fatalf("unexpected digest: %q", msg)
+	}
+	if rn.headers[0]["X-Title"] != "Missed notifications" {
+		t.Fatalf("unexpected digest headers: %v", rn.headers[0])
+	}
+}
+
+func TestCatchUp_MaxAgeAndGrace(t *testing.T) {
+	// only the notification due 1h ago is younger than max_age
+	rn := runCatchUp(t, config.CatchUpConfig{Mode: config.CatchUpSendAll, MaxAge: 90 * time.Minute})
+	if strings.Join(rn.messages, ",") != "latest" {
+		t.Fatalf("unexpected messages with max_age: %v", rn.messages)
+	}
+
+	// a 4h grace window sends everything normally even when dropping missed notifications
+	rn = runCatchUp(t, config.CatchUpConfig{Mode: config.CatchUpDrop, Grace: 4 * time.Hour})
+	if len(rn.messages) != 3 {
+		t.Fatalf("expected all notifications within grace to be sent, got %v", rn.messages)
+	}
+}
@@ -0,0 +1,165 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/notify"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+// digest is a compiled scheduled digest
+type digest struct {
+	name      string
+	schedule  *util.Schedule
+	filter    *taskwarrior.Filter
+	title     string
+	message   string
+	sendEmpty bool
+	target    routeTarget
+}
+
+// buildDigests compiles the configured digests. Targets are resolved like
+// route targets.
+func buildDigests(cfg *config.Config, defaultNotifier typeNotifier, newBackend backendFunc, logger func(format string, v ...interface{})) ([]digest, error) {
+	senders := map[string]typeNotifier{}
+	var out []digest
+	for i, dc := range cfg.Digests {
+		name := dc.Name
+		if name == "" {
+			name = fmt.Sprintf("digest %d", i+1)
+		}
+		d := digest{name: name, title: dc.Title, message: dc.Message, sendEmpty: dc.SendEmpty}
+		if d.title == "" {
+			d.title = name
+		}
+		if dc.Schedule == "" {
+			return nil, fmt.Errorf("digest %q: schedule required", name)
+		}
+		var err error
+		if d.schedule, err = util.ParseSchedule(dc.Schedule); err != nil {
+			return nil, fmt.Errorf("digest %q: %w", name, err)
+		}
+		if d.filter, err = taskwarrior.ParseFilter(dc.Filter); err != nil {
+			return nil, fmt.Errorf("digest %q: %w", name, err)
+		}
+		n, err := targetNotifier(cfg, dc.Target, defaultNotifier, newBackend, senders, logger)
+		if err != nil {
+			return nil, fmt.Errorf("digest %q: %w", name, err)
+		}
+		d.target = routeTarget{name: name, backend: targetBackend(cfg, dc.Target), notifier: n, headers: dc.Target.Headers}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// runDigests sends each digest on its schedule until stop is closed
+func (s *scheduler) runDigests(digests []digest, stop <-chan struct{}) {
+	next := make([]time.Time, len(digests))
+	now := time.Now()
+	for i, d := range digests {
+		next[i] = d.schedule.Next(now, nil)
+		notifyLog.Info("Digest scheduled", "digest", d.name, "at", next[i].In(util.Location()))
+	}
+	for {
+		var first time.Time
+		for _, t := range next {
+			if !t.IsZero() && (first.IsZero() || t.Before(first)) {
+				first = t
+			}
+		}
+		if first.IsZero() {
+			return
+		}
+		timer := time.NewTimer(time.Until(first))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		now := time.Now()
+		for i, d := range digests {
+			if next[i].IsZero() || next[i].After(now) {
+				continue
+			}
+			if err := s.sendDigest(d); err != nil {
+				notifyLog.Error("Failed to send digest", "digest", d.name, "error", err)
+			}
+			next[i] = d.schedule.Next(now, nil)
+		}
+	}
+}
+
+// sendDigest renders the tasks of the current snapshot that match the
+// digest filter into one message and sends it
+func (s *scheduler) sendDigest(d digest) error {
+	s.mu.RLock()
+	var tasks []taskwarrior.Task
+	for _, task := range s.tasks {
+		if d.filter.Match(task) {
+			tasks = append(tasks, task)
+		}
+	}
+	s.mu.RUnlock()
+	if len(tasks) == 0 && !d.sendEmpty {
+		notifyLog.Info("No tasks for digest, not sent", "digest", d.name)
+		return nil
+	}
+	sortAgenda(tasks)
+	data := notify.Digest{Title: d.title}
+	for _, task := range tasks {
+		info := taskInfo(task, time.Time{})
+		info.NotificationDate = nil
+		if at, _ := nextNotification(task); !at.IsZero() {
+			at = at.In(util.Location())
+			info.NotificationDate = &at
+		}
+		data.Tasks = append(data.Tasks, info)
+	}
+	tmpl := d.message
+	if tmpl == "" {
+		tmpl = notify.DefaultAgendaMessage
+	}
+	msg, err := notify.RenderDigest(data, tmpl)
+	if err != nil {
+		return fmt.Errorf("render: %w", err)
+	}
+	headers := map[string]string{}
+	for k, v := range d.target.headers {
+		headers[k] = v
+	}
+	if _, ok := headers["X-Title"]; !ok {
+		headers["X-Title"] = d.title
+	}
+	if err := d.target.send(msg, headers); err != nil {
+		return err
+	}
+	notifyLog.Info("Sent digest", "digest", d.name, "count", len(tasks))
+	return nil
+}
+
+// sortAgenda orders tasks by due date, tasks without one last, then by
+// urgency
+func sortAgenda(tasks []taskwarrior.Task) {
+	due := make(map[string]time.Time, len(tasks))
+	for _, t := range tasks {
+		if at := parseTaskDate(t.Due); at != nil {
+			due[t.UUID] = *at
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		di, iok := due[tasks[i].UUID]
+		dj, jok := due[tasks[j].UUID]
+		switch {
+		case iok && jok && !di.Equal(dj):
+			return di.Before(dj)
+		case iok != jok:
+			return iok
+		}
+		return tasks[i].Urgency > tasks[j].Urgency
+	})
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+func TestDigest_AgendaFromSnapshot(t *testing.T) {
+	cfg := &config.Config{Digests: []config.DigestConfig{{
+		Name:     "agenda",
+		Schedule: "0 8 * * *",
+		Filter:   "due.before:tomorrow or priority:H",
+		Title:    "Today's agenda",
+	}}}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	digests, err := buildDigests(cfg, rn, newBackendFunc, nil)
+	if err != nil {
+		t.Fatalf("buildDigests: %v", err)
+	}
+	date := func(d time.Duration) string { return time.Now().Add(d).UTC().Format("20060102T150405Z") }
+	s.setTasks([]taskwarrior.Task{
+		{UUID: "later", Description: "next week", Due: date(7 * 24 * time.Hour)},
+		{UUID: "urgent", Description: "call bank", Priority: "H"},
+		{UUID: "today", Description: "water plants", Due: date(-time.Minute)},
+		{UUID: "overdue", Description: "pay rent", Due: date(-48 * time.Hour), Project: "home"},
+	})
+	if err := s.sendDigest(digests[0]); err != nil {
+		t.Fatalf("sendDigest: %v", err)
+	}
+	if len(rn.messages) != 1 {
+		t.Fatalf("expected one digest message, got %d", len(rn.messages))
+	}
+	msg := rn.messages[0]
+	if !strings.HasPrefix(msg, "📋 Today's agenda (3)") || strings.Contains(msg, "next week") {
+		t.Fatalf("unexpected digest:\n%s", msg)
+	}
+	// overdue first, then due today, then tasks without a due date
+	if i, j, k := strings.Index(msg, "pay rent [home]"), strings.Index(msg, "water plants"), strings.Index(msg, "call bank"); !(i >= 0 && i < j && j < k) {
+		t.Fatalf("unexpected order:\n%s", msg)
+	}
+	if rn.headers[0]["X-Title"] != "Today's agenda" {
+		t.Errorf("unexpected headers: %v", rn.headers[0])
+	}
+}
+
+func TestDigest_EmptyNotSent(t *testing.T) {
+	cfg := &config.Config{Digests: []config.DigestConfig{
+		{Schedule: "@daily", Filter: "+nothing"},
+		{Schedule: "@daily", Filter: "+nothing", SendEmpty: true, Message: "{{.Title}}: {{len .Tasks}}"},
+	}}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	digests, err := buildDigests(cfg, rn, newBackendFunc, nil)
+	if err != nil {
+		t.Fatalf("buildDigests: %v", err)
+	}
+	for _, d := range digests {
+		if err := s.sendDigest(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(rn.messages) != 1 || rn.messages[0] != "digest 2: 0" {
+		t.Fatalf("expected only the send_empty digest, got %v", rn.messages)
+	}
+}
+
+func TestBuildDigests_Invalid(t *testing.T) {
+	for _, dc := range []config.DigestConfig{
+		{Name: "no schedule"},
+		{Schedule: "0 25 * * *"},
+		{Schedule: "@daily", Filter: "(+work"},
+		{Schedule: "@daily", Target: config.RouteTarget{Backend: "gotify", Topic: "x"}},
+	} {
+		if _, err := buildDigests(&config.Config{Digests: []config.DigestConfig{dc}}, &recordingNotifier{}, newBackendFunc, nil); err == nil {
+			t.Errorf("expected error for %+v", dc)
+		}
+	}
+}
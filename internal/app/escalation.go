@@ -0,0 +1,171 @@
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+// escalation is a compiled escalation chain
+type escalation struct {
+	name   string
+	filter *taskwarrior.Filter
+	steps  []escalationStep // ordered by after
+}
+
+// escalationStep is a compiled escalation step
+type escalationStep struct {
+	after    time.Duration
+	priority string        // ntfy priority; empty raises the task priority
+	targets  []routeTarget // nil sends to the task's route targets
+}
+
+// ntfyPriorities are the ntfy priority names from lowest to highest
+var ntfyPriorities = []string{"min", "low", "default", "high", "max"}
+
+// buildEscalations compiles the configured escalation chains. Step targets
+// are resolved like route targets.
+func buildEscalations(cfg *config.Config, defaultNotifier typeNotifier, newBackend backendFunc, logger func(format string, v ...interface{})) ([]escalation, error) {
+	senders := map[string]typeNotifier{}
+	var out []escalation
+	for i, ec := range cfg.Escalations {
+		name := ec.Name
+		if name == "" {
+			name = fmt.Sprintf("escalation %d", i+1)
+		}
+		e := escalation{name: name}
+		var err error
+		if e.filter, err = taskwarrior.ParseFilter(ec.Filter); err != nil {
+			return nil, fmt.Errorf("escalation %q: %w", name, err)
+		}
+		if len(ec.Steps) == 0 {
+			return nil, fmt.Errorf("escalation %q: no steps", name)
+		}
+		for j, sc := range ec.Steps {
+			if sc.After <= 0 {
+				return nil, fmt.Errorf("escalation %q: step %d: after must be positive", name, j+1)
+			}
+			step := escalationStep{after: sc.After}
+			if sc.Priority != "" {
+				if step.priority = ntfyPriority(sc.Priority); step.priority == "" {
+					return nil, fmt.Errorf("escalation %q: step %d: unknown priority %q", name, j+1, sc.Priority)
+				}
+			}
+			if sc.Target.Backend != "" || sc.Target.Topic != "" {
+				n, err := targetNotifier(cfg, sc.Target, defaultNotifier, newBackend, senders, logger)
+				if err != nil {
+					return nil, fmt.Errorf("escalation %q: step %d: %w", name, j+1, err)
+				}
+				target := fmt.Sprintf("%s step %d", name, j+1)
+				step.targets = []routeTarget{{name: target, backend: targetBackend(cfg, sc.Target), notifier: n, headers: sc.Target.Headers, message: sc.Target.Message}}
+			}
+			e.steps = append(e.steps, step)
+		}
+		sort.SliceStable(e.steps, func(a, b int) bool { return e.steps[a].after < e.steps[b].after })
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// ntfyPriority returns the ntfy priority name for a name or number 1-5, or
+// "" if p is not a priority
+func ntfyPriority(p string) string {
+	p = strings.ToLower(strings.TrimSpace(p))
+	if p == "urgent" {
+		return "max"
+	}
+	for i, name := range ntfyPriorities {
+		if p == name || p == strconv.Itoa(i+1) {
+			return name
+		}
+	}
+	return ""
+}
+
+// raisedPriority returns the ntfy priority of a task raised by levels, at
+// most max
+func raisedPriority(task taskwarrior.Task, levels int) string {
+	level := 2 // default
+	switch strings.ToUpper(task.Priority) {
+	case "H":
+		level = 4
+	case "M":
+		level = 3
+	}
+	level += levels
+	if level >= len(ntfyPriorities) {
+		level = len(ntfyPriorities) - 1
+	}
+	return ntfyPriorities[level]
+}
+
+// escalationFor returns the first escalation chain matching the task
+func (s *scheduler) escalationFor(task taskwarrior.Task) *escalation {
+	for i := range s.escalations {
+		if s.escalations[i].filter.Match(task) {
+			return &s.escalations[i]
+		}
+	}
+	return nil
+}
+
+// nextEscalation returns when the next escalation step of a sent
+// notification is due; callers hold s.mu
+func (s *scheduler) nextEscalation(task taskwarrior.Task, key string) (time.Time, bool) {
+	e := s.escalationFor(task)
+	if e == nil {
+		return time.Time{}, false
+	}
+	done := s.state.Escalations(key)
+	first, ok := s.state.FirstSent(key)
+	if !ok || done >= len(e.steps) {
+		return time.Time{}, false
+	}
+	return first.Add(e.steps[done].after), true
+}
+
+// escalate sends the latest due escalation step of a notification; steps
+// that became due together (e.g. while the daemon was offline) are sent
+// once. It reports whether the state changed; callers hold s.mu.
+func (s *scheduler) escalate(task taskwarrior.Task, key string, notifyAt, now time.Time, out *[]outgoing) bool {
+	e := s.escalationFor(task)
+	first, ok := s.state.FirstSent(key)
+	if e == nil || !ok {
+		return false
+	}
+	level := s.state.Escalations(key)
+	for level < len(e.steps) && !first.Add(e.steps[level].after).After(now) {
+		level++
+	}
+	if level == s.state.Escalations(key) {
+		return false
+	}
+	step := e.steps[level-1]
+	priority := step.priority
+	if priority == "" {
+		priority = raisedPriority(task, level)
+	}
+	targets := step.targets
+	if targets == nil {
+		targets = s.targets(task)
+	}
+	notifyLog.Info("Task not acknowledged, escalating", "task_uuid", task.UUID, "after", step.after, "escalation", e.name, "step", level, "priority", priority)
+	msgs := s.messages(targets, task, notifyAt, priority)
+	*out = append(*out, outgoing{
+		send: func() error { return deliver(task.UUID, msgs) },
+		done: func(err error) bool {
+			if err != nil {
+				notifyLog.Error("Failed to send escalation", "task_uuid", task.UUID, "error", err)
+				return false
+			}
+			s.state.MarkEscalated(key, task.UUID, level, now)
+			return true
+		},
+	})
+	return false
+}
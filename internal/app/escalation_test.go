@@ -0,0 +1,128 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+func escalationScheduler(t *testing.T, escalations []config.EscalationConfig) (*scheduler, map[string]*recordingNotifier) {
+	t.Helper()
+	cfg := &config.Config{
+		NotificationMessage: "{{.Description}}",
+		Ntfy:                config.NtfyConfig{Topic: "main"},
+		Escalations:         escalations,
+	}
+	s, byTopic := routedScheduler(t, cfg)
+	var err error
+	if s.escalations, err = buildEscalations(cfg, s.notifier, newBackendFunc, t.Logf); err != nil {
+		t.Fatalf("buildEscalations: %v", err)
+	}
+	return s, byTopic
+}
+
+var onCall = []config.EscalationConfig{{
+	Name:   "on-call",
+	Filter: "+chore",
+	Steps: []config.EscalationStep{
+		{After: 15 * time.Minute},
+		{After: 30 * time.Minute, Priority: "urgent", Target: config.RouteTarget{Topic: "team"}},
+	},
+}}
+
+func choreTask(at time.Time) taskwarrior.Task {
+	return taskwarrior.Task{UUID: "u1", Description: "take out trash", Tags: []string{"chore"}, NotificationDate: at.UTC().Format("20060102T150405Z")}
+}
+
+func TestEscalation_Chain(t *testing.T) {
+	s, byTopic := escalationScheduler(t, onCall)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{choreTask(t0)})
+
+	s.notifyDue(t0)
+	main := byTopic["main"]
+	if len(main.messages) != 1 || main.headers[0]["X-Default"] != "default" {
+		t.Fatalf("expected the normal notification, got %v %v", main.messages, main.headers)
+	}
+	if next, ok := s.queue.next(); !ok || !next.Equal(t0.Add(15*time.Minute)) {
+		t.Fatalf("expected the first escalation to be queued, got %v %v", next, ok)
+	}
+	s.notifyDue(t0.Add(10 * time.Minute))
+	if len(main.messages) != 1 {
+		t.Fatalf("expected no escalation before 15m, got %v", main.messages)
+	}
+
+	// Step 1 re-sends to the task's target one priority level higher
+	s.notifyDue(t0.Add(15 * time.Minute))
+	if len(main.messages) != 2 || main.headers[1]["X-Priority"] != "high" || main.headers[1]["X-Default"] != "high" {
+		t.Fatalf("expected a re-send with raised priority, got %v", main.headers)
+	}
+
+	// Step 2 goes to the team topic
+	s.notifyDue(t0.Add(30 * time.Minute))
+	team := byTopic["team"]
+	if team == nil || len(team.messages) != 1 || team.headers[0]["X-Priority"] != "max" {
+		t.Fatalf("expected the team topic to be notified at max priority, got %+v", team)
+	}
+	if len(main.messages) != 2 {
+		t.Errorf("expected no further message on the main topic, got %v", main.messages)
+	}
+	if _, ok := s.queue.next(); ok {
+		t.Error("expected nothing queued after the last step")
+	}
+}
+
+func TestEscalation_StopsWhenAcknowledged(t *testing.T) {
+	s, byTopic := escalationScheduler(t, onCall)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{choreTask(t0)})
+	s.notifyDue(t0)
+	if err := s.acknowledge("u1", ""); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	s.notifyDue(t0.Add(time.Hour))
+	if len(byTopic["main"].messages) != 1 || byTopic["team"] != nil && len(byTopic["team"].messages) != 0 {
+		t.Fatalf("expected no escalation after acknowledgement, got %v", byTopic["main"].messages)
+	}
+}
+
+func TestEscalation_OverdueStepsSentOnce(t *testing.T) {
+	s, byTopic := escalationScheduler(t, onCall)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{choreTask(t0)})
+	s.notifyDue(t0)
+	// e.g. the daemon was asleep through both steps
+	s.notifyDue(t0.Add(45 * time.Minute))
+	s.notifyDue(t0.Add(50 * time.Minute))
+	if len(byTopic["main"].messages) != 1 || len(byTopic["team"].messages) != 1 {
+		t.Fatalf("expected only the latest step, got main %v team %v", byTopic["main"].messages, byTopic["team"].messages)
+	}
+}
+
+func TestEscalation_OnlyMatchingTasks(t *testing.T) {
+	s, byTopic := escalationScheduler(t, onCall)
+	t0 := time.Now().Add(-time.Hour).Truncate(time.Second)
+	task := choreTask(t0)
+	task.Tags = nil
+	s.setTasks([]taskwarrior.Task{task})
+	s.notifyDue(t0)
+	s.notifyDue(t0.Add(time.Hour))
+	if len(byTopic["main"].messages) != 1 {
+		t.Fatalf("expected no escalation for tasks outside the filter, got %v", byTopic["main"].messages)
+	}
+}
+
+func TestBuildEscalations_Invalid(t *testing.T) {
+	for _, ec := range []config.EscalationConfig{
+		{Name: "empty"},
+		{Steps: []config.EscalationStep{{}}},
+		{Steps: []config.EscalationStep{{After: time.Minute, Priority: "loud"}}},
+		{Filter: "(", Steps: []config.EscalationStep{{After: time.Minute}}},
+	} {
+		if _, err := buildEscalations(&config.Config{Escalations: []config.EscalationConfig{ec}}, &recordingNotifier{}, newBackendFunc, nil); err == nil {
+			t.Errorf("expected error for %+v", ec)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
     "io/ioutil"
     "os"
+    "path/filepath"
     "strings"
 
     "task-herald/internal/config"
@@ -56,3 +57,24 @@ func resolveTLSPaths(cfg *config.Config) (string, string) {
     }
     return cert, key
 }
+
+// resolveStateDir returns the directory for persisted notification state:
+// state.dir from the config, else $STATE_DIRECTORY (set by systemd's
+// StateDirectory=), else task-herald under the XDG state dir
+// ($XDG_STATE_HOME or ~/.local/state). It is "" only if no home is known.
+func resolveStateDir(cfg *config.Config) string {
+    if cfg != nil && cfg.State.Dir != "" {
+        return cfg.State.Dir
+    }
+    if dir := os.Getenv("STATE_DIRECTORY"); dir != "" {
+        // systemd may pass several colon-separated directories
+        return strings.Split(dir, ":")[0]
+    }
+    if dir := os.Getenv("XDG_STATE_HOME"); filepath.IsAbs(dir) {
+        return filepath.Join(dir, "task-herald")
+    }
+    if home, err := os.UserHomeDir(); err == nil {
+        return filepath.Join(home, ".local", "state", "task-herald")
+    }
+    return ""
+}
@@ -72,3 +72,27 @@ func TestResolveTLSPaths_FileFallback(t *testing.T) {
         t.Fatalf("unexpected tls paths: %q %q", c, k)
     }
 }
+
+func TestResolveStateDir(t *testing.T) {
+    t.Setenv("STATE_DIRECTORY", "/var/lib/task-herald:/var/lib/other")
+
+    cfg := &config.Config{}
+    if got := resolveStateDir(cfg); got != "/var/lib/task-herald" {
+        t.Fatalf("expected STATE_DIRECTORY fallback, got %q", got)
+    }
+    cfg.State.Dir = "/srv/herald"
+    if got := resolveStateDir(cfg); got != "/srv/herald" {
+        t.Fatalf("expected configured dir, got %q", got)
+    }
+
+    t.Setenv("STATE_DIRECTORY", "")
+    t.Setenv("XDG_STATE_HOME", "/home/alice/state")
+    if got := resolveStateDir(&config.Config{}); got != "/home/alice/state/task-herald" {
+        t.Fatalf("expected XDG_STATE_HOME fallback, got %q", got)
+    }
+    t.Setenv("XDG_STATE_HOME", "")
+    t.Setenv("HOME", "/home/alice")
+    if got := resolveStateDir(&config.Config{}); got != "/home/alice/.local/state/task-herald" {
+        t.Fatalf("expected ~/.local/state fallback, got %q", got)
+    }
+}
@@ -0,0 +1,36 @@
+package app
+
+import (
+	"context"
+
+	"task-herald/internal/metrics"
+	"task-herald/internal/notify"
+)
+
+var (
+	notificationsSent = metrics.NewCounter("task_herald_notifications_sent_total",
+		"Notifications, digests and escalations sent, by notifier backend.", "backend")
+	notificationsFailed = metrics.NewCounter("task_herald_notifications_failed_total",
+		"Notifications, digests and escalations that failed to send, by notifier backend.", "backend")
+	schedulerLag = metrics.NewHistogram("task_herald_scheduler_lag_seconds",
+		"Time between when a notification was due and when it was sent.",
+		[]float64{.1, .5, 1, 5, 10, 30, 60, 120, 300})
+	trackedTasks = metrics.NewGauge("task_herald_tracked_tasks",
+		"Pending and waiting tasks in the current snapshot.")
+	scheduledTasks = metrics.NewGauge("task_herald_scheduled_tasks",
+		"Tracked tasks with a notification_date.")
+)
+
+// send sends a notification to the target, giving up after
+// notify.SendTimeout, and counts it for its backend
+func (t routeTarget) send(msg string, headers map[string]string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), notify.SendTimeout)
+	defer cancel()
+	err := t.notifier.Send(ctx, msg, headers)
+	if err != nil {
+		notificationsFailed.Inc(t.backend)
+	} else {
+		notificationsSent.Inc(t.backend)
+	}
+	return err
+}
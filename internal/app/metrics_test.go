@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+type failingNotifier struct{}
+
+func (failingNotifier) Send(ctx context.Context, message string, headers map[string]string) error {
+	return errors.New("unavailable")
+}
+
+func TestMetrics_Notifications(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	due := now.Add(-30 * time.Second).UTC().Format("20060102T150405Z")
+	tasks := []taskwarrior.Task{{UUID: "m1", Description: "due", NotificationDate: due}, {UUID: "m2", Description: "later"}}
+	sent, failed, lag := notificationsSent.Value("ntfy"), notificationsFailed.Value("gotify"), schedulerLag.Count()
+
+	cfg := &config.Config{}
+	config.Set(cfg)
+	s := newScheduler(cfg, &recordingNotifier{})
+	s.setTasks(tasks)
+	s.notifyDue(now)
+	if got := notificationsSent.Value("ntfy") - sent; got != 1 {
+		t.Errorf("expected 1 notification counted as sent with ntfy, got %v", got)
+	}
+	if got := schedulerLag.Count() - lag; got != 1 {
+		t.Errorf("expected the lag of 1 notification, got %v", got)
+	}
+	if trackedTasks.Value() != 2 || scheduledTasks.Value() != 1 {
+		t.Errorf("expected 2 tracked and 1 scheduled task, got %v and %v", trackedTasks.Value(), scheduledTasks.Value())
+	}
+
+	cfg = &config.Config{Notifier: "gotify"}
+	config.Set(cfg)
+	s = newScheduler(cfg, failingNotifier{})
+	s.setTasks(tasks)
+	s.notifyDue(now)
+	if got := notificationsFailed.Value("gotify") - failed; got != 1 {
+		t.Errorf("expected 1 notification counted as failed with gotify, got %v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"container/heap"
+	"time"
+)
+
+// queued is a notification instant waiting in the scheduler queue. fire is
+// when it is next evaluated: the notification time, or the time of the next
+// repeat or retry.
+type queued struct {
+	fire time.Time
+	uuid string
+	n    notification
+}
+
+// notificationQueue is a min-heap of queued notifications ordered by fire time
+type notificationQueue []queued
+
+func (q notificationQueue) Len() int           { return len(q) }
+func (q notificationQueue) Less(i, j int) bool { return q[i].fire.Before(q[j].fire) }
+func (q notificationQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *notificationQueue) Push(x interface{}) { *q = append(*q, x.(queued)) }
+
+func (q *notificationQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// push adds an item to the queue
+func (q *notificationQueue) push(item queued) {
+	heap.Push(q, item)
+}
+
+// popDue removes and returns all items that fire at or before now
+func (q *notificationQueue) popDue(now time.Time) []queued {
+	var due []queued
+	for q.Len() > 0 && !(*q)[0].fire.After(now) {
+		due = append(due, heap.Pop(q).(queued))
+	}
+	return due
+}
+
+// next returns the earliest fire time, if any
+func (q notificationQueue) next() (time.Time, bool) {
+	if len(q) == 0 {
+		return time.Time{}, false
+	}
+	return q[0].fire, true
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+func TestNotificationQueue_PopDueInOrder(t *testing.T) {
+	base := time.Now()
+	var q notificationQueue
+	for _, offset := range []int{5, 1, 3, 2, 4} {
+		q.push(queued{fire: base.Add(time.Duration(offset) * time.Second), uuid: fmt.Sprint(offset)})
+	}
+	if next, ok := q.next(); !ok || !next.Equal(base.Add(time.Second)) {
+		t.Fatalf("expected earliest fire time first, got %v", next)
+	}
+	due := q.popDue(base.Add(3 * time.Second))
+	if len(due) != 3 || due[0].uuid != "1" || due[1].uuid != "2" || due[2].uuid != "3" {
+		t.Fatalf("unexpected due items: %+v", due)
+	}
+	if q.Len() != 2 {
+		t.Fatalf("expected 2 items left, got %d", q.Len())
+	}
+}
+
+func TestScheduler_QueueOnlyHoldsPendingNotifications(t *testing.T) {
+	cfg := &config.Config{NotificationMessage: "{{.Description}}"}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	now := time.Now().Truncate(time.Second)
+	var tasks []taskwarrior.Task
+	for i := 0; i < 1000; i++ {
+		tasks = append(tasks, taskwarrior.Task{
+			UUID:             fmt.Sprintf("u%d", i),
+			Description:      fmt.Sprintf("task %d", i),
+			NotificationDate: now.Add(time.Duration(i+1) * time.Minute).UTC().Format("20060102T150405Z"),
+		})
+	}
+	tasks = append(tasks, taskwarrior.Task{UUID: "plain", Description: "no date"})
+	s.setTasks(tasks)
+	if s.queue.Len() != 1000 {
+		t.Fatalf("expected 1000 queued notifications, got %d", s.queue.Len())
+	}
+	if w := s.nextWait(now); w != time.Minute {
+		t.Fatalf("expected to sleep until the first notification, got %s", w)
+	}
+	s.notifyDue(now.Add(4 * time.Minute))
+	if len(rn.messages) != 4 || s.queue.Len() != 996 {
+		t.Fatalf("expected 4 sent and 996 queued, got %d and %d", len(rn.messages), s.queue.Len())
+	}
+}
+
+func TestScheduler_RepeatIsRequeued(t *testing.T) {
+	cfg := &config.Config{NotificationMessage: "{{.Description}}"}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	now := time.Now().Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{repeatTask(now)})
+	s.notifyDue(now)
+	next, ok := s.queue.next()
+	if !ok || !next.Equal(now.Add(10*time.Minute)) {
+		t.Fatalf("expected repeat queued at +10m, got %v %v", next, ok)
+	}
+}
+
+func TestScheduler_RunFiresOnTime(t *testing.T) {
+	cfg := &config.Config{NotificationMessage: "{{.Description}}"}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	go s.run()
+	// The task arrives after run started sleeping; setTasks re-arms the timer
+	time.Sleep(20 * time.Millisecond)
+	due := time.Now().Add(time.Second).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{{UUID: "u1", Description: "on time", NotificationDate: due.UTC().Format(time.RFC3339)}})
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		rn.mu.Lock()
+		n := len(rn.messages)
+		rn.mu.Unlock()
+		if n == 1 {
+			if late := time.Since(due); late < 0 || late > 500*time.Millisecond {
+				t.Fatalf("notification fired %s after its time", late)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("notification was not sent")
+}
@@ -0,0 +1,252 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/state"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+// quietHours is the compiled quiet_hours config. It also holds the policy
+// and priority overrides applied while do-not-disturb is on.
+type quietHours struct {
+	windows  []quietWindow
+	policy   string
+	override map[string]bool // priorities notified anyway
+}
+
+// quietWindow is a compiled quiet window
+type quietWindow struct {
+	days       [7]bool       // by the weekday the window starts on
+	start, end time.Duration // since midnight
+}
+
+var quietDays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// buildQuietHours compiles the configured quiet windows
+func buildQuietHours(cfg *config.Config) (*quietHours, error) {
+	qc := cfg.QuietHours
+	q := &quietHours{policy: strings.ToLower(qc.Policy), override: map[string]bool{}}
+	switch q.policy {
+	case "":
+		q.policy = config.QuietDefer
+	case config.QuietDefer, config.QuietDrop:
+	default:
+		return nil, fmt.Errorf("unknown policy %q (want defer or drop)", qc.Policy)
+	}
+	for _, p := range qc.Override {
+		q.override[strings.ToUpper(p)] = true
+	}
+	for i, wc := range qc.Windows {
+		var w quietWindow
+		var err error
+		if w.start, err = parseTimeOfDay(wc.Start); err != nil {
+			return nil, fmt.Errorf("window %d: start: %w", i+1, err)
+		}
+		if w.end, err = parseTimeOfDay(wc.End); err != nil {
+			return nil, fmt.Errorf("window %d: end: %w", i+1, err)
+		}
+		if w.start == w.end {
+			return nil, fmt.Errorf("window %d: start and end are both %s", i+1, wc.Start)
+		}
+		if len(wc.Days) == 0 {
+			w.days = [7]bool{true, true, true, true, true, true, true}
+		}
+		for _, d := range wc.Days {
+			name := strings.ToLower(d)
+			if len(name) > 3 {
+				name = name[:3]
+			}
+			wd, ok := quietDays[name]
+			if !ok {
+				return nil, fmt.Errorf("window %d: unknown day %q", i+1, d)
+			}
+			w.days[wd] = true
+		}
+		q.windows = append(q.windows, w)
+	}
+	return q, nil
+}
+
+// parseTimeOfDay parses 22:00 or 22:00:30 into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time of day %q (want e.g. 22:00)", s)
+}
+
+// windowEnd returns the end of the quiet window t falls in. Windows are
+// evaluated in the configured timezone, so they follow DST changes.
+func (q *quietHours) windowEnd(t time.Time) (time.Time, bool) {
+	local := t.In(util.Location())
+	y, m, d := local.Date()
+	for _, w := range q.windows {
+		// A window that started yesterday may still be running
+		for _, day := range []int{d - 1, d} {
+			startDay := util.LocalTime(y, m, day, 0, 0, 0, util.Location())
+			if !w.days[startDay.Weekday()] {
+				continue
+			}
+			endDay := day
+			if w.end < w.start {
+				endDay++
+			}
+			start := atTimeOfDay(y, m, day, w.start)
+			end := atTimeOfDay(y, m, endDay, w.end)
+			if !t.Before(start) && t.Before(end) {
+				return end, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// atTimeOfDay returns the wall clock time of day on the given date
+func atTimeOfDay(y int, m time.Month, d int, tod time.Duration) time.Time {
+	h, min, sec := int(tod/time.Hour), int(tod%time.Hour/time.Minute), int(tod%time.Minute/time.Second)
+	return util.LocalTime(y, m, d, h, min, sec, util.Location())
+}
+
+// end returns when the quiet time t falls in ends, following adjacent
+// windows, e.g. a weekend window that starts when a night window ends
+func (q *quietHours) end(t time.Time) (time.Time, bool) {
+	end, quiet := t, false
+	for i := 0; i < 2*7*len(q.windows); i++ {
+		next, ok := q.windowEnd(end)
+		if !ok {
+			break
+		}
+		end, quiet = next, true
+	}
+	return end, quiet
+}
+
+// quietUntil reports whether notifications of a task are held back at t
+// by quiet hours or do-not-disturb, and until when. A zero time means until
+// do-not-disturb is turned off. Callers hold s.mu.
+func (s *scheduler) quietUntil(task taskwarrior.Task, t time.Time) (time.Time, bool) {
+	if s.quiet == nil || s.quiet.override[strings.ToUpper(task.Priority)] {
+		return time.Time{}, false
+	}
+	until, quiet := t, false
+	// Bounded, in case windows and do-not-disturb never end
+	for i := 0; i < 16; i++ {
+		moved := false
+		if dnd := s.state.DND; dnd != nil && (dnd.Until.IsZero() || until.Before(dnd.Until)) {
+			if dnd.Until.IsZero() {
+				return time.Time{}, true
+			}
+			until, moved = dnd.Until, true
+		}
+		if end, ok := s.quiet.end(until); ok {
+			until, moved = end, true
+		}
+		if !moved {
+			break
+		}
+		quiet = true
+	}
+	return until, quiet
+}
+
+// holdBack defers or, with the drop policy, drops a notification that is
+// due during quiet hours or do-not-disturb. Repeats are always deferred. It
+// reports whether the notification was held back and whether the state
+// changed; callers hold s.mu.
+func (s *scheduler) holdBack(task taskwarrior.Task, key string, repeat bool, now time.Time) (bool, bool) {
+	until, quiet := s.quietUntil(task, now)
+	if !quiet {
+		return false, false
+	}
+	if s.quiet.policy == config.QuietDrop && !repeat {
+		notifyLog.Info("Dropping notification during quiet hours", "task_uuid", task.UUID)
+		s.state.MarkDropped(key, task.UUID, now)
+		return true, true
+	}
+	if prev, ok := s.state.Deferred(key); ok && prev.Equal(until) {
+		return true, false
+	}
+	s.state.Defer(key, task.UUID, until)
+	if until.IsZero() {
+		notifyLog.Info("Deferring notification until do-not-disturb is turned off", "task_uuid", task.UUID)
+	} else {
+		notifyLog.Info("Deferring notification for quiet hours", "task_uuid", task.UUID, "until", until.In(util.Location()))
+	}
+	return true, true
+}
+
+// dndStatus implements web.DNDStatusFunc
+func (s *scheduler) dndStatus() (web.DNDStatus, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dndStatusAt(time.Now()), nil
+}
+
+// dndStatusAt reports do-not-disturb and quiet hours at now; callers hold s.mu
+func (s *scheduler) dndStatusAt(now time.Time) web.DNDStatus {
+	var st web.DNDStatus
+	if dnd := s.state.DND; dnd != nil && (dnd.Until.IsZero() || now.Before(dnd.Until)) {
+		st.Enabled = true
+		since := dnd.Since
+		st.Since = &since
+		if !dnd.Until.IsZero() {
+			until := dnd.Until
+			st.Until = &until
+		}
+	}
+	if s.quiet != nil {
+		if end, ok := s.quiet.end(now); ok {
+			st.QuietHours = true
+			st.QuietUntil = &end
+		}
+	}
+	return st
+}
+
+// setDND implements web.SetDNDFunc. Do-not-disturb is turned on until
+// req.Until, a duration or date such as "2h" or "tomorrow 07:00", or until
+// it is turned off. Notifications held back by it are re-evaluated.
+func (s *scheduler) setDND(req web.DNDRequest) (web.DNDStatus, error) {
+	now := time.Now()
+	var until time.Time
+	if req.Enabled && req.Until != "" {
+		var err error
+		if until, err = util.ParseDate(req.Until, now, util.Location()); err != nil {
+			return web.DNDStatus{}, fmt.Errorf("%w: until: %v", web.ErrBadRequest, err)
+		}
+		if !until.After(now) {
+			return web.DNDStatus{}, fmt.Errorf("%w: until %s is in the past", web.ErrBadRequest, until.Format("2006-01-02 15:04:05 MST"))
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if req.Enabled {
+		s.state.DND = &state.DND{Since: now, Until: until}
+		if until.IsZero() {
+			notifyLog.Info("Do-not-disturb turned on")
+		} else {
+			notifyLog.Info("Do-not-disturb turned on", "until", until.In(util.Location()))
+		}
+	} else {
+		s.state.DND = nil
+		notifyLog.Info("Do-not-disturb turned off")
+	}
+	// Held back notifications are due again now; those still in quiet
+	// hours are deferred again
+	s.state.ReleaseDeferrals(now)
+	s.save()
+	s.requeue()
+	s.signal()
+	return s.dndStatusAt(now), nil
+}
@@ -0,0 +1,212 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/state"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+func quietScheduler(t *testing.T, qc config.QuietHoursConfig) (*scheduler, *recordingNotifier) {
+	t.Helper()
+	cfg := &config.Config{NotificationMessage: "{{.Description}}", QuietHours: qc}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	var err error
+	if s.quiet, err = buildQuietHours(cfg); err != nil {
+		t.Fatalf("buildQuietHours: %v", err)
+	}
+	return s, rn
+}
+
+var nightly = config.QuietHoursConfig{Windows: []config.QuietWindow{{Start: "22:00", End: "07:00"}}}
+
+func quietTask(at time.Time, priority string) taskwarrior.Task {
+	return taskwarrior.Task{ID: 1, UUID: "u1", Description: "call", Priority: priority, NotificationDate: at.UTC().Format("20060102T150405Z")}
+}
+
+func TestQuietHours_End(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	q, err := buildQuietHours(&config.Config{QuietHours: config.QuietHoursConfig{Windows: []config.QuietWindow{
+		{Start: "22:00", End: "07:00"},
+		{Days: []string{"sat", "Sunday"}, Start: "07:00", End: "10:00"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(d, h, m int) time.Time { return time.Date(2025, 9, d, h, m, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		t     time.Time
+		want  time.Time
+		quiet bool
+	}{
+		{at(3, 23, 0), at(4, 7, 0), true},  // Wednesday night
+		{at(4, 6, 59), at(4, 7, 0), true},  // started yesterday
+		{at(3, 22, 0), at(4, 7, 0), true},  // start is inclusive
+		{at(4, 7, 0), time.Time{}, false},  // end is exclusive
+		{at(4, 12, 0), time.Time{}, false}, // daytime
+		{at(5, 23, 0), at(6, 10, 0), true}, // Friday night runs into the weekend window
+		{at(6, 8, 0), at(6, 10, 0), true},
+	} {
+		got, ok := q.end(tc.t)
+		if ok != tc.quiet || (ok && !got.Equal(tc.want)) {
+			t.Errorf("end(%v) = %v, %v; want %v, %v", tc.t, got, ok, tc.want, tc.quiet)
+		}
+	}
+}
+
+func TestQuietHours_FollowsTimezone(t *testing.T) {
+	util.SetLocation(mustLoadLocation(t, "Europe/Berlin"))
+	defer util.SetLocation(nil)
+	q, _ := buildQuietHours(&config.Config{QuietHours: nightly})
+	// 21:30 UTC is 23:30 in Berlin (CEST)
+	got, ok := q.end(time.Date(2025, 9, 3, 21, 30, 0, 0, time.UTC))
+	if want := time.Date(2025, 9, 4, 5, 0, 0, 0, time.UTC); !ok || !got.Equal(want) {
+		t.Fatalf("got %v, %v; want %v", got, ok, want)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestBuildQuietHours_Invalid(t *testing.T) {
+	for _, qc := range []config.QuietHoursConfig{
+		{Policy: "snooze"},
+		{Windows: []config.QuietWindow{{Start: "22", End: "07:00"}}},
+		{Windows: []config.QuietWindow{{Start: "22:00", End: "25:00"}}},
+		{Windows: []config.QuietWindow{{Start: "22:00", End: "22:00"}}},
+		{Windows: []config.QuietWindow{{Days: []string{"someday"}, Start: "22:00", End: "07:00"}}},
+	} {
+		if _, err := buildQuietHours(&config.Config{QuietHours: qc}); err == nil {
+			t.Errorf("expected error for %+v", qc)
+		}
+	}
+}
+
+func TestScheduler_QuietHoursDefer(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	s, rn := quietScheduler(t, nightly)
+	store := state.NewMemoryStore()
+	if err := s.useStore(store); err != nil {
+		t.Fatal(err)
+	}
+	night := time.Date(2025, 9, 3, 23, 0, 0, 0, time.UTC)
+	morning := time.Date(2025, 9, 4, 7, 0, 0, 0, time.UTC)
+	s.setTasks([]taskwarrior.Task{quietTask(night, "")})
+
+	s.notifyDue(night)
+	if len(rn.messages) != 0 {
+		t.Fatalf("expected no notification during quiet hours, got %v", rn.messages)
+	}
+	if next, ok := s.queue.next(); !ok || !next.Equal(morning) {
+		t.Fatalf("expected the notification to be queued at the end of quiet hours, got %v, %v", next, ok)
+	}
+
+	// The deferral survives a restart and is not treated as missed
+	restarted, rn2 := quietScheduler(t, nightly)
+	if err := restarted.useStore(store); err != nil {
+		t.Fatal(err)
+	}
+	restarted.setTasks([]taskwarrior.Task{quietTask(night, "")})
+	restarted.notifyDue(morning.Add(-time.Minute))
+	if len(rn2.messages) != 0 {
+		t.Fatalf("expected no notification before the end of quiet hours, got %v", rn2.messages)
+	}
+	restarted.notifyDue(morning.Add(time.Minute))
+	if len(rn2.messages) != 1 {
+		t.Fatalf("expected the deferred notification after quiet hours, got %v", rn2.messages)
+	}
+	if _, ok := restarted.state.Deferred(notifyKey("u1", quietTask(night, "").NotificationDate)); ok {
+		t.Error("expected the deferral to be cleared once sent")
+	}
+}
+
+func TestScheduler_QuietHoursPriorityOverride(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	qc := nightly
+	qc.Override = []string{"h"}
+	s, rn := quietScheduler(t, qc)
+	night := time.Date(2025, 9, 3, 23, 0, 0, 0, time.UTC)
+	s.setTasks([]taskwarrior.Task{quietTask(night, "H")})
+	s.notifyDue(night)
+	if len(rn.messages) != 1 {
+		t.Fatalf("expected a high priority task to break through, got %v", rn.messages)
+	}
+}
+
+func TestScheduler_QuietHoursDrop(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	qc := nightly
+	qc.Policy = config.QuietDrop
+	s, rn := quietScheduler(t, qc)
+	night := time.Date(2025, 9, 3, 23, 0, 0, 0, time.UTC)
+	s.setTasks([]taskwarrior.Task{quietTask(night, "")})
+	s.notifyDue(night)
+	s.notifyDue(time.Date(2025, 9, 4, 8, 0, 0, 0, time.UTC))
+	if len(rn.messages) != 0 {
+		t.Fatalf("expected the notification to be dropped, got %v", rn.messages)
+	}
+	if !s.state.Handled(notifyKey("u1", quietTask(night, "").NotificationDate)) {
+		t.Error("expected the dropped notification to be recorded")
+	}
+}
+
+func TestScheduler_DoNotDisturb(t *testing.T) {
+	s, rn := quietScheduler(t, config.QuietHoursConfig{})
+	now := time.Now().Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{quietTask(now.Add(-time.Minute), "")})
+
+	st, err := s.setDND(web.DNDRequest{Enabled: true})
+	if err != nil || !st.Enabled || st.Until != nil {
+		t.Fatalf("setDND: %+v, %v", st, err)
+	}
+	s.notifyDue(now)
+	if len(rn.messages) != 0 {
+		t.Fatalf("expected no notification during do-not-disturb, got %v", rn.messages)
+	}
+	if _, ok := s.queue.next(); ok {
+		t.Fatal("expected nothing queued until do-not-disturb is turned off")
+	}
+
+	if st, err = s.setDND(web.DNDRequest{}); err != nil || st.Enabled {
+		t.Fatalf("setDND off: %+v, %v", st, err)
+	}
+	s.notifyDue(time.Now())
+	if len(rn.messages) != 1 {
+		t.Fatalf("expected the held notification once do-not-disturb is off, got %v", rn.messages)
+	}
+}
+
+func TestScheduler_DoNotDisturbUntil(t *testing.T) {
+	s, rn := quietScheduler(t, config.QuietHoursConfig{})
+	now := time.Now().Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{quietTask(now.Add(-time.Minute), "")})
+	st, err := s.setDND(web.DNDRequest{Enabled: true, Until: "2h"})
+	if err != nil || st.Until == nil {
+		t.Fatalf("setDND: %+v, %v", st, err)
+	}
+	s.notifyDue(time.Now())
+	if next, ok := s.queue.next(); len(rn.messages) != 0 || !ok || !next.Equal(*st.Until) {
+		t.Fatalf("expected the notification deferred to %v, got %v queued at %v", *st.Until, rn.messages, next)
+	}
+	for _, until := range []string{"soon", "yesterday"} {
+		if _, err := s.setDND(web.DNDRequest{Enabled: true, Until: until}); err == nil {
+			t.Errorf("expected error for until %q", until)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package app
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+// defaultWatchInterval is how often a watched config file is checked
+const defaultWatchInterval = 2 * time.Second
+
+// restartKeys are config sections that are only read at startup
+var restartKeys = []string{"http.", "state.", "hooks."}
+
+// components is everything built from a config that a reload replaces
+type components struct {
+	cfg         *config.Config
+	loc         *time.Location
+	notifier    typeNotifier
+	routes      []*route
+	reminders   []reminder
+	quiet       *quietHours
+	escalations []escalation
+	digests     []digest
+	baseSource  taskwarrior.TaskSource // source before transforms
+	source      taskwarrior.TaskSource
+	// exportFilter is taskwarrior.filter for tasks forwarded by the hook;
+	// exportFilterErr is set if it cannot be matched here
+	exportFilter    *taskwarrior.Filter
+	exportFilterErr error
+	pipeline        *taskwarrior.Pipeline // transforms applied to source
+	sync            bool                  // run task sync; export snapshots are not Taskwarrior replicas
+}
+
+// buildComponents validates cfg by building everything Run needs from it
+func buildComponents(cfg *config.Config) (*components, error) {
+	c, err := compileComponents(cfg, newBackendFunc)
+	if err != nil {
+		return nil, err
+	}
+	// Report the default backend before anything was sent
+	notificationsSent.Add(0, defaultBackend(cfg))
+	notificationsFailed.Add(0, defaultBackend(cfg))
+
+	if c.baseSource, err = newTaskSourceFunc(cfg); err != nil {
+		return nil, fmt.Errorf("failed to create task source: %w", err)
+	}
+	c.source = c.baseSource
+	switch c.baseSource.(type) {
+	case taskwarrior.FileSource, taskwarrior.DirSource:
+		if c.pipeline != nil {
+			pollLog.Warn("Transforms modify tasks with the task command; ignored for this source", "source", cfg.Source.Type)
+		}
+	default:
+		c.sync = true
+		if _, ok := c.baseSource.(taskwarrior.CLISource); ok && cfg.Taskwarrior.Filter != "" {
+			if c.exportFilter, c.exportFilterErr = taskwarrior.ParseFilter(cfg.Taskwarrior.Filter); c.exportFilterErr != nil {
+				hookLog.Warn("taskwarrior.filter cannot be matched against hook updates; new tasks are added by the next poll", "error", c.exportFilterErr)
+			}
+		}
+		if c.pipeline != nil {
+			pollLog.Info("Task transforms enabled", "transforms", c.pipeline.String())
+			c.source = taskwarrior.TransformSource{TaskSource: c.baseSource, Pipeline: c.pipeline}
+		}
+	}
+	return c, nil
+}
+
+// compileComponents builds the parts of the components that only depend on
+// cfg, with notifiers from newBackend. It has no side effects of its own, so
+// with checkBackend it validates cfg without touching Taskwarrior or the
+// network.
+func compileComponents(cfg *config.Config, newBackend backendFunc) (*components, error) {
+	if cfg.PollInterval <= 0 {
+		return nil, fmt.Errorf("poll_interval must be positive")
+	}
+	if cfg.SyncInterval < 0 {
+		return nil, fmt.Errorf("sync_interval must not be negative")
+	}
+	c := &components{cfg: cfg}
+	var err error
+	if c.loc, err = cfg.Location(); err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", cfg.Timezone, err)
+	}
+
+	// Backends report failed sends with this logger
+	loggerFunc := func(format string, v ...interface{}) {
+		notifyLog.Warn(fmt.Sprintf(format, v...))
+	}
+	if c.notifier, err = newBackend(cfg, loggerFunc); err != nil {
+		return nil, fmt.Errorf("failed to create notifier: %w", err)
+	}
+	if c.routes, err = buildRoutes(cfg, c.notifier, newBackend, loggerFunc); err != nil {
+		return nil, fmt.Errorf("invalid routes: %w", err)
+	}
+	if c.reminders, err = buildReminders(cfg); err != nil {
+		return nil, fmt.Errorf("invalid reminders: %w", err)
+	}
+	if c.quiet, err = buildQuietHours(cfg); err != nil {
+		return nil, fmt.Errorf("invalid quiet_hours: %w", err)
+	}
+	if c.escalations, err = buildEscalations(cfg, c.notifier, newBackend, loggerFunc); err != nil {
+		return nil, fmt.Errorf("invalid escalations: %w", err)
+	}
+	if c.digests, err = buildDigests(cfg, c.notifier, newBackend, loggerFunc); err != nil {
+		return nil, fmt.Errorf("invalid digests: %w", err)
+	}
+
+	if c.pipeline, err = taskwarrior.NewPipeline(cfg.Transforms, udaName(cfg, "notification_date")); err != nil {
+		return nil, fmt.Errorf("invalid transforms: %w", err)
+	}
+	return c, nil
+}
+
+// logSummary logs what the components enable
+func (c *components) logSummary() {
+	if len(c.quiet.windows) > 0 {
+		notifyLog.Info("Loaded quiet hours", "windows", len(c.quiet.windows), "policy", c.quiet.policy)
+	}
+	if len(c.routes) > 0 {
+		notifyLog.Info("Loaded notification routes", "count", len(c.routes))
+	}
+}
+
+// start runs the poller, task sync and digests until the returned channel
+// is closed
+func (c *components) start(sched *scheduler, taskCh chan []taskwarrior.Task) chan struct{} {
+	stop := make(chan struct{})
+	if c.sync {
+		go syncTaskwarriorFunc(stop)
+	}
+	go pollerFunc(c.source, c.cfg.PollInterval, taskCh, stop)
+	if len(c.digests) > 0 {
+		go sched.runDigests(c.digests, stop)
+	}
+	return stop
+}
+
+// reconfigure makes the scheduler use the given components
+func (s *scheduler) reconfigure(c *components) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = c.cfg
+	s.notifier = c.notifier
+	s.routes = c.routes
+	s.reminders = c.reminders
+	s.quiet = c.quiet
+	s.escalations = c.escalations
+	s.exportFilter, s.exportFilterErr = c.exportFilter, c.exportFilterErr
+	s.requeue()
+	s.signal()
+}
+
+// reloader replaces the running config with the one in path
+type reloader struct {
+	path    string
+	sched   *scheduler
+	taskCh  chan []taskwarrior.Task
+	current *components
+	stop    chan struct{} // stops the workers started for current
+}
+
+// reload loads, validates and applies the config file. An invalid config
+// is rejected and the running one is kept.
+func (r *reloader) reload() error {
+	cfg, err := loadConfigFunc(r.path)
+	if err != nil {
+		appLog.Error("Rejected config, keeping the running one", "path", r.path, "error", err)
+		return err
+	}
+	diff := config.Diff(r.current.cfg, cfg)
+	if len(diff) == 0 {
+		// Still reopen the log output, e.g. after logrotate moved the file
+		if err := config.ConfigureLogging(cfg); err != nil {
+			appLog.Error("Failed to reopen the log output", "error", err)
+		}
+		appLog.Info("Config unchanged", "path", r.path)
+		return nil
+	}
+	c, err := buildComponents(cfg)
+	if err != nil {
+		appLog.Error("Rejected config, keeping the running one", "path", r.path, "error", err, "changes", diff)
+		return err
+	}
+	if err := config.ConfigureLogging(cfg); err != nil {
+		appLog.Error("Rejected config, keeping the running one", "path", r.path, "error", err, "changes", diff)
+		return err
+	}
+	for _, line := range diff {
+		for _, key := range restartKeys {
+			if strings.HasPrefix(line, key) {
+				appLog.Warn("Setting takes effect after a restart", "setting", strings.SplitN(line, ":", 2)[0])
+			}
+		}
+	}
+
+	config.Set(cfg)
+	util.SetLocation(c.loc)
+	close(r.stop)
+	r.sched.reconfigure(c)
+	r.stop = c.start(r.sched, r.taskCh)
+	r.current = c
+
+	appLog.Info("Applied config", "path", r.path, "changes", diff)
+	c.logSummary()
+	return nil
+}
+
+// notifySignals returns a channel that receives a value for each of the
+// given signals
+func notifySignals(sig ...os.Signal) <-chan struct{} {
+	in := make(chan os.Signal, 1)
+	signal.Notify(in, sig...)
+	out := make(chan struct{}, 1)
+	go func() {
+		for range in {
+			select {
+			case out <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return out
+}
+
+// watchConfig requests a reload when the contents of path change, while
+// reload.watch is set, until stop is closed
+func watchConfig(path string, reload chan<- struct{}, stop <-chan struct{}) {
+	last := fileHash(path)
+	for {
+		interval := defaultWatchInterval
+		watch := false
+		if cfg := config.Get(); cfg != nil {
+			watch = cfg.Reload.Watch
+			if cfg.Reload.Interval > 0 {
+				interval = cfg.Reload.Interval
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-time.After(interval):
+		}
+		h := fileHash(path)
+		if h == last {
+			continue
+		}
+		last = h
+		// Missing files are usually being replaced; wait for the new one
+		if watch && h != "" {
+			select {
+			case reload <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// fileHash returns a hash of the contents of path, or "" if it can't be read
+func fileHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
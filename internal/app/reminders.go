@@ -0,0 +1,116 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+// reminder is a compiled reminder rule
+type reminder struct {
+	anchor   string
+	offset   util.Offset // negative before the anchor date
+	label    string      // e.g. "1d before due"; part of the state key
+	priority string
+	filter   *taskwarrior.Filter
+}
+
+// notification is a single notification instant of a task
+type notification struct {
+	at  time.Time
+	key string // state key, see notifyKey
+}
+
+// buildReminders compiles the configured reminder rules
+func buildReminders(cfg *config.Config) ([]reminder, error) {
+	var out []reminder
+	for i, rc := range cfg.Reminders {
+		r := reminder{anchor: strings.ToLower(rc.Anchor), priority: strings.ToUpper(rc.Priority)}
+		if r.anchor == "" {
+			r.anchor = "due"
+		}
+		if rc.Before != "" && rc.After != "" {
+			return nil, fmt.Errorf("reminder %d: set either before or after, not both", i+1)
+		}
+		r.label = "at " + r.anchor
+		for _, o := range []struct {
+			value, word string
+			before      bool
+		}{{rc.Before, "before", true}, {rc.After, "after", false}} {
+			if o.value == "" {
+				continue
+			}
+			off, err := util.ParseOffset(o.value)
+			if err != nil {
+				return nil, fmt.Errorf("reminder %d: %w", i+1, err)
+			}
+			if o.before {
+				off = off.Neg()
+			}
+			r.offset = off
+			r.label = fmt.Sprintf("%s %s %s", o.value, o.word, r.anchor)
+		}
+		if rc.Filter != "" {
+			f, err := taskwarrior.ParseFilter(rc.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("reminder %d: %w", i+1, err)
+			}
+			r.filter = f
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// at returns when the reminder fires for a task, if it applies to it
+func (r reminder) at(task taskwarrior.Task) (time.Time, bool) {
+	if r.priority != "" && !strings.EqualFold(task.Priority, r.priority) {
+		return time.Time{}, false
+	}
+	if r.filter != nil && !r.filter.Match(task) {
+		return time.Time{}, false
+	}
+	v, ok := task.Attribute(r.anchor)
+	if !ok {
+		return time.Time{}, false
+	}
+	anchor, err := util.ParseNotificationDate(v)
+	if err != nil {
+		notifyLog.Debug("Cannot parse reminder anchor", "task_uuid", task.UUID, "anchor", r.anchor, "value", v, "error", err)
+		return time.Time{}, false
+	}
+	// Days keep the wall clock time in the configured timezone across DST
+	return r.offset.AddTo(anchor.In(util.Location())), true
+}
+
+// notifications returns the notification instants of a task: its earliest
+// notification date, the end of a snooze and one per applicable reminder
+// rule. Reminder keys include the fire time so moving the anchor date
+// re-arms the reminder. Callers hold s.mu.
+func (s *scheduler) notifications(task taskwarrior.Task) []notification {
+	var out []notification
+	at, nd := nextNotification(task)
+	if !at.IsZero() {
+		out = append(out, notification{at: at, key: notifyKey(task.UUID, nd)})
+	}
+	// A snooze re-arms the task even if the notification_date it wrote was
+	// not polled yet or did not reach Taskwarrior; the key matches the
+	// written date so the task is notified once. Dates moved past the
+	// snooze win.
+	if until, ok := s.state.Snoozed(task.UUID); ok && (at.IsZero() || at.Before(until)) {
+		out = append(out, notification{at: until, key: notifyKey(task.UUID, until.UTC().Format("20060102T150405Z"))})
+	}
+	for _, r := range s.reminders {
+		at, ok := r.at(task)
+		if !ok {
+			continue
+		}
+		key := notifyKey(task.UUID, r.label+"@"+at.UTC().Format("20060102T150405Z"))
+		out = append(out, notification{at: at, key: key})
+	}
+	return out
+}
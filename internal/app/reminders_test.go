@@ -0,0 +1,125 @@
+package app
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+)
+
+func reminderScheduler(t *testing.T, reminders []config.ReminderConfig) (*scheduler, *recordingNotifier) {
+	t.Helper()
+	cfg := &config.Config{NotificationMessage: "{{.Description}}", Reminders: reminders}
+	config.Set(cfg)
+	rn := &recordingNotifier{}
+	s := newScheduler(cfg, rn)
+	var err error
+	if s.reminders, err = buildReminders(cfg); err != nil {
+		t.Fatalf("buildReminders: %v", err)
+	}
+	return s, rn
+}
+
+func TestReminders_OffsetsFromDue(t *testing.T) {
+	s, rn := reminderScheduler(t, []config.ReminderConfig{
+		{Before: "1d"},
+		{Anchor: "due", Before: "15m", Priority: "H"},
+	})
+	now := time.Now().Truncate(time.Second)
+	due := now.Add(24 * time.Hour)
+	s.setTasks([]taskwarrior.Task{
+		{UUID: "high", Description: "high", Priority: "H", Due: due.UTC().Format("20060102T150405Z")},
+		{UUID: "low", Description: "low", Due: due.UTC().Format("20060102T150405Z")},
+		{UUID: "none", Description: "no due date"},
+	})
+
+	s.notifyDue(now)
+	if len(rn.messages) != 2 {
+		t.Fatalf("expected the 1d reminder for both tasks, got %v", rn.messages)
+	}
+	s.notifyDue(now.Add(time.Hour))
+	if len(rn.messages) != 2 {
+		t.Fatalf("expected no repeats, got %v", rn.messages)
+	}
+	s.notifyDue(due.Add(-15 * time.Minute))
+	if len(rn.messages) != 3 || rn.messages[2] != "high" {
+		t.Fatalf("expected the 15m reminder for the H task only, got %v", rn.messages)
+	}
+}
+
+func TestReminders_MovedAnchorRearms(t *testing.T) {
+	s, rn := reminderScheduler(t, []config.ReminderConfig{{Anchor: "scheduled"}})
+	now := time.Now().Truncate(time.Second)
+	task := taskwarrior.Task{UUID: "u1", Description: "call", Scheduled: now.UTC().Format("20060102T150405Z")}
+	s.setTasks([]taskwarrior.Task{task})
+	s.notifyDue(now)
+	if len(rn.messages) != 1 {
+		t.Fatalf("expected reminder at the scheduled date, got %v", rn.messages)
+	}
+	later := now.Add(2 * time.Hour)
+	task.Scheduled = later.UTC().Format("20060102T150405Z")
+	s.setTasks([]taskwarrior.Task{task})
+	s.notifyDue(later)
+	if len(rn.messages) != 2 {
+		t.Fatalf("expected reminder again after rescheduling, got %v", rn.messages)
+	}
+}
+
+func TestReminders_AcknowledgeKeepsLaterReminders(t *testing.T) {
+	s, rn := reminderScheduler(t, []config.ReminderConfig{{Before: "1h"}, {Before: "10m"}})
+	now := time.Now().Truncate(time.Second)
+	due := now.Add(time.Hour)
+	s.setTasks([]taskwarrior.Task{{UUID: "u1", Description: "meeting", Due: due.UTC().Format("20060102T150405Z")}})
+	s.notifyDue(now)
+	if err := s.acknowledge("u1", ""); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	s.notifyDue(due.Add(-10 * time.Minute))
+	if len(rn.messages) != 2 {
+		t.Fatalf("expected the later reminder after acknowledging the first, got %v", rn.messages)
+	}
+}
+
+func TestReminders_DateUDAAnchorFromTaskChampion(t *testing.T) {
+	s, _ := reminderScheduler(t, []config.ReminderConfig{{Anchor: "followup", Before: "1h"}})
+	src, err := taskwarrior.NewSource(config.SourceConfig{Type: "taskchampion", Path: filepath.Join("..", "taskwarrior", "testdata", "taskchampion.sqlite3")}, dateUDAs(config.Get()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := src.Tasks()
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	var rent taskwarrior.Task
+	for _, task := range tasks {
+		if task.Description == "Pay rent" {
+			rent = task
+		}
+	}
+	// followup is stored as epoch seconds 1756800000
+	at, ok := s.reminders[0].at(rent)
+	if want := time.Unix(1756800000, 0).Add(-time.Hour); !ok || !at.Equal(want) {
+		t.Fatalf("expected reminder at %v, got %v %v (followup %v)", want, at, ok, rent.UDAs["followup"])
+	}
+}
+
+func TestBuildReminders(t *testing.T) {
+	s, _ := reminderScheduler(t, []config.ReminderConfig{{Before: "1d"}, {Anchor: "Wait", After: "PT30M", Filter: "+remind"}, {Anchor: "until"}})
+	want := []struct {
+		label  string
+		offset util.Offset
+	}{{"1d before due", util.Offset{Days: -1}}, {"PT30M after wait", util.Offset{Clock: 30 * time.Minute}}, {"at until", util.Offset{}}}
+	for i, w := range want {
+		if s.reminders[i].label != w.label || s.reminders[i].offset != w.offset {
+			t.Errorf("reminder %d: got %q %+v, want %q %+v", i, s.reminders[i].label, s.reminders[i].offset, w.label, w.offset)
+		}
+	}
+	for _, bad := range []config.ReminderConfig{{Before: "soon"}, {Before: "1d", After: "1h"}, {Filter: "(+x"}} {
+		if _, err := buildReminders(&config.Config{Reminders: []config.ReminderConfig{bad}}); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
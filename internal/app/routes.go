@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+// route is a compiled routing rule
+type route struct {
+	name     string
+	project  string
+	tags     []string
+	priority string
+	filter   *taskwarrior.Filter
+	cont     bool
+	target   routeTarget
+}
+
+// routeTarget is where a notification is sent and how it is rendered
+type routeTarget struct {
+	name     string
+	backend  string // notifier backend, e.g. ntfy, for metrics
+	notifier typeNotifier
+	headers  map[string]string // extra header templates, merged over ntfy.headers
+	message  string            // message template; empty uses notification_message
+}
+
+// buildRoutes compiles the configured routes. Targets that use the default
+// backend and topic share the default notifier; other targets get their own
+// notifier, shared between routes with the same backend and topic.
+func buildRoutes(cfg *config.Config, defaultNotifier typeNotifier, newBackend backendFunc, logger func(format string, v ...interface{})) ([]*route, error) {
+	senders := map[string]typeNotifier{}
+	var routes []*route
+	for i, rc := range cfg.Routes {
+		name := rc.Name
+		if name == "" {
+			name = fmt.Sprintf("route %d", i+1)
+		}
+		r := &route{
+			name:     name,
+			project:  strings.ToLower(rc.Project),
+			tags:     rc.Tags,
+			priority: strings.ToUpper(rc.Priority),
+			cont:     rc.Continue,
+		}
+		if rc.Filter != "" {
+			f, err := taskwarrior.ParseFilter(rc.Filter)
+			if err != nil {
+				return nil, fmt.Errorf("route %q: %w", name, err)
+			}
+			r.filter = f
+		}
+		n, err := targetNotifier(cfg, rc.Target, defaultNotifier, newBackend, senders, logger)
+		if err != nil {
+			return nil, fmt.Errorf("route %q: %w", name, err)
+		}
+		r.target = routeTarget{name: name, backend: targetBackend(cfg, rc.Target), notifier: n, headers: rc.Target.Headers, message: rc.Target.Message}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
+
+// targetNotifier returns the notifier for a route target, creating one per
+// backend and topic with newBackend and caching it
+func targetNotifier(cfg *config.Config, t config.RouteTarget, defaultNotifier typeNotifier, newBackend backendFunc, cache map[string]typeNotifier, logger func(format string, v ...interface{})) (typeNotifier, error) {
+	backend := targetBackend(cfg, t)
+	if backend != "ntfy" && t.Topic != "" {
+		return nil, fmt.Errorf("topic is only supported by the ntfy backend, not %s", backend)
+	}
+	if backend == defaultBackend(cfg) && t.Topic == "" {
+		return defaultNotifier, nil
+	}
+	key := backend + "|" + t.Topic
+	if n, ok := cache[key]; ok {
+		return n, nil
+	}
+	c := *cfg
+	c.Notifier = backend
+	if t.Topic != "" {
+		c.Ntfy.Topic = t.Topic
+		c.Ntfy.TopicFile = ""
+	}
+	n, err := newBackend(&c, logger)
+	if err != nil {
+		return nil, err
+	}
+	cache[key] = n
+	return n, nil
+}
+
+// matches reports whether all conditions of the route hold for the task
+func (r *route) matches(task taskwarrior.Task) bool {
+	if r.project != "" {
+		p := strings.ToLower(task.Project)
+		if p != r.project && !strings.HasPrefix(p, r.project+".") {
+			return false
+		}
+	}
+	for _, tag := range r.tags {
+		if !task.HasTag(strings.TrimPrefix(tag, "+")) {
+			return false
+		}
+	}
+	if r.priority != "" && !strings.EqualFold(task.Priority, r.priority) {
+		return false
+	}
+	if r.filter != nil && !r.filter.Match(task) {
+		return false
+	}
+	return true
+}
+
+// targets returns the targets a task is routed to: the first matching route
+// (plus any following matches while routes have continue set), or the
+// default notifier when no route matches
+func (s *scheduler) targets(task taskwarrior.Task) []routeTarget {
+	var out []routeTarget
+	for _, r := range s.routes {
+		if !r.matches(task) {
+			continue
+		}
+		out = append(out, r.target)
+		if !r.cont {
+			break
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, s.defaultTarget())
+	}
+	return out
+}
+
+// defaultTarget sends with the default notifier
+func (s *scheduler) defaultTarget() routeTarget {
+	return routeTarget{name: "default", backend: defaultBackend(s.cfg), notifier: s.notifier}
+}
+
+// defaultBackend returns the name of the default notifier backend
+func defaultBackend(cfg *config.Config) string {
+	if cfg.Notifier == "" {
+		return "ntfy"
+	}
+	return cfg.Notifier
+}
+
+// targetBackend returns the name of the backend a target sends with
+func targetBackend(cfg *config.Config, t config.RouteTarget) string {
+	if t.Backend != "" {
+		return t.Backend
+	}
+	return defaultBackend(cfg)
+}
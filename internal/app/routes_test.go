@@ -0,0 +1,148 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+// routedScheduler builds a scheduler whose ntfy notifiers are recorded per topic
+func routedScheduler(t *testing.T, cfg *config.Config) (*scheduler, map[string]*recordingNotifier) {
+	t.Helper()
+	orig := newNotifierFunc
+	t.Cleanup(func() { newNotifierFunc = orig })
+	byTopic := map[string]*recordingNotifier{}
+	newNotifierFunc = func(c config.NtfyConfig, logger func(format string, v ...interface{})) typeNotifier {
+		rn := &recordingNotifier{}
+		byTopic[c.Topic] = rn
+		return rn
+	}
+	config.Set(cfg)
+	def, err := newBackendFunc(cfg, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newScheduler(cfg, def)
+	if s.routes, err = buildRoutes(cfg, def, newBackendFunc, t.Logf); err != nil {
+		t.Fatalf("buildRoutes: %v", err)
+	}
+	return s, byTopic
+}
+
+func TestRoutes_SendToMatchingTopic(t *testing.T) {
+	cfg := &config.Config{
+		NotificationMessage: "{{.Description}}",
+		Ntfy:                config.NtfyConfig{Topic: "main"},
+		Routes: []config.RouteConfig{
+			{Name: "work", Tags: []string{"work"}, Target: config.RouteTarget{Topic: "work", Message: "[work] {{.Description}}"}},
+			{Name: "household", Project: "home", Target: config.RouteTarget{Topic: "family", Headers: map[string]string{"X-Tags": "house"}}},
+			{Name: "urgent", Filter: "priority:H and -work", Target: config.RouteTarget{Topic: "urgent"}},
+		},
+	}
+	s, byTopic := routedScheduler(t, cfg)
+	now := time.Now().Truncate(time.Second)
+	due := now.Add(-time.Minute).UTC().Format("20060102T150405Z")
+	s.setTasks([]taskwarrior.Task{
+		{UUID: "u1", Description: "standup", Tags: []string{"work"}, NotificationDate: due},
+		{UUID: "u2", Description: "laundry", Project: "home.chores", NotificationDate: due},
+		{UUID: "u3", Description: "dentist", Priority: "H", NotificationDate: due},
+		{UUID: "u4", Description: "read", NotificationDate: due},
+	})
+	s.notifyDue(now)
+
+	want := map[string]string{"work": "[work] standup", "family": "laundry", "urgent": "dentist", "main": "read"}
+	for topic, msg := range want {
+		rn := byTopic[topic]
+		if rn == nil || len(rn.messages) != 1 || rn.messages[0] != msg {
+			t.Errorf("topic %s: expected [%s], got %+v", topic, msg, rn)
+		}
+	}
+	if h := byTopic["family"].headers[0]; h["X-Tags"] != "house" || h["X-Title"] != "home.chores" {
+		t.Errorf("unexpected family headers: %+v", h)
+	}
+}
+
+func TestRoutes_Continue(t *testing.T) {
+	cfg := &config.Config{
+		NotificationMessage: "{{.Description}}",
+		Ntfy:                config.NtfyConfig{Topic: "main"},
+		Routes: []config.RouteConfig{
+			{Tags: []string{"work"}, Target: config.RouteTarget{Topic: "work"}, Continue: true},
+			{Priority: "h", Target: config.RouteTarget{Topic: "urgent"}},
+		},
+	}
+	s, byTopic := routedScheduler(t, cfg)
+	got := s.targets(taskwarrior.Task{Tags: []string{"work"}, Priority: "H"})
+	if len(got) != 2 || got[0].notifier != byTopic["work"] || got[1].notifier != byTopic["urgent"] {
+		t.Errorf("expected work and urgent targets, got %+v", got)
+	}
+	got = s.targets(taskwarrior.Task{Tags: []string{"work"}})
+	if len(got) != 1 || got[0].notifier != byTopic["work"] {
+		t.Errorf("expected work target only, got %+v", got)
+	}
+	got = s.targets(taskwarrior.Task{})
+	if len(got) != 1 || got[0].notifier != s.notifier {
+		t.Errorf("expected default target, got %+v", got)
+	}
+}
+
+func TestRoute_FilterOnUDA(t *testing.T) {
+	f, err := taskwarrior.ParseFilter("estimate.above:2 and reviewer:sam")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &route{filter: f}
+	task := taskwarrior.Task{UDAs: map[string]interface{}{"estimate": float64(3), "reviewer": "sam"}}
+	if !r.matches(task) {
+		t.Error("expected route to match on UDAs")
+	}
+	task.UDAs["estimate"] = float64(1)
+	if r.matches(task) {
+		t.Error("expected route not to match a smaller estimate")
+	}
+}
+
+func TestRoute_ProjectHierarchy(t *testing.T) {
+	r := &route{project: "work"}
+	for project, want := range map[string]bool{"work": true, "Work.email": true, "workshop": false, "": false} {
+		if got := r.matches(taskwarrior.Task{Project: project}); got != want {
+			t.Errorf("project %q: got %v, want %v", project, got, want)
+		}
+	}
+}
+
+func TestBuildRoutes_SharesNotifiers(t *testing.T) {
+	cfg := &config.Config{
+		Ntfy: config.NtfyConfig{Topic: "main"},
+		Routes: []config.RouteConfig{
+			{Tags: []string{"a"}, Target: config.RouteTarget{Topic: "work"}},
+			{Tags: []string{"b"}, Target: config.RouteTarget{Topic: "work"}},
+			{Tags: []string{"c"}, Target: config.RouteTarget{Backend: "ntfy"}},
+		},
+	}
+	s, _ := routedScheduler(t, cfg)
+	if s.routes[0].target.notifier != s.routes[1].target.notifier {
+		t.Error("expected routes with the same topic to share a notifier")
+	}
+	if s.routes[2].target.notifier != s.notifier {
+		t.Error("expected route without overrides to use the default notifier")
+	}
+}
+
+func TestBuildRoutes_Errors(t *testing.T) {
+	cases := []config.RouteConfig{
+		{Name: "bad filter", Filter: "(+work"},
+		{Name: "topic on gotify", Target: config.RouteTarget{Backend: "gotify", Topic: "x"}},
+		{Name: "unknown backend", Target: config.RouteTarget{Backend: "pigeon"}},
+	}
+	for _, rc := range cases {
+		cfg := &config.Config{Routes: []config.RouteConfig{rc}}
+		_, err := buildRoutes(cfg, &recordingNotifier{}, newBackendFunc, t.Logf)
+		if err == nil || !strings.Contains(err.Error(), rc.Name) {
+			t.Errorf("%s: expected error naming the route, got %v", rc.Name, err)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"testing"
@@ -11,9 +13,28 @@ import (
 
 	"task-herald/internal/config"
 	"task-herald/internal/notify"
+	"task-herald/internal/state"
 	"task-herald/internal/taskwarrior"
 )
 
+// TestMain keeps the state of Run in memory, so tests do not see
+// notifications sent by earlier tests, and moves the state dir (e.g. for the
+// action secret) to a temporary dir
+func TestMain(m *testing.M) {
+	openStateStoreFunc = func(*config.Config) (state.Store, error) {
+		return state.NewMemoryStore(), nil
+	}
+	dir, err := os.MkdirTemp("", "task-herald-state")
+	if err != nil {
+		panic(err)
+	}
+	os.Unsetenv("STATE_DIRECTORY")
+	os.Setenv("XDG_STATE_HOME", dir)
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 // fakeNotifier tracks Send calls
 type fakeNotifier struct {
 	mu         sync.Mutex
@@ -53,7 +74,7 @@ func TestRun_Integration_ShortLived(t *testing.T) {
 	// Override pollerFunc to send a single task then close
 	origPoller := pollerFunc
 	defer func() { pollerFunc = origPoller }()
-	pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+	pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 		out <- []taskwarrior.Task{{ID: 1, UUID: "u1", Description: "t1", NotificationDate: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), Tags: []string{}, Priority: "L", Project: "p1", Status: "pending"}}
 		close(out)
 	}
@@ -133,7 +154,7 @@ func TestRun_NoProjectHeader(t *testing.T) {
 
 	origPoller := pollerFunc
 	defer func() { pollerFunc = origPoller }()
-	pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+	pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 		out <- []taskwarrior.Task{{ID: 2, UUID: "u2", Description: "no-project", NotificationDate: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), Tags: []string{"a"}, Priority: "L", Project: "", Status: "pending"}}
 		close(out)
 	}
@@ -207,7 +228,7 @@ func TestRun_PriorityMappings(t *testing.T) {
 
 			origPoller := pollerFunc
 			defer func() { pollerFunc = origPoller }()
-			pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+			pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 				out <- []taskwarrior.Task{{ID: 3, UUID: "u3", Description: "prio-test", NotificationDate: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), Tags: []string{}, Priority: tc.prio, Project: "proj", Status: "pending"}}
 				close(out)
 			}
@@ -272,7 +293,7 @@ func TestRun_HeaderMerging(t *testing.T) {
 
 	origPoller := pollerFunc
 	defer func() { pollerFunc = origPoller }()
-	pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+	pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 		out <- []taskwarrior.Task{{ID: 4, UUID: "u4", Description: "hdr-test", NotificationDate: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), Tags: []string{}, Priority: "L", Project: "proj", Status: "pending"}}
 		close(out)
 	}
@@ -356,7 +377,7 @@ func TestRun_XActionsTemplate(t *testing.T) {
 	// Provide poller that sends a task and closes
 	origPoller := pollerFunc
 	defer func() { pollerFunc = origPoller }()
-	pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+	pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 		out <- []taskwarrior.Task{{ID: 5, UUID: "u/with spaces", Description: "act", NotificationDate: time.Now().Add(-1 * time.Minute).Format(time.RFC3339), Tags: []string{}, Priority: "L", Project: "p", Status: "pending"}}
 		close(out)
 	}
@@ -426,7 +447,7 @@ func TestRun_StartHTTPServer(t *testing.T) {
 	// override poller to no-op
 	origPoller := pollerFunc
 	defer func() { pollerFunc = origPoller }()
-	pollerFunc = func(interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
+	pollerFunc = func(src taskwarrior.TaskSource, interval time.Duration, out chan<- []taskwarrior.Task, stop <-chan struct{}) {
 		out <- []taskwarrior.Task{}
 		close(out)
 	}
@@ -470,3 +491,79 @@ func TestRun_StartHTTPServer(t *testing.T) {
 		t.Fatalf("Run returned error: %v", err)
 	}
 }
+
+func TestRun_UnknownNotifierBackend(t *testing.T) {
+	origLoad := loadConfigFunc
+	defer func() { loadConfigFunc = origLoad }()
+	loadConfigFunc = func(path string) (*config.Config, error) {
+		return &config.Config{PollInterval: 10 * time.Millisecond, Notifier: "carrier-pigeon", LogLevel: "debug"}, nil
+	}
+
+	if err := Run(""); err == nil || !strings.Contains(err.Error(), "carrier-pigeon") {
+		t.Fatalf("expected unknown backend error, got %v", err)
+	}
+}
+
+func TestNewBackendFunc_SelectsBackend(t *testing.T) {
+	n, err := newBackendFunc(&config.Config{Notifier: "gotify", Gotify: config.GotifyConfig{URL: "https://gotify.example.com", Token: "t"}}, nil)
+	if err != nil {
+		t.Fatalf("newBackendFunc: %v", err)
+	}
+	if _, ok := n.(*notify.GotifySender); !ok {
+		t.Fatalf("expected gotify backend, got %T", n)
+	}
+	n, err = newBackendFunc(&config.Config{Ntfy: config.NtfyConfig{URL: "https://ntfy.example.com", Topic: "t"}}, nil)
+	if err != nil {
+		t.Fatalf("newBackendFunc: %v", err)
+	}
+	if _, ok := n.(*notify.Notifier); !ok {
+		t.Fatalf("expected ntfy backend by default, got %T", n)
+	}
+}
+
+func TestRun_FileSource(t *testing.T) {
+	export := filepath.Join(t.TempDir(), "export.json")
+	due := time.Now().Add(-time.Minute).UTC().Format("20060102T150405Z")
+	os.WriteFile(export, []byte(`[{"id":1,"uuid":"f1","description":"from snapshot","status":"pending","notification_date":"`+due+`"}]`), 0o644)
+
+	origLoad := loadConfigFunc
+	defer func() { loadConfigFunc = origLoad }()
+	loadConfigFunc = func(path string) (*config.Config, error) {
+		return &config.Config{
+			PollInterval:        time.Hour,
+			Ntfy:                config.NtfyConfig{URL: "https://ntfy.example.com", Topic: "test-topic"},
+			NotificationMessage: "{{.Description}}",
+			Source:              config.SourceConfig{Type: "file", Path: export},
+		}, nil
+	}
+	// the default poller reads the file; snapshots are never synced
+	origSyncOnce := syncOnceFunc
+	defer func() { syncOnceFunc = origSyncOnce }()
+	syncOnceFunc = func() { t.Error("task sync must not run for a file source") }
+	origSyncTask := syncTaskwarriorFunc
+	defer func() { syncTaskwarriorFunc = origSyncTask }()
+	syncTaskwarriorFunc = func(stop <-chan struct{}) { t.Error("task sync must not run for a file source") }
+
+	origNewNotifier := newNotifierFunc
+	defer func() { newNotifierFunc = origNewNotifier }()
+	fn := &fakeNotifier{}
+	newNotifierFunc = func(cfg config.NtfyConfig, logger func(format string, v ...interface{})) typeNotifier {
+		return fn
+	}
+	origRunSigCh := runSigCh
+	defer func() { runSigCh = origRunSigCh }()
+	runSigCh = func() <-chan struct{} {
+		ch := make(chan struct{})
+		go func() { time.Sleep(100 * time.Millisecond); close(ch) }()
+		return ch
+	}
+
+	if err := Run(""); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	fn.mu.Lock()
+	defer fn.mu.Unlock()
+	if fn.calls != 1 || fn.lastMsg != "from snapshot" {
+		t.Fatalf("expected the snapshot task to be notified, got %d calls (%q)", fn.calls, fn.lastMsg)
+	}
+}
@@ -0,0 +1,736 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/notify"
+	"task-herald/internal/state"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+// defaultRepeatDelay is used when repeat is enabled on a task but no
+// repeat delay UDA is set
+const defaultRepeatDelay = 15 * time.Minute
+
+// sendRetryDelay is how long to wait before retrying a notification that
+// failed to send
+const sendRetryDelay = 30 * time.Second
+
+// modifyTaskFunc is overridable for testing
+var modifyTaskFunc = taskwarrior.ModifyTask
+
+// scheduler holds the latest polled tasks and the notification state
+type scheduler struct {
+	mu               sync.RWMutex
+	cfg              *config.Config
+	notifier         typeNotifier
+	routes           []*route            // routing rules; tasks matching none go to notifier
+	reminders        []reminder          // notifications relative to due/scheduled/wait/until
+	quiet            *quietHours         // quiet windows and the do-not-disturb policy
+	escalations      []escalation        // re-sends of unacknowledged notifications
+	actionSecret     []byte              // signs ntfy action links
+	exportFilter     *taskwarrior.Filter // taskwarrior.filter, for hook updates
+	exportFilterErr  error               // set if taskwarrior.filter cannot be matched
+	tasks            []taskwarrior.Task
+	polled           bool              // set once the first poll was applied
+	index            map[string]int    // task UUID -> position in tasks
+	queue            notificationQueue // upcoming notification instants
+	wake             chan struct{}     // signals run that the queue changed
+	state            *state.State      // sent/acknowledged notifications and snoozes
+	store            state.Store       // persists state after every change
+	lastNotifiedDate map[string]string // Key: UUID, Value: last seen notification_date
+}
+
+func newScheduler(cfg *config.Config, notifier typeNotifier) *scheduler {
+	return &scheduler{
+		cfg:              cfg,
+		notifier:         notifier,
+		quiet:            &quietHours{policy: config.QuietDefer},
+		state:            state.New(),
+		index:            make(map[string]int),
+		wake:             make(chan struct{}, 1),
+		lastNotifiedDate: make(map[string]string),
+	}
+}
+
+// useStore loads the persisted state from store and saves changes to it
+func (s *scheduler) useStore(store state.Store) error {
+	st, err := store.Load()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = st
+	s.store = store
+	s.requeue()
+	return nil
+}
+
+// save persists the state; callers hold s.mu
+func (s *scheduler) save() {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.Save(s.state); err != nil {
+		notifyLog.Error("Failed to save notification state", "error", err)
+	}
+}
+
+// notifyKey identifies a single notification of a task
+func notifyKey(uuid, notificationDate string) string {
+	return fmt.Sprintf("%s|%s", uuid, notificationDate)
+}
+
+// setTasks replaces the task snapshot with the latest poll result
+func (s *scheduler) setTasks(t []taskwarrior.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replaceTasks(t)
+	s.polled = true
+
+	// Forget state of tasks that are no longer pending (completed or deleted)
+	live := make(map[string]struct{}, len(t))
+	for _, task := range t {
+		live[task.UUID] = struct{}{}
+	}
+	if removed := s.state.Prune(live); removed > 0 {
+		notifyLog.Debug("Pruned notification state of completed/deleted tasks", "count", removed)
+		s.save()
+	}
+
+	pollLog.Info("Tasks available from Taskwarrior", "count", len(t))
+
+	futureTasks := 0
+	for _, task := range t {
+		if task.NotificationDate == "" {
+			continue
+		}
+		prev := s.lastNotifiedDate[task.UUID]
+		if prev != task.NotificationDate {
+			s.lastNotifiedDate[task.UUID] = task.NotificationDate
+		}
+		notifyAt, err := util.ParseNotificationDate(task.NotificationDate)
+		if err == nil && notifyAt.After(time.Now()) {
+			notifyLog.Info("Task with future notification_date", "task_uuid", task.UUID, "id", task.ID, "description", task.Description, "at", notifyAt.In(util.Location()))
+			futureTasks++
+		}
+	}
+	notifyLog.Info("Tasks with future notification_date", "count", futureTasks)
+
+	// Log all tasks returned by task export at the verbose level
+	if pollLog.Enabled(context.Background(), config.LevelVerbose) {
+		for _, task := range t {
+			pollLog.Log(context.Background(), config.LevelVerbose, "Task", "task_uuid", task.UUID, "task", fmt.Sprintf("%+v", task))
+		}
+	}
+
+	notifyLog.Debug("Notification state", "notifications", len(s.state.Notifications), "snoozes", len(s.state.Snoozes))
+	notifyLog.Debug("Last notified dates", "dates", fmt.Sprintf("%+v", s.lastNotifiedDate))
+}
+
+// replaceTasks swaps in a new task snapshot and rebuilds the queue; callers
+// hold s.mu
+func (s *scheduler) replaceTasks(t []taskwarrior.Task) {
+	s.tasks = t
+	s.index = make(map[string]int, len(t))
+	scheduled := 0
+	for i, task := range t {
+		s.index[task.UUID] = i
+		if task.NotificationDate != "" {
+			scheduled++
+		}
+	}
+	trackedTasks.Set(float64(len(t)))
+	scheduledTasks.Set(float64(scheduled))
+	defer s.signal()
+	s.requeue()
+}
+
+// upsertTask applies a single task change forwarded by the Taskwarrior hook:
+// pending and waiting tasks matching taskwarrior.filter are added or
+// replaced, others are removed. Updates arriving before the first poll are
+// dropped: the snapshot is still empty and the poll picks them up. Only a
+// poll prunes the state of vanished tasks; here just the state of a completed
+// or deleted task is removed.
+func (s *scheduler) upsertTask(task taskwarrior.Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.polled {
+		hookLog.Debug("Ignoring task update before the first poll", "task_uuid", task.UUID)
+		return
+	}
+	tasks := make([]taskwarrior.Task, 0, len(s.tasks)+1)
+	replaced := false
+	for _, t := range s.tasks {
+		if t.UUID != task.UUID {
+			tasks = append(tasks, t)
+			continue
+		}
+		replaced = true
+		// Hook JSON has no working-set id; keep the one from the last poll
+		if task.ID == 0 {
+			task.ID = t.ID
+		}
+	}
+	switch task.Status {
+	case "", "pending", "waiting":
+		if !s.exported(task, replaced) {
+			hookLog.Info("Task does not match taskwarrior.filter, not tracked", "task_uuid", task.UUID)
+			break
+		}
+		tasks = append(tasks, task)
+		if replaced {
+			hookLog.Info("Task updated", "task_uuid", task.UUID)
+		} else {
+			hookLog.Info("Task added", "task_uuid", task.UUID)
+		}
+	case "completed", "deleted":
+		hookLog.Info("Task no longer tracked", "task_uuid", task.UUID, "status", task.Status)
+		if removed := s.state.Forget(task.UUID); removed > 0 {
+			notifyLog.Debug("Removed notification state of completed/deleted task", "task_uuid", task.UUID, "count", removed)
+			s.save()
+		}
+	default:
+		hookLog.Info("Task no longer tracked", "task_uuid", task.UUID, "status", task.Status)
+	}
+	s.replaceTasks(tasks)
+}
+
+// exported reports whether the poller would export a task forwarded by the
+// hook, i.e. whether it matches taskwarrior.filter. If the filter cannot be
+// matched here only tracked tasks are updated; new ones wait for the next
+// poll. Callers hold s.mu.
+func (s *scheduler) exported(task taskwarrior.Task, tracked bool) bool {
+	if s.exportFilterErr != nil {
+		return tracked
+	}
+	return s.exportFilter.Match(task)
+}
+
+// nextNotification returns the earliest notification time of a task and the
+// raw date string it was parsed from
+func nextNotification(task taskwarrior.Task) (time.Time, string) {
+	var notifyAt time.Time
+	var notifyDateStr string
+	for _, nd := range notificationDates(task) {
+		t, err := util.ParseNotificationDate(nd)
+		if err == nil && (notifyAt.IsZero() || t.Before(notifyAt)) {
+			notifyAt = t
+			notifyDateStr = nd
+		}
+	}
+	return notifyAt, notifyDateStr
+}
+
+// notificationDates collects the non-empty notification dates of a task
+func notificationDates(task taskwarrior.Task) []string {
+	var ndates []string
+	add := func(v string) {
+		if v == "" {
+			return
+		}
+		for _, d := range ndates {
+			if d == v {
+				return
+			}
+		}
+		ndates = append(ndates, v)
+	}
+	add(task.NotificationDate)
+	if v, ok := getUDA(task, "notification_date"); ok {
+		add(v)
+	}
+	if v, ok := getUDA(task, "taskherald.notification_date"); ok {
+		add(v)
+	}
+	return ndates
+}
+
+// repeatDelay returns the repeat interval of a task and whether repeat is enabled
+func repeatDelay(task taskwarrior.Task) (time.Duration, bool) {
+	cfg := config.Get()
+	if enabled, ok := task.UDABool(udaName(cfg, "repeat_enable")); !ok || !enabled {
+		return 0, false
+	}
+	name := udaName(cfg, "repeat_delay")
+	if raw, ok := task.UDAString(name); ok {
+		if d, ok := task.UDADuration(name); ok && d > 0 {
+			return d, true
+		}
+		notifyLog.Warn("Invalid repeat delay, using the default", "task_uuid", task.UUID, "delay", raw, "default", defaultRepeatDelay)
+	}
+	return defaultRepeatDelay, true
+}
+
+// outgoing is a notification rendered under s.mu and sent after it is
+// released, so a slow or hung backend does not block polls, the API and
+// the hook
+type outgoing struct {
+	send func() error
+	// done records the outcome in the state and reports whether it
+	// changed; it runs under s.mu
+	done func(err error) bool
+}
+
+// notifyDue sends the queued notifications that are due at now, repeats
+// unacknowledged notifications for tasks with repeat enabled and applies the
+// catch-up policy to notifications that were missed
+func (s *scheduler) notifyDue(now time.Time) {
+	s.mu.Lock()
+	changed := false
+	var missed []missedNotification
+	var out []outgoing
+	due := s.queue.popDue(now)
+	for _, item := range due {
+		task, ok := s.task(item.uuid)
+		if !ok {
+			continue
+		}
+		if s.notifyOne(task, item.n, now, &missed, &out) {
+			changed = true
+		}
+	}
+	if len(missed) > 0 && s.catchUp(missed, now, &out) {
+		changed = true
+	}
+	s.mu.Unlock()
+
+	errs := make([]error, len(out))
+	for i, o := range out {
+		errs[i] = o.send()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, o := range out {
+		if o.done(errs[i]) {
+			changed = true
+		}
+	}
+	// Queue repeats, and retries of notifications that failed to send
+	for _, item := range due {
+		task, ok := s.task(item.uuid)
+		if !ok {
+			continue
+		}
+		if fire, ok := s.nextFire(task, item.n); ok {
+			if !fire.After(now) {
+				fire = now.Add(sendRetryDelay)
+			}
+			s.queue.push(queued{fire: fire, uuid: item.uuid, n: item.n})
+		}
+	}
+	if changed {
+		s.save()
+	}
+}
+
+// task returns the task with the given UUID from the snapshot; callers hold s.mu
+func (s *scheduler) task(uuid string) (taskwarrior.Task, bool) {
+	i, ok := s.index[uuid]
+	if !ok || i >= len(s.tasks) {
+		return taskwarrior.Task{}, false
+	}
+	return s.tasks[i], true
+}
+
+// requeue rebuilds the queue from the task snapshot and state; callers hold s.mu
+func (s *scheduler) requeue() {
+	s.queue = s.queue[:0]
+	for _, task := range s.tasks {
+		for _, n := range s.notifications(task) {
+			if fire, ok := s.nextFire(task, n); ok {
+				s.queue.push(queued{fire: fire, uuid: task.UUID, n: n})
+			}
+		}
+	}
+}
+
+// nextFire returns when a notification must next be evaluated: its time if
+// it was never sent, or the next repeat or escalation step if it was sent.
+// Acknowledged, dropped and sent notifications with no repeat or escalation
+// left are done. Callers hold s.mu.
+func (s *scheduler) nextFire(task taskwarrior.Task, n notification) (time.Time, bool) {
+	if s.state.Acknowledged(n.key) {
+		return time.Time{}, false
+	}
+	if lastSent, ok := s.state.LastSent(n.key); ok {
+		var fire time.Time
+		if delay, ok := repeatDelay(task); ok {
+			fire = lastSent.Add(delay)
+		}
+		if at, ok := s.nextEscalation(task, n.key); ok && (fire.IsZero() || at.Before(fire)) {
+			fire = at
+		}
+		if fire.IsZero() {
+			return time.Time{}, false
+		}
+		return s.deferredFire(n.key, fire)
+	}
+	if s.state.Handled(n.key) {
+		return time.Time{}, false
+	}
+	return s.deferredFire(n.key, n.at)
+}
+
+// deferredFire moves fire to the end of a deferral of the notification;
+// notifications held until do-not-disturb is turned off are not queued.
+// Callers hold s.mu.
+func (s *scheduler) deferredFire(key string, fire time.Time) (time.Time, bool) {
+	until, ok := s.state.Deferred(key)
+	switch {
+	case !ok:
+		return fire, true
+	case until.IsZero():
+		return time.Time{}, false
+	case until.After(fire):
+		return until, true
+	}
+	return fire, true
+}
+
+// signal wakes run so it re-arms its timer for the new queue head
+func (s *scheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// nextWait returns how long run sleeps: until the earliest queued
+// notification, at most notifySleepDuration
+func (s *scheduler) nextWait(now time.Time) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	wait := notifySleepDuration
+	if next, ok := s.queue.next(); ok && next.Sub(now) < wait {
+		wait = next.Sub(now)
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// notifyOne renders a single notification instant of a task into out if it
+// is due, or queues it in missed for the catch-up policy. It reports whether
+// the state changed; callers hold s.mu.
+func (s *scheduler) notifyOne(task taskwarrior.Task, n notification, now time.Time, missed *[]missedNotification, out *[]outgoing) bool {
+	notifyAt, key := n.at, n.key
+	// Skip if notification time is in the future
+	if notifyAt.After(now) {
+		return false
+	}
+
+	if s.state.Acknowledged(key) {
+		return false
+	}
+	lastSent, already := s.state.LastSent(key)
+	delay, repeat := repeatDelay(task)
+	escalate := false
+	if already {
+		// Escalate or repeat until acknowledged if configured
+		at, ok := s.nextEscalation(task, key)
+		escalate = ok && !at.After(now)
+		if !escalate && (!repeat || now.Before(lastSent.Add(delay))) {
+			return false
+		}
+	} else if s.state.Handled(key) {
+		return false
+	}
+	if held, changed := s.holdBack(task, key, already, now); held {
+		return changed
+	}
+	if escalate {
+		return s.escalate(task, key, notifyAt, now, out)
+	}
+	// due is when the notification should be sent, for the scheduler lag
+	due := notifyAt
+	if already {
+		due = lastSent.Add(delay)
+		notifyLog.Info("Repeating unacknowledged notification", "task_uuid", task.UUID, "every", delay)
+	} else {
+		// Deferred notifications are due at the end of their deferral
+		if until, ok := s.state.Deferred(key); ok && until.After(due) {
+			due = until
+		}
+		// Notifications due longer ago than the grace window were missed
+		// (daemon offline or asleep) and are handled by the catch-up policy
+		if due.Before(now.Add(-catchUpGrace(s.cfg))) {
+			*missed = append(*missed, missedNotification{task: task, notifyAt: notifyAt, key: key})
+			return false
+		}
+		// Log the notification time in both UTC and local
+		notifyLog.Info("Sending notification", "task_uuid", task.UUID, "at", notifyAt.In(util.Location()))
+	}
+	msgs := s.messages(s.targets(task), task, notifyAt, "")
+	*out = append(*out, outgoing{
+		send: func() error { return deliver(task.UUID, msgs) },
+		done: func(err error) bool {
+			if err != nil {
+				notifyLog.Error("Failed to send notification", "task_uuid", task.UUID, "error", err)
+				return false
+			}
+			s.state.MarkSent(key, task.UUID, now)
+			schedulerLag.Observe(now.Sub(due).Seconds())
+			notifyLog.Info("Notification sent", "task_uuid", task.UUID)
+			return true
+		},
+	})
+	return false
+}
+
+// taskInfo returns the template data for a task notified at notifyAt
+func taskInfo(task taskwarrior.Task, notifyAt time.Time) notify.TaskInfo {
+	var annotations []string
+	for _, a := range task.Annotations {
+		annotations = append(annotations, a.Description)
+	}
+	return notify.TaskInfo{
+		ID:               fmt.Sprintf("%d", task.ID),
+		UUID:             task.UUID,
+		Description:      task.Description,
+		Tags:             task.Tags,
+		Project:          task.Project,
+		Priority:         task.Priority,
+		Due:              parseTaskDate(task.Due),
+		Scheduled:        parseTaskDate(task.Scheduled),
+		Wait:             parseTaskDate(task.Wait),
+		Until:            parseTaskDate(task.Until),
+		NotificationDate: &notifyAt,
+		Status:           task.Status,
+		Urgency:          task.Urgency,
+		Annotations:      annotations,
+		UDAs:             task.UDAs,
+	}
+}
+
+// parseTaskDate parses an exported task date, returning nil if unset or
+// invalid. Dates are returned in the configured timezone for templates.
+func parseTaskDate(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := util.ParseNotificationDate(s)
+	if err != nil {
+		return nil
+	}
+	t = t.In(util.Location())
+	return &t
+}
+
+// send renders the message and headers for a task and sends it to every
+// target it is routed to. It fails only if no target accepted it. Callers
+// hold s.mu.
+func (s *scheduler) send(task taskwarrior.Task, notifyAt time.Time) error {
+	return deliver(task.UUID, s.messages(s.targets(task), task, notifyAt, ""))
+}
+
+// message is a notification rendered for one route target
+type message struct {
+	target  routeTarget
+	text    string
+	headers map[string]string
+}
+
+// messages renders a notification for each target. priority overrides the
+// ntfy priority mapped from the task priority. Callers hold s.mu.
+func (s *scheduler) messages(targets []routeTarget, task taskwarrior.Task, notifyAt time.Time, priority string) []message {
+	info := taskInfo(task, notifyAt)
+	msgs := make([]message, 0, len(targets))
+	for _, target := range targets {
+		msgs = append(msgs, s.render(target, task, info, priority))
+	}
+	return msgs
+}
+
+// deliver sends rendered messages of a task to their targets. It fails only
+// if all targets fail.
+func deliver(uuid string, msgs []message) error {
+	var errs []error
+	for _, m := range msgs {
+		if err := m.target.send(m.text, m.headers); err != nil {
+			if len(msgs) > 1 {
+				notifyLog.Error("Route failed", "task_uuid", uuid, "target", m.target.name, "error", err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(msgs) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// render renders the notification for one route target. A non-empty
+// priority is sent instead of the mapped task priority.
+func (s *scheduler) render(target routeTarget, task taskwarrior.Task, info notify.TaskInfo, priority string) message {
+	cfg := s.cfg
+	// Prepare message
+	msgTmpl := cfg.NotificationMessage
+	if target.message != "" {
+		msgTmpl = target.message
+	}
+	msg, err := notify.RenderMessage(info, msgTmpl)
+	if err != nil {
+		msg = fmt.Sprintf("Task %d: %s", task.ID, task.Description)
+	}
+	// Prepare dynamic headers (e.g., X-Title, X-Click, X-Actions)
+	headers := map[string]string{}
+	// Render configured headers as templates against TaskInfo, route headers last
+	renderHeaders(headers, cfg.Ntfy.Headers, info)
+	renderHeaders(headers, target.headers, info)
+	// Set X-Title to project
+	if _, ok := target.headers["X-Title"]; !ok && task.Project != "" {
+		headers["X-Title"] = task.Project
+	}
+	// Map Taskwarrior priority to ntfy priority for X-Default
+	var ntfyPriority string
+	switch task.Priority {
+	case "H", "h":
+		ntfyPriority = "max"
+	case "M", "m":
+		ntfyPriority = "high"
+	case "L", "l":
+		ntfyPriority = "default"
+	default:
+		ntfyPriority = "default"
+	}
+	headers["X-Default"] = ntfyPriority
+	if priority != "" {
+		headers["X-Default"] = priority
+		headers["X-Priority"] = priority
+	}
+	// Add Done/Snooze/Acknowledge buttons unless X-Actions is configured explicitly
+	if cfg.Ntfy.ActionsEnabled {
+		if _, ok := headers["X-Actions"]; !ok {
+			if actions := buildActions(cfg, s.actionSecret, task.UUID, time.Now()); actions != "" {
+				headers["X-Actions"] = actions
+			}
+		}
+	}
+	return message{target: target, text: msg, headers: headers}
+}
+
+// renderHeaders renders header templates against info (allowing the
+// urlquery func) into headers; values that fail to render are used as-is
+func renderHeaders(headers, tmpls map[string]string, info notify.TaskInfo) {
+	funcMap := template.FuncMap{"urlquery": url.QueryEscape}
+	for k, v := range tmpls {
+		t, err := template.New(k).Funcs(funcMap).Parse(v)
+		if err != nil {
+			headers[k] = v
+			continue
+		}
+		var buf strings.Builder
+		if err := t.Execute(&buf, info); err != nil {
+			headers[k] = v
+			continue
+		}
+		headers[k] = buf.String()
+	}
+}
+
+// acknowledge stops repeats of the current notification of a task. If
+// repeatDelay is set the task is snoozed instead: its notification date UDA
+// is moved to now+repeatDelay so it is notified again later. repeatDelay may
+// also be a date such as "tomorrow 09:00".
+func (s *scheduler) acknowledge(uuid string, repeatDelay string) error {
+	var snoozeUntil time.Time
+	if repeatDelay != "" {
+		var err error
+		if snoozeUntil, err = snoozeTime(repeatDelay, time.Now()); err != nil {
+			return fmt.Errorf("%w: %v", web.ErrBadRequest, err)
+		}
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	found := false
+	for _, task := range s.tasks {
+		if task.UUID != uuid {
+			continue
+		}
+		found = true
+		for _, nd := range notificationDates(task) {
+			s.state.Acknowledge(notifyKey(uuid, nd), uuid, now)
+		}
+		// Reminders that have fired; later reminders still notify
+		for _, n := range s.notifications(task) {
+			if !n.at.After(now) {
+				s.state.Acknowledge(n.key, uuid, now)
+			}
+		}
+	}
+	for _, key := range s.state.Keys(uuid) {
+		found = true
+		s.state.Acknowledge(key, uuid, now)
+	}
+	if found {
+		s.save()
+	}
+	s.mu.Unlock()
+
+	if !found {
+		return fmt.Errorf("task %s not found", uuid)
+	}
+	notifyLog.Info("Task acknowledged", "task_uuid", uuid)
+	if snoozeUntil.IsZero() {
+		return nil
+	}
+	uda := udaName(config.Get(), "notification_date")
+	newDate := snoozeUntil.UTC().Format("20060102T150405Z")
+	if !modifyTaskFunc(uuid, uda+":"+newDate) {
+		return fmt.Errorf("failed to snooze task %s", uuid)
+	}
+	s.mu.Lock()
+	s.state.Snooze(uuid, snoozeUntil, now)
+	s.save()
+	s.mu.Unlock()
+	notifyLog.Info("Task snoozed", "task_uuid", uuid, "until", snoozeUntil.In(util.Location()))
+	return nil
+}
+
+// snoozeTime returns when a task snoozed with delay is notified again. delay
+// is a duration from now (10m, PT30M, 1d) or a date in the configured timezone.
+func snoozeTime(delay string, now time.Time) (time.Time, error) {
+	at, err := util.ParseDate(delay, now, util.Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid snooze delay %q: not a duration or date", delay)
+	}
+	if !at.After(now) {
+		return time.Time{}, fmt.Errorf("snooze date %s is in the past", at.Format("2006-01-02 15:04:05 MST"))
+	}
+	return at.Truncate(time.Second), nil
+}
+
+// run sends notifications as they become due. A single timer is armed for
+// the earliest queued notification and re-armed whenever the queue changes.
+func (s *scheduler) run() {
+	timer := time.NewTimer(s.nextWait(time.Now()))
+	for {
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		}
+		s.notifyDue(time.Now())
+		timer.Reset(s.nextWait(time.Now()))
+	}
+}
@@ -0,0 +1,437 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/state"
+	"task-herald/internal/taskwarrior"
+	"task-herald/internal/util"
+	"task-herald/internal/web"
+)
+
+func repeatTask(due time.Time) taskwarrior.Task {
+	return taskwarrior.Task{
+		ID:               1,
+		UUID:             "u1",
+		Description:      "water plants",
+		NotificationDate: due.UTC().Format("20060102T150405Z"),
+		Status:           "pending",
+		UDAs: map[string]interface{}{
+			"notification_repeat_enable": "true",
+			"notification_repeat_delay":  "PT10M",
+		},
+	}
+}
+
+func TestScheduler_RepeatsUntilAcknowledged(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{repeatTask(due)})
+
+	s.notifyDue(due.Add(time.Minute))
+	if fn.calls != 1 {
+		t.Fatalf("expected initial notification, got %d calls", fn.calls)
+	}
+	// before the repeat delay nothing is re-sent
+	s.notifyDue(due.Add(5 * time.Minute))
+	if fn.calls != 1 {
+		t.Fatalf("expected no repeat before delay, got %d calls", fn.calls)
+	}
+	// after the repeat delay the notification is repeated, even outside the 5 minute window
+	s.notifyDue(due.Add(11 * time.Minute))
+	if fn.calls != 2 {
+		t.Fatalf("expected repeat after delay, got %d calls", fn.calls)
+	}
+
+	if err := s.acknowledge("u1", ""); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	s.notifyDue(due.Add(time.Hour))
+	if fn.calls != 2 {
+		t.Fatalf("expected no repeat after acknowledgement, got %d calls", fn.calls)
+	}
+}
+
+func TestScheduler_NoRepeatWithoutUDA(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	task := repeatTask(due)
+	task.UDAs = nil
+	s.setTasks([]taskwarrior.Task{task})
+
+	s.notifyDue(due.Add(time.Minute))
+	s.notifyDue(due.Add(time.Hour))
+	if fn.calls != 1 {
+		t.Fatalf("expected a single notification, got %d calls", fn.calls)
+	}
+}
+
+func TestScheduler_CustomRepeatUDAMapping(t *testing.T) {
+	cfg := &config.Config{UDAMap: config.UDAMap{RepeatEnable: "nag", RepeatDelay: "nag_every"}}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	task := repeatTask(due)
+	task.UDAs = map[string]interface{}{"nag": "yes", "nag_every": "2m"}
+	s.setTasks([]taskwarrior.Task{task})
+
+	s.notifyDue(due.Add(time.Minute))
+	s.notifyDue(due.Add(4 * time.Minute))
+	if fn.calls != 2 {
+		t.Fatalf("expected repeat with custom UDA mapping, got %d calls", fn.calls)
+	}
+}
+
+func TestScheduler_AcknowledgeSnoozeModifiesTask(t *testing.T) {
+	cfg := &config.Config{UDAMap: config.UDAMap{NotificationDate: "notification_date"}}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{repeatTask(due)})
+
+	orig := modifyTaskFunc
+	defer func() { modifyTaskFunc = orig }()
+	var gotUUID string
+	var gotArgs []string
+	modifyTaskFunc = func(uuid string, args ...string) bool {
+		gotUUID = uuid
+		gotArgs = args
+		return true
+	}
+
+	before := time.Now()
+	if err := s.acknowledge("u1", "PT30M"); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+	if gotUUID != "u1" || len(gotArgs) != 1 || !strings.HasPrefix(gotArgs[0], "notification_date:") {
+		t.Fatalf("unexpected modify call: %s %v", gotUUID, gotArgs)
+	}
+	snoozed, err := time.Parse("20060102T150405Z", strings.TrimPrefix(gotArgs[0], "notification_date:"))
+	if err != nil {
+		t.Fatalf("unexpected snooze date: %v", err)
+	}
+	if snoozed.Before(before.Add(29*time.Minute)) || snoozed.After(time.Now().Add(31*time.Minute)) {
+		t.Fatalf("snooze date %v not ~30m from now", snoozed)
+	}
+
+	// the old notification is acknowledged and not sent
+	s.notifyDue(due.Add(time.Minute))
+	if fn.calls != 0 {
+		t.Fatalf("expected acknowledged notification not to be sent, got %d calls", fn.calls)
+	}
+}
+
+func TestScheduler_SnoozeRearmsAfterRestart(t *testing.T) {
+	cfg := &config.Config{UDAMap: config.UDAMap{NotificationDate: "notification_date"}}
+	config.Set(cfg)
+	orig := modifyTaskFunc
+	defer func() { modifyTaskFunc = orig }()
+	modifyTaskFunc = func(uuid string, args ...string) bool { return true }
+
+	store := state.NewMemoryStore()
+	s := newScheduler(cfg, &fakeNotifier{})
+	if err := s.useStore(store); err != nil {
+		t.Fatal(err)
+	}
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	task := repeatTask(due)
+	s.setTasks([]taskwarrior.Task{task})
+	if err := s.acknowledge("u1", "PT30M"); err != nil {
+		t.Fatalf("acknowledge: %v", err)
+	}
+
+	// Restarted before the snoozed notification_date was polled
+	fn := &fakeNotifier{}
+	restarted := newScheduler(cfg, fn)
+	if err := restarted.useStore(store); err != nil {
+		t.Fatal(err)
+	}
+	restarted.setTasks([]taskwarrior.Task{task})
+	restarted.notifyDue(time.Now().Add(10 * time.Minute))
+	if fn.calls != 0 {
+		t.Fatalf("expected nothing before the snooze ends, got %d calls", fn.calls)
+	}
+	restarted.notifyDue(time.Now().Add(31 * time.Minute))
+	if fn.calls != 1 {
+		t.Fatalf("expected the snoozed task to be notified, got %d calls", fn.calls)
+	}
+
+	// Polling the snoozed date does not notify the task again
+	until, _ := restarted.state.Snoozed("u1")
+	task.NotificationDate = until.UTC().Format("20060102T150405Z")
+	restarted.setTasks([]taskwarrior.Task{task})
+	restarted.notifyDue(time.Now().Add(32 * time.Minute))
+	if fn.calls != 1 {
+		t.Fatalf("expected a single notification, got %d calls", fn.calls)
+	}
+}
+
+func TestSnoozeTime(t *testing.T) {
+	config.Set(&config.Config{})
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	now := time.Date(2025, 9, 3, 14, 30, 0, 0, time.UTC)
+	cases := []struct {
+		delay string
+		want  time.Time
+	}{
+		{"10m", now.Add(10 * time.Minute)},
+		{"PT30M", now.Add(30 * time.Minute)},
+		{"tomorrow 09:00", time.Date(2025, 9, 4, 9, 0, 0, 0, time.UTC)},
+		{"monday", time.Date(2025, 9, 8, 0, 0, 0, 0, time.UTC)},
+		{"now+2h", now.Add(2 * time.Hour)},
+	}
+	for _, tc := range cases {
+		got, err := snoozeTime(tc.delay, now)
+		if err != nil {
+			t.Errorf("snoozeTime(%q): %v", tc.delay, err)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("snoozeTime(%q) = %v, want %v", tc.delay, got, tc.want)
+		}
+	}
+	for _, delay := range []string{"whenever", "yesterday", "today 08:00"} {
+		if _, err := snoozeTime(delay, now); err == nil {
+			t.Errorf("snoozeTime(%q): expected error", delay)
+		}
+	}
+}
+
+func TestScheduler_AcknowledgeErrors(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	s := newScheduler(cfg, &fakeNotifier{})
+	s.setTasks([]taskwarrior.Task{repeatTask(time.Now())})
+
+	if err := s.acknowledge("unknown", ""); err == nil {
+		t.Error("expected error for unknown task")
+	}
+	if err := s.acknowledge("u1", "whenever"); !errors.Is(err, web.ErrBadRequest) {
+		t.Errorf("expected bad request for invalid repeat delay, got %v", err)
+	}
+
+	orig := modifyTaskFunc
+	defer func() { modifyTaskFunc = orig }()
+	modifyTaskFunc = func(uuid string, args ...string) bool { return false }
+	if err := s.acknowledge("u1", "10m"); err == nil {
+		t.Error("expected error when modify fails")
+	}
+}
+
+func TestScheduler_StatePersistsAcrossRestart(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	store, err := state.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	task := repeatTask(due)
+	task.UDAs = nil
+
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+	if err := s.useStore(store); err != nil {
+		t.Fatalf("useStore: %v", err)
+	}
+	s.setTasks([]taskwarrior.Task{task})
+	s.notifyDue(due.Add(time.Minute))
+	if fn.calls != 1 {
+		t.Fatalf("expected one notification, got %d", fn.calls)
+	}
+
+	// a restarted scheduler inside the 5 minute window does not re-send
+	fn2 := &fakeNotifier{}
+	s2 := newScheduler(cfg, fn2)
+	if err := s2.useStore(store); err != nil {
+		t.Fatalf("useStore: %v", err)
+	}
+	s2.setTasks([]taskwarrior.Task{task})
+	s2.notifyDue(due.Add(2 * time.Minute))
+	if fn2.calls != 0 {
+		t.Fatalf("expected no re-send after restart, got %d", fn2.calls)
+	}
+}
+
+func TestScheduler_PrunesCompletedTasks(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	store := state.NewMemoryStore()
+	s := newScheduler(cfg, &fakeNotifier{})
+	if err := s.useStore(store); err != nil {
+		t.Fatalf("useStore: %v", err)
+	}
+
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{repeatTask(due)})
+	s.notifyDue(due.Add(time.Minute))
+
+	// the task disappears from the export once completed
+	s.setTasks(nil)
+	saved, _ := store.Load()
+	if len(saved.Notifications) != 0 {
+		t.Fatalf("expected completed task to be pruned, got %+v", saved.Notifications)
+	}
+}
+
+func TestScheduler_UpsertTask(t *testing.T) {
+	cfg := &config.Config{NotificationMessage: "{{.Description}}"}
+	config.Set(cfg)
+	fn := &fakeNotifier{}
+	s := newScheduler(cfg, fn)
+	now := time.Now().Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{{ID: 7, UUID: "u1", Description: "old", Status: "pending"}})
+
+	// A hook update adds a notification date; it is queued without a poll
+	s.upsertTask(taskwarrior.Task{UUID: "u1", Description: "new", Status: "pending", NotificationDate: now.UTC().Format("20060102T150405Z")})
+	if len(s.tasks) != 1 || s.tasks[0].ID != 7 || s.queue.Len() != 1 {
+		t.Fatalf("expected task replaced and queued, got %+v (queue %d)", s.tasks, s.queue.Len())
+	}
+	s.notifyDue(now)
+	if fn.calls != 1 || fn.lastMsg != "new" {
+		t.Fatalf("expected updated task notified, got %d %q", fn.calls, fn.lastMsg)
+	}
+
+	s.upsertTask(taskwarrior.Task{UUID: "u2", Description: "added", Status: "pending"})
+	s.upsertTask(taskwarrior.Task{UUID: "u1", Status: "completed"})
+	if len(s.tasks) != 1 || s.tasks[0].UUID != "u2" {
+		t.Fatalf("expected completed task removed, got %+v", s.tasks)
+	}
+	if len(s.state.Keys("u1")) != 0 {
+		t.Fatal("expected state of completed task pruned")
+	}
+}
+
+func TestScheduler_UpsertTaskKeepsOtherState(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	store := state.NewMemoryStore()
+	persisted := state.New()
+	now := time.Now()
+	persisted.MarkSent("u1|d1", "u1", now)
+	persisted.Snooze("u2", now.Add(time.Hour), now)
+	persisted.MarkSent("u3|d3", "u3", now)
+	if err := store.Save(persisted); err != nil {
+		t.Fatal(err)
+	}
+	s := newScheduler(cfg, &fakeNotifier{})
+	if err := s.useStore(store); err != nil {
+		t.Fatal(err)
+	}
+
+	// A hook update before the first poll must not replace the snapshot
+	s.upsertTask(taskwarrior.Task{UUID: "u4", Description: "early", Status: "pending"})
+	if len(s.tasks) != 0 {
+		t.Fatalf("expected update before the first poll dropped, got %+v", s.tasks)
+	}
+
+	s.setTasks([]taskwarrior.Task{{UUID: "u1", Status: "pending"}, {UUID: "u2", Status: "pending"}, {UUID: "u3", Status: "pending"}})
+	s.upsertTask(taskwarrior.Task{UUID: "u4", Description: "added", Status: "pending"})
+	s.upsertTask(taskwarrior.Task{UUID: "u3", Status: "deleted"})
+	saved, _ := store.Load()
+	if len(saved.Keys("u1")) != 1 {
+		t.Fatalf("expected state of other tasks kept, got %+v", saved.Notifications)
+	}
+	if _, ok := saved.Snoozed("u2"); !ok {
+		t.Fatal("expected snooze of other task kept")
+	}
+	if len(saved.Keys("u3")) != 0 {
+		t.Fatal("expected state of deleted task removed")
+	}
+}
+
+func TestScheduler_UpsertTaskFilter(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	s := newScheduler(cfg, &fakeNotifier{})
+	var err error
+	if s.exportFilter, err = taskwarrior.ParseFilter("+remind"); err != nil {
+		t.Fatal(err)
+	}
+	s.setTasks([]taskwarrior.Task{{UUID: "u1", Description: "tracked", Status: "pending", Tags: []string{"remind"}}})
+
+	s.upsertTask(taskwarrior.Task{UUID: "u2", Description: "other", Status: "pending"})
+	s.upsertTask(taskwarrior.Task{UUID: "u3", Description: "added", Status: "pending", Tags: []string{"remind"}})
+	// Removing the tag drops a tracked task, as the next export would
+	s.upsertTask(taskwarrior.Task{UUID: "u1", Description: "tracked", Status: "pending"})
+	if len(s.tasks) != 1 || s.tasks[0].UUID != "u3" {
+		t.Fatalf("expected only the matching task tracked, got %+v", s.tasks)
+	}
+
+	// A filter that cannot be matched here only updates tracked tasks
+	s.exportFilter, s.exportFilterErr = nil, errors.New("unsupported")
+	s.upsertTask(taskwarrior.Task{UUID: "u4", Description: "new", Status: "pending"})
+	s.upsertTask(taskwarrior.Task{UUID: "u3", Description: "updated", Status: "pending"})
+	if len(s.tasks) != 1 || s.tasks[0].Description != "updated" {
+		t.Fatalf("expected only the tracked task updated, got %+v", s.tasks)
+	}
+}
+
+// blockingNotifier holds every send until release is closed
+type blockingNotifier struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingNotifier) Send(ctx context.Context, message string, headers map[string]string) error {
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestScheduler_SendsOutsideTheLock(t *testing.T) {
+	cfg := &config.Config{}
+	config.Set(cfg)
+	bn := &blockingNotifier{started: make(chan struct{}), release: make(chan struct{})}
+	s := newScheduler(cfg, bn)
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.setTasks([]taskwarrior.Task{{UUID: "u1", Description: "d", Status: "pending", NotificationDate: due.UTC().Format("20060102T150405Z")}})
+
+	done := make(chan struct{})
+	go func() {
+		s.notifyDue(due.Add(time.Minute))
+		close(done)
+	}()
+	<-bn.started
+	// A poll must not wait for the hung backend
+	polled := make(chan struct{})
+	go func() {
+		s.setTasks([]taskwarrior.Task{{UUID: "u1", Description: "d", Status: "pending", NotificationDate: due.UTC().Format("20060102T150405Z")}})
+		close(polled)
+	}()
+	select {
+	case <-polled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("setTasks blocked while a notification was being sent")
+	}
+	close(bn.release)
+	<-done
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.state.LastSent(notifyKey("u1", due.UTC().Format("20060102T150405Z"))); !ok {
+		t.Error("expected the notification to be recorded as sent")
+	}
+}
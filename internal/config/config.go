@@ -5,18 +5,38 @@ import (
 	"sync"
 	"time"
 	"bytes"
-
-	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
 	PollInterval        time.Duration `yaml:"poll_interval"`
 	SyncInterval        time.Duration `yaml:"sync_interval"`
+	Notifier            string        `yaml:"notifier"` // default backend: ntfy, gotify, webhook, matrix, smtp or pushover
 	Ntfy                NtfyConfig    `yaml:"ntfy"`
+	Gotify              GotifyConfig  `yaml:"gotify"`
+	Webhook             WebhookConfig `yaml:"webhook"`
+	Matrix              MatrixConfig  `yaml:"matrix"`
+	SMTP                SMTPConfig    `yaml:"smtp"`
+	Pushover            PushoverConfig `yaml:"pushover"`
 	HTTP                HTTPConfig    `yaml:"http"`
 	LogLevel            string        `yaml:"log_level"`
 	NotificationMessage string        `yaml:"notification_message"`
 	UDAMap              UDAMap        `yaml:"udas"`
+	State               StateConfig   `yaml:"state"`
+	CatchUp             CatchUpConfig `yaml:"catch_up"`
+	Routes              []RouteConfig `yaml:"routes"`
+	Reminders           []ReminderConfig `yaml:"reminders"`
+	Hooks               HookConfig       `yaml:"hooks"`
+	Source              SourceConfig     `yaml:"source"`
+	Taskwarrior         TaskwarriorConfig `yaml:"taskwarrior"`
+	Transforms          TransformConfig   `yaml:"transforms"`
+	QuietHours          QuietHoursConfig  `yaml:"quiet_hours"`
+	Digests             []DigestConfig    `yaml:"digests"`
+	Escalations         []EscalationConfig `yaml:"escalations"`
+	Reload              ReloadConfig       `yaml:"reload"`
+	Logging             LoggingConfig      `yaml:"logging"`
+	// Timezone for dates without a zone, such as "tomorrow 09:00", e.g.
+	// Europe/Berlin. Defaults to the system timezone.
+	Timezone string `yaml:"timezone"`
 }
 
 type NtfyConfig struct {
@@ -26,6 +46,49 @@ type NtfyConfig struct {
 	Token          string            `yaml:"token"`
 	Headers        map[string]string `yaml:"headers"`
 	ActionsEnabled bool              `yaml:"actions_enabled"`
+	SnoozeDelay    string            `yaml:"snooze_delay"`
+}
+
+// GotifyConfig configures the Gotify backend
+type GotifyConfig struct {
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"` // application token
+}
+
+// WebhookConfig configures the generic webhook backend. Body is a Go
+// template rendered against notify.WebhookPayload; empty sends JSON.
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Method  string            `yaml:"method"`
+	Headers map[string]string `yaml:"headers"`
+	Body    string            `yaml:"body"`
+}
+
+// MatrixConfig configures the Matrix client-server API backend
+type MatrixConfig struct {
+	Homeserver  string `yaml:"homeserver"`
+	AccessToken string `yaml:"access_token"`
+	RoomID      string `yaml:"room_id"`
+	MsgType     string `yaml:"msgtype"` // m.text (default) or m.notice
+}
+
+// SMTPConfig configures the email backend
+type SMTPConfig struct {
+	Host        string   `yaml:"host"`
+	Port        int      `yaml:"port"`
+	Username    string   `yaml:"username"`
+	Password    string   `yaml:"password"`
+	From        string   `yaml:"from"`
+	To          []string `yaml:"to"`
+	ImplicitTLS bool     `yaml:"implicit_tls"` // TLS from the first byte (port 465); otherwise STARTTLS when offered
+}
+
+// PushoverConfig configures the Pushover(-compatible) backend
+type PushoverConfig struct {
+	URL    string `yaml:"url"` // defaults to the Pushover messages API
+	Token  string `yaml:"token"`
+	User   string `yaml:"user"`
+	Device string `yaml:"device"`
 }
 
 type HTTPConfig struct {
@@ -39,6 +102,8 @@ type HTTPConfig struct {
 	AuthTokenFile string `yaml:"auth_token_file"`
 	TLSCertFile string `yaml:"tls_cert_file"`
 	TLSKeyFile string `yaml:"tls_key_file"`
+	ActionSecret string `yaml:"action_secret"`
+	ActionSecretFile string `yaml:"action_secret_file"`
 	Debug     bool   `yaml:"debug"`
 }
 
@@ -59,6 +124,168 @@ type UDAMap struct {
 	RepeatDelay      string `yaml:"repeat_delay"`
 }
 
+// StateConfig selects where notification state is persisted
+type StateConfig struct {
+	Backend string `yaml:"backend"` // file or memory
+	Dir     string `yaml:"dir"`
+}
+
+// Catch-up modes for notifications missed while the daemon was offline or asleep
+const (
+	CatchUpDrop           = "drop"
+	CatchUpSendAll        = "send_all"
+	CatchUpSendLatestOnly = "send_latest_only"
+	CatchUpDigest         = "digest"
+)
+
+// CatchUpConfig controls what happens to notifications that are due longer
+// ago than Grace and were never sent
+type CatchUpConfig struct {
+	Mode          string        `yaml:"mode"`           // drop (default), send_all, send_latest_only or digest
+	Grace         time.Duration `yaml:"grace"`          // notifications due within this window are sent normally (default 5m)
+	MaxAge        time.Duration `yaml:"max_age"`        // missed notifications older than this are always dropped (default 24h)
+	DigestMessage string        `yaml:"digest_message"` // Go template for the digest, see notify.Digest
+}
+
+// RouteConfig sends matching tasks to a different target. All conditions
+// that are set must match; the first matching route wins unless Continue is set.
+type RouteConfig struct {
+	Name     string      `yaml:"name"`
+	Project  string      `yaml:"project"`  // matches the project and its subprojects (work matches work.email)
+	Tags     []string    `yaml:"tags"`     // all tags must be present
+	Priority string      `yaml:"priority"` // H, M, L
+	Filter   string      `yaml:"filter"`   // Taskwarrior-style filter, e.g. "+work or project:office"
+	Target   RouteTarget `yaml:"target"`
+	Continue bool        `yaml:"continue"` // also evaluate the following routes
+}
+
+// RouteTarget names where a routed notification goes. Empty fields fall
+// back to the default notifier, topic, headers and notification_message.
+type RouteTarget struct {
+	Backend string            `yaml:"backend"` // ntfy, gotify, webhook, matrix, smtp or pushover
+	Topic   string            `yaml:"topic"`   // ntfy topic override
+	Headers map[string]string `yaml:"headers"` // merged over ntfy.headers
+	Message string            `yaml:"message"` // Go template overriding notification_message
+}
+
+// ReminderConfig adds a notification at an offset from a task date, e.g.
+// 1d before due. Tasks without the anchor date are not affected.
+type ReminderConfig struct {
+	Anchor   string `yaml:"anchor"`   // due (default), scheduled, wait, until or a date UDA
+	Before   string `yaml:"before"`   // e.g. 1d, 15m; empty notifies at the anchor date
+	After    string `yaml:"after"`    // alternatively, an offset after the anchor date
+	Priority string `yaml:"priority"` // only tasks with this priority
+	Filter   string `yaml:"filter"`   // only tasks matching this Taskwarrior-style filter
+}
+
+// HookConfig enables instant task updates forwarded by the Taskwarrior hook
+// installed with `task-herald hook install`
+type HookConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Socket  string `yaml:"socket"` // defaults to $XDG_RUNTIME_DIR/task-herald.sock
+}
+
+// SourceConfig selects where tasks are loaded from
+type SourceConfig struct {
+	Type string `yaml:"type"` // task (default), taskchampion, file or dir
+	// Path of the TaskChampion database or its directory (defaults to
+	// $TASKDATA/taskchampion.sqlite3 or ~/.task/taskchampion.sqlite3), of
+	// the export file, or of the directory of export files
+	Path string `yaml:"path"`
+}
+
+// TaskwarriorConfig controls how the task command is run
+type TaskwarriorConfig struct {
+	// Filter limits which pending and waiting tasks are exported, e.g.
+	// "notification_date.any: or +remind"
+	Filter       string            `yaml:"filter"`
+	DataLocation string            `yaml:"data_location"` // passed as rc.data.location
+	TaskRC       string            `yaml:"taskrc"`        // sets TASKRC
+	RC           map[string]string `yaml:"rc"`            // extra rc.<name>=<value> overrides
+}
+
+// TransformConfig enables rewriting shorthands in task descriptions into
+// task attributes. No transforms run unless steps are listed.
+type TransformConfig struct {
+	Steps  []string `yaml:"steps"`   // tags (+tag), project (@project) and/or dates (!tomorrow)
+	DryRun bool     `yaml:"dry_run"` // only log the task modify commands
+}
+
+// Quiet hours policies for notifications that fall in a quiet window or
+// while do-not-disturb is on
+const (
+	QuietDefer = "defer"
+	QuietDrop  = "drop"
+)
+
+// QuietHoursConfig holds notifications back during quiet windows, e.g.
+// 22:00-07:00. Windows are in the configured timezone.
+type QuietHoursConfig struct {
+	Windows []QuietWindow `yaml:"windows"`
+	Policy  string        `yaml:"policy"` // defer (default) sends at the end of the window, drop skips
+	// Priorities that are notified anyway, also while do-not-disturb is on, e.g. [H]
+	Override []string `yaml:"override_priorities"`
+}
+
+// QuietWindow is a daily quiet time range. An end before the start ends on
+// the next day; Days name the day a window starts.
+type QuietWindow struct {
+	Days  []string `yaml:"days"`  // mon ... sun; empty means every day
+	Start string   `yaml:"start"` // e.g. 22:00
+	End   string   `yaml:"end"`   // e.g. 07:00
+}
+
+// DigestConfig sends one summary of the tasks matching Filter on a
+// schedule, e.g. a daily agenda at 08:00
+type DigestConfig struct {
+	Name      string      `yaml:"name"`
+	Schedule  string      `yaml:"schedule"`   // cron expression in the configured timezone, e.g. "0 8 * * *"
+	Filter    string      `yaml:"filter"`     // Taskwarrior-style filter, e.g. "due.before:tomorrow or priority:H"
+	Title     string      `yaml:"title"`      // defaults to the name
+	Message   string      `yaml:"message"`    // Go template over notify.Digest
+	SendEmpty bool        `yaml:"send_empty"` // also send when no task matches
+	Target    RouteTarget `yaml:"target"`     // backend/topic/headers; message is ignored
+}
+
+// EscalationConfig re-sends notifications of matching tasks that are not
+// acknowledged or completed in time. The first matching chain applies.
+type EscalationConfig struct {
+	Name   string           `yaml:"name"`
+	Filter string           `yaml:"filter"` // Taskwarrior-style filter; empty matches every task
+	Steps  []EscalationStep `yaml:"steps"`
+}
+
+// EscalationStep is one re-send of an escalation chain
+type EscalationStep struct {
+	After time.Duration `yaml:"after"` // since the notification was first sent
+	// ntfy priority (min, low, default, high, max or 1-5); empty raises the
+	// task's priority by one level per step
+	Priority string      `yaml:"priority"`
+	Target   RouteTarget `yaml:"target"` // e.g. a team topic; empty uses the task's routes
+}
+
+// ReloadConfig controls reloading the config file while running. SIGHUP
+// always reloads it.
+type ReloadConfig struct {
+	Watch    bool          `yaml:"watch"`    // also reload when the file changes
+	Interval time.Duration `yaml:"interval"` // how often the file is checked, default 2s
+}
+
+// LoggingConfig selects how logs are written; log_level is the default level
+type LoggingConfig struct {
+	Format string            `yaml:"format"` // text (default) or json
+	Output string            `yaml:"output"` // stdout (default), stderr, file, syslog or journald
+	File   LogFileConfig     `yaml:"file"`
+	Levels map[string]string `yaml:"levels"` // per component, e.g. notify: debug
+}
+
+// LogFileConfig configures the log file of the file output
+type LogFileConfig struct {
+	Path       string `yaml:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb"` // rotate when larger, default 10
+	MaxBackups int    `yaml:"max_backups"` // rotated files kept, default 5
+}
+
 // WebConfig struct removed
 
 var (
@@ -66,18 +293,17 @@ var (
 	mu            sync.RWMutex
 )
 
+// LoadConfig reads and validates the config file at path. An invalid file
+// returns Problems listing everything wrong with it.
 func LoadConfig(path string) (*Config, error) {
-	f, err := os.Open(path)
+	cfg, problems, err := ParseFile(path)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	var cfg Config
-	dec := yaml.NewDecoder(f)
-	if err := dec.Decode(&cfg); err != nil {
-		return nil, err
+	if len(problems) > 0 {
+		return nil, problems
 	}
-	return &cfg, nil
+	return cfg, nil
 }
 
 func Get() *Config {
@@ -91,3 +317,11 @@ func Set(cfg *Config) {
 	defer mu.Unlock()
 	currentConfig = cfg
 }
+
+// Location returns the time zone named by Timezone, or time.Local if unset
+func (c *Config) Location() (*time.Location, error) {
+	if c == nil || c.Timezone == "" || c.Timezone == "Local" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(c.Timezone)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// secretKeys mark settings whose values are masked in a Diff; so are all
+// headers, which often carry credentials such as Authorization
+var secretKeys = []string{"token", "password", "secret", "key", "user"}
+
+// Diff lists the settings that differ between two configs, one
+// "key: old -> new" line per setting, sorted by key. Keys use the YAML
+// names, e.g. ntfy.topic or routes[0].name. Secrets and headers are masked,
+// and so are the credentials and query strings of URLs.
+func Diff(old, new *Config) []string {
+	a, b := flatten(old), flatten(new)
+	keys := map[string]bool{}
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	var out []string
+	for k := range keys {
+		va, inA := a[k]
+		vb, inB := b[k]
+		if inA == inB && va == vb {
+			continue
+		}
+		out = append(out, fmt.Sprintf("%s: %s -> %s", k, display(k, va, inA), display(k, vb, inB)))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// flatten maps the dotted key of every scalar setting of cfg to its value
+func flatten(cfg *Config) map[string]string {
+	out := map[string]string{}
+	if cfg == nil {
+		return out
+	}
+	raw, err := yaml.Marshal(cfg)
+	if err != nil {
+		return out
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(raw, &v); err != nil {
+		return out
+	}
+	flattenValue("", v, out)
+	return out
+}
+
+func flattenValue(key string, v interface{}, out map[string]string) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, sub := range v {
+			if key != "" {
+				k = key + "." + k
+			}
+			flattenValue(k, sub, out)
+		}
+	case []interface{}:
+		for i, sub := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", key, i), sub, out)
+		}
+	default:
+		s := fmt.Sprint(v)
+		// Unset settings are omitted so that a new empty section is no change
+		if v == nil || s == "" || s == "0" || s == "0s" || s == "false" {
+			return
+		}
+		out[key] = s
+	}
+}
+
+// display formats a value of a Diff line, masking secrets
+func display(key, v string, set bool) string {
+	switch {
+	case !set:
+		return "<unset>"
+	case isSecretKey(key):
+		return `"***"`
+	}
+	return fmt.Sprintf("%q", redactURL(v))
+}
+
+func isSecretKey(key string) bool {
+	parts := strings.Split(strings.ToLower(key), ".")
+	for _, p := range parts[:len(parts)-1] {
+		if p == "headers" {
+			return true
+		}
+	}
+	name := parts[len(parts)-1]
+	for _, s := range secretKeys {
+		if strings.Contains(name, s) && !strings.HasSuffix(name, "_file") {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL masks the user info and query string of a URL, which may hold
+// credentials; other values are returned as they are
+func redactURL(v string) string {
+	if !strings.Contains(v, "://") {
+		return v
+	}
+	u, err := url.Parse(v)
+	if err != nil {
+		return "***"
+	}
+	out := u.Scheme + "://"
+	if u.User != nil {
+		out += "***@"
+	}
+	out += u.Host + u.EscapedPath()
+	if u.RawQuery != "" {
+		out += "?***"
+	}
+	if u.Fragment != "" {
+		out += "#" + u.EscapedFragment()
+	}
+	return out
+}
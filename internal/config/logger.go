@@ -1,9 +1,13 @@
 package config
 
 import (
-	"fmt"
+	"context"
+	"io"
+	"log/slog"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 type LogLevel int
@@ -16,11 +20,76 @@ const (
 	VERBOSE
 )
 
+// Components log with their own level if logging.levels sets one, and with
+// log_level otherwise
+const (
+	LogApp    = "app"    // startup and reloads
+	LogPoller = "poller" // task export, TaskChampion reads and transforms
+	LogSync   = "sync"   // task sync
+	LogNotify = "notify" // the scheduler and notifier backends
+	LogHTTP   = "http"   // the HTTP API
+	LogHook   = "hook"   // updates from the Taskwarrior hook
+	LogTask   = "task"   // task add/modify/done commands
+)
+
+// LogComponents lists the components in logging.levels
+var LogComponents = []string{LogApp, LogPoller, LogSync, LogNotify, LogHTTP, LogHook, LogTask}
+
+// LevelVerbose logs more than slog.LevelDebug
+const LevelVerbose = slog.LevelDebug - 4
+
+// logLevels holds the default and per-component levels; a reload replaces it
+type logLevels struct {
+	def        LogLevel
+	components map[string]LogLevel
+}
+
 var (
-	logLevel LogLevel = INFO
-	once     sync.Once
+	levels atomic.Pointer[logLevels]
+	// output is what all loggers write to; a reload may replace it
+	output atomic.Pointer[logOutput]
 )
 
+// logOutput is a handler and what closes it. Records are written under a
+// read lock, so a replaced output is closed only after the writes in
+// flight finished.
+type logOutput struct {
+	handler slog.Handler
+	closer  io.Closer // nil for stdout and stderr
+	mu      sync.RWMutex
+	closed  bool
+}
+
+func init() {
+	levels.Store(&logLevels{def: INFO})
+	output.Store(&logOutput{handler: newLineHandler(writerSink{os.Stdout}, LogText)})
+}
+
+// acquireOutput returns the current output, read locked; callers unlock it
+// when the record is written
+func acquireOutput() *logOutput {
+	for {
+		o := output.Load()
+		o.mu.RLock()
+		if !o.closed {
+			return o
+		}
+		// Replaced and closed since it was loaded; the new one is stored
+		o.mu.RUnlock()
+	}
+}
+
+// close waits for the writes in flight and closes the output
+func (o *logOutput) close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	if o.closer == nil {
+		return nil
+	}
+	return o.closer.Close()
+}
+
 func ParseLogLevel(level string) LogLevel {
 	switch strings.ToLower(level) {
 	case "error":
@@ -38,16 +107,97 @@ func ParseLogLevel(level string) LogLevel {
 	}
 }
 
+// SetLogLevelFromConfig applies log_level and logging.levels; it is called
+// again on reload
 func SetLogLevelFromConfig(cfg *Config) {
-	once.Do(func() {
-		logLevel = ParseLogLevel(cfg.LogLevel)
-	})
+	l := &logLevels{def: ParseLogLevel(cfg.LogLevel), components: map[string]LogLevel{}}
+	for c, level := range cfg.Logging.Levels {
+		l.components[c] = ParseLogLevel(level)
+	}
+	levels.Store(l)
 }
 
-func Log(level LogLevel, format string, a ...interface{}) {
-	if level <= logLevel {
-		fmt.Printf("[%s] ", strings.ToUpper(level.String()))
-		fmt.Printf(format+"\n", a...)
+// CurrentLogLevel returns the default level set by SetLogLevelFromConfig
+func CurrentLogLevel() LogLevel {
+	return levels.Load().def
+}
+
+// componentLevel returns the level of a component
+func componentLevel(component string) LogLevel {
+	l := levels.Load()
+	if level, ok := l.components[component]; ok {
+		return level
+	}
+	return l.def
+}
+
+// ConfigureLogging applies the levels, format and output of cfg. If the
+// output can't be opened the current one is kept.
+func ConfigureLogging(cfg *Config) error {
+	h, closer, err := newOutput(cfg.Logging)
+	if err != nil {
+		return err
+	}
+	SetLogLevelFromConfig(cfg)
+	output.Swap(&logOutput{handler: h, closer: closer}).close()
+	return nil
+}
+
+// Logger returns the logger of a component. Its records carry a component
+// attribute and follow the component's level and the current output, also
+// after a reload changed them.
+func Logger(component string) *slog.Logger {
+	return slog.New(&componentHandler{component: component})
+}
+
+// componentHandler adds the component to records and filters them by its
+// level before passing them to the current output
+type componentHandler struct {
+	component string
+	with      []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= componentLevel(h.component).Level()
+}
+
+func (h *componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	o := acquireOutput()
+	defer o.mu.RUnlock()
+	out := o.handler.WithAttrs([]slog.Attr{slog.String("component", h.component)})
+	for _, with := range h.with {
+		out = with(out)
+	}
+	return out.Handle(ctx, r)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.and(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.and(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *componentHandler) and(with func(slog.Handler) slog.Handler) slog.Handler {
+	c := &componentHandler{component: h.component}
+	c.with = append(append(c.with, h.with...), with)
+	return c
+}
+
+// Level returns the slog level of l
+func (l LogLevel) Level() slog.Level {
+	switch l {
+	case ERROR:
+		return slog.LevelError
+	case WARN:
+		return slog.LevelWarn
+	case DEBUG:
+		return slog.LevelDebug
+	case VERBOSE:
+		return LevelVerbose
+	default:
+		return slog.LevelInfo
 	}
 }
 
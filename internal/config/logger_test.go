@@ -0,0 +1,193 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// captureLogs sends all loggers to a JSON buffer until the test ends
+func captureLogs(t *testing.T, cfg *Config) *bytes.Buffer {
+	t.Helper()
+	prevOutput, prevLevels := output.Load(), levels.Load()
+	t.Cleanup(func() {
+		output.Store(prevOutput)
+		levels.Store(prevLevels)
+	})
+	var buf bytes.Buffer
+	output.Store(&logOutput{handler: newLineHandler(writerSink{&buf}, LogJSON)})
+	SetLogLevelFromConfig(cfg)
+	return &buf
+}
+
+func TestLogger_ComponentLevels(t *testing.T) {
+	buf := captureLogs(t, &Config{LogLevel: "warn", Logging: LoggingConfig{Levels: map[string]string{LogNotify: "verbose"}}})
+
+	Logger(LogPoller).Info("hidden")
+	Logger(LogNotify).Log(context.Background(), LevelVerbose, "shown", "task_uuid", "abc")
+	Logger(LogSync).Warn("sync failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 records, got %q", buf.String())
+	}
+	var rec map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec["msg"] != "shown" || rec["level"] != "VERBOSE" || rec["component"] != LogNotify || rec["task_uuid"] != "abc" {
+		t.Errorf("unexpected record %v", rec)
+	}
+	if !strings.Contains(lines[1], `"msg":"sync failed","component":"sync"`) {
+		t.Errorf("unexpected record %s", lines[1])
+	}
+}
+
+func TestConfigureLogging_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "task-herald.log")
+	prevOutput, prevLevels := output.Load(), levels.Load()
+	defer func() {
+		// ConfigureLogging closed the previous output; store a fresh one
+		output.Swap(&logOutput{handler: prevOutput.handler}).close()
+		levels.Store(prevLevels)
+	}()
+
+	if err := ConfigureLogging(&Config{Logging: LoggingConfig{Output: LogFile, File: LogFileConfig{Path: path}}}); err != nil {
+		t.Fatal(err)
+	}
+	Logger(LogApp).Info("started", "count", 2)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `level=INFO msg=started component=app count=2`) {
+		t.Errorf("unexpected log file %q", data)
+	}
+}
+
+func TestConfigureLogging_ReloadWhileLogging(t *testing.T) {
+	dir := t.TempDir()
+	prevOutput, prevLevels := output.Load(), levels.Load()
+	defer func() {
+		output.Swap(&logOutput{handler: prevOutput.handler}).close()
+		levels.Store(prevLevels)
+	}()
+	fileConfig := func(name string) *Config {
+		return &Config{Logging: LoggingConfig{Output: LogFile, File: LogFileConfig{Path: filepath.Join(dir, name)}}}
+	}
+	if err := ConfigureLogging(fileConfig("a.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Writers log from before the first reload until the last one is done
+	var wg, started sync.WaitGroup
+	var logged atomic.Int64
+	stop := make(chan struct{})
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			logger := Logger(LogApp)
+			logger.Info("record")
+			logged.Add(1)
+			started.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				logger.Info("record")
+				logged.Add(1)
+			}
+		}()
+	}
+	started.Wait()
+	for i := 0; i < 20; i++ {
+		if err := ConfigureLogging(fileConfig([]string{"a.log", "b.log"}[i%2])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	// Every record reached a file; none was written to a closed one
+	lines := 0
+	for _, name := range []string{"a.log", "b.log"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines += strings.Count(string(data), "msg=record")
+	}
+	if want := int(logged.Load()); lines != want {
+		t.Errorf("expected %d records, got %d", want, lines)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task-herald.log")
+	f, err := openRotatingFile(LogFileConfig{Path: path, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.maxSize = 10
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for file, want := range map[string]string{path: "fourth\n", path + ".1": "third\n", path + ".2": "second\n"} {
+		if data, _ := os.ReadFile(file); string(data) != want {
+			t.Errorf("%s: got %q, want %q", filepath.Base(file), data, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups, got %v", err)
+	}
+}
+
+func TestJournalHandler(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "journal.sock")
+	ln, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+	prevSocket := journalSocket
+	journalSocket = socket
+	defer func() { journalSocket = prevSocket }()
+
+	h, closer, err := newOutput(LoggingConfig{Output: LogJournald})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer.Close()
+	logger := slog.New(h).With("component", LogNotify)
+	logger.Warn("two\nlines", "task_uuid", "abc")
+
+	buf := make([]byte, 4096)
+	n, err := ln.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	want.WriteString("MESSAGE\n")
+	binary.Write(&want, binary.LittleEndian, uint64(len("two\nlines")))
+	want.WriteString("two\nlines\nPRIORITY=4\nSYSLOG_IDENTIFIER=task-herald\nCOMPONENT=notify\nTASK_UUID=abc\n")
+	if got := buf[:n]; !bytes.Equal(got, want.Bytes()) {
+		t.Errorf("got %q, want %q", got, want.Bytes())
+	}
+}
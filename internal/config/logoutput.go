@@ -0,0 +1,340 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log outputs and formats
+const (
+	LogStdout   = "stdout"
+	LogStderr   = "stderr"
+	LogFile     = "file"
+	LogSyslog   = "syslog"
+	LogJournald = "journald"
+
+	LogText = "text"
+	LogJSON = "json"
+)
+
+// journalSocket is where journald receives native protocol messages
+var journalSocket = "/run/systemd/journal/socket"
+
+// logIdentifier names the program in syslog and the journal
+const logIdentifier = "task-herald"
+
+// newOutput opens the output selected by cfg. The closer is nil for stdout
+// and stderr.
+func newOutput(cfg LoggingConfig) (slog.Handler, io.Closer, error) {
+	format := cfg.Format
+	if format == "" {
+		format = LogText
+	}
+	switch cfg.Output {
+	case "", LogStdout:
+		return newLineHandler(writerSink{os.Stdout}, format), nil, nil
+	case LogStderr:
+		return newLineHandler(writerSink{os.Stderr}, format), nil, nil
+	case LogFile:
+		f, err := openRotatingFile(cfg.File)
+		if err != nil {
+			return nil, nil, fmt.Errorf("log file: %w", err)
+		}
+		return newLineHandler(writerSink{f}, format), f, nil
+	case LogSyslog:
+		s, err := dialSyslog()
+		if err != nil {
+			return nil, nil, fmt.Errorf("syslog: %w", err)
+		}
+		h := newLineHandler(s, format)
+		h.omitTime = true
+		return h, s, nil
+	case LogJournald:
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+		if err != nil {
+			return nil, nil, fmt.Errorf("journald: %w", err)
+		}
+		return &journalHandler{conn: conn}, conn, nil
+	}
+	return nil, nil, fmt.Errorf("unknown log output %q", cfg.Output)
+}
+
+// sink receives formatted log lines
+type sink interface {
+	write(level slog.Level, line []byte) error
+}
+
+type writerSink struct{ w io.Writer }
+
+func (s writerSink) write(_ slog.Level, line []byte) error {
+	_, err := s.w.Write(line)
+	return err
+}
+
+// lineHandler formats each record as one text or JSON line for a sink
+type lineHandler struct {
+	sink     sink
+	format   string
+	omitTime bool                              // the sink adds its own timestamp
+	with     []func(slog.Handler) slog.Handler // WithAttrs and WithGroup calls
+}
+
+func newLineHandler(s sink, format string) *lineHandler {
+	return &lineHandler{sink: s, format: format}
+}
+
+func (h *lineHandler) Enabled(context.Context, slog.Level) bool {
+	// Levels are checked per component before records get here
+	return true
+}
+
+func (h *lineHandler) Handle(ctx context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	opts := &slog.HandlerOptions{Level: LevelVerbose, ReplaceAttr: h.replaceAttr}
+	var out slog.Handler
+	if h.format == LogJSON {
+		out = slog.NewJSONHandler(&buf, opts)
+	} else {
+		out = slog.NewTextHandler(&buf, opts)
+	}
+	for _, with := range h.with {
+		out = with(out)
+	}
+	if err := out.Handle(ctx, r); err != nil {
+		return err
+	}
+	return h.sink.write(r.Level, buf.Bytes())
+}
+
+// replaceAttr names the verbose level and drops the time if the sink adds it
+func (h *lineHandler) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		if h.omitTime {
+			return slog.Attr{}
+		}
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok && level < slog.LevelDebug {
+			a.Value = slog.StringValue("VERBOSE")
+		}
+	}
+	return a
+}
+
+func (h *lineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.and(func(out slog.Handler) slog.Handler { return out.WithAttrs(attrs) })
+}
+
+func (h *lineHandler) WithGroup(name string) slog.Handler {
+	return h.and(func(out slog.Handler) slog.Handler { return out.WithGroup(name) })
+}
+
+func (h *lineHandler) and(with func(slog.Handler) slog.Handler) slog.Handler {
+	c := *h
+	c.with = append(append([]func(slog.Handler) slog.Handler(nil), h.with...), with)
+	return &c
+}
+
+// rotatingFile is a log file that is renamed to <path>.1 when it would grow
+// beyond the maximum size; older files move to <path>.2 and so on
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(cfg LogFileConfig) (*rotatingFile, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("logging.file.path is required")
+	}
+	r := &rotatingFile{path: cfg.Path, maxSize: int64(cfg.MaxSizeMB) << 20, backups: cfg.MaxBackups}
+	if r.maxSize <= 0 {
+		r.maxSize = 10 << 20
+	}
+	if r.backups <= 0 {
+		r.backups = 5
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return nil, err
+	}
+	return r, r.open()
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups and starts a new file; callers hold r.mu
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.backups))
+	for i := r.backups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// journalHandler sends records to journald with its native protocol, so
+// attributes become journal fields, e.g. task_uuid becomes TASK_UUID
+type journalHandler struct {
+	conn   *net.UnixConn
+	attrs  []slog.Attr
+	prefix string // open groups, e.g. "REQUEST_"
+}
+
+func (h *journalHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", r.Message)
+	writeJournalField(&buf, "PRIORITY", fmt.Sprint(syslogPriority(r.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", logIdentifier)
+	for _, a := range h.attrs {
+		writeJournalAttr(&buf, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeJournalAttr(&buf, h.prefix, a)
+		return true
+	})
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		a.Key = h.prefix + a.Key
+		c.attrs = append(c.attrs, a)
+	}
+	return &c
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.prefix = h.prefix + name + "_"
+	return &c
+}
+
+// writeJournalAttr writes an attribute, flattening groups into prefixed fields
+func writeJournalAttr(buf *bytes.Buffer, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		for _, sub := range v.Group() {
+			writeJournalAttr(buf, prefix+a.Key+"_", sub)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	value := v.String()
+	if v.Kind() == slog.KindTime {
+		value = v.Time().Format(time.RFC3339)
+	}
+	writeJournalField(buf, journalFieldName(prefix+a.Key), value)
+}
+
+// journalFieldName makes a valid journal field name: upper case letters,
+// digits and underscores, not starting with an underscore or digit
+func journalFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+	return name
+}
+
+// writeJournalField writes NAME=value, or the length-prefixed form if the
+// value spans lines
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	buf.WriteString(name + "\n")
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value + "\n")
+}
+
+// syslogPriority returns the syslog severity of a level
+func syslogPriority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	}
+	return 7 // debug
+}
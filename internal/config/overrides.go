@@ -0,0 +1,261 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the environment variables that override settings, e.g.
+// TASK_HERALD_NTFY_TOKEN for ntfy.token
+const EnvPrefix = "TASK_HERALD_"
+
+// interpolate replaces ${VAR} and ${VAR:-default} in the scalar values of a
+// parsed config document with environment variables. Values are replaced
+// after parsing, so whatever a variable contains can't change the structure
+// of the document, and comments are never expanded. $${ is a literal ${.
+// An unset variable without a default is a problem.
+func interpolate(n *yaml.Node, ps *Problems) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(n.Value, "${") {
+			return
+		}
+		n.Value = expandEnv(n.Value, n.Line, ps)
+		if n.Style&yaml.TaggedStyle == 0 {
+			// Resolve the type from the expanded value, e.g. a ${PORT} number
+			n.Tag = ""
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(n.Content); i += 2 {
+			interpolate(n.Content[i], ps)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			interpolate(c, ps)
+		}
+	}
+}
+
+// expandEnv expands the variables of a value found on line
+func expandEnv(s string, line int, ps *Problems) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			break
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1] + "${")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			*ps = append(*ps, Problem{Line: line, Msg: "unterminated ${ in " + s[start:]})
+			break
+		}
+		name := s[start+2 : start+end]
+		def, hasDef := "", false
+		if j := strings.Index(name, ":-"); j >= 0 {
+			name, def, hasDef = name[:j], name[j+2:], true
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok || (value == "" && hasDef) {
+			if !hasDef {
+				*ps = append(*ps, Problem{Line: line, Msg: fmt.Sprintf("environment variable %s is not set (use ${%s:-} for an empty default)", name, name)})
+			}
+			value = def
+		}
+		b.WriteString(s[:start] + value)
+		s = s[start+end+1:]
+	}
+	b.WriteString(s)
+	return b.String()
+}
+
+// prepare checks the keys of a config document against the Config type and
+// replaces <name>_file keys of string settings without a _file setting of
+// their own by <name> with the trimmed contents of the file. Unknown keys
+// are problems. If both are set, <name>_file wins.
+func prepare(n *yaml.Node, t reflect.Type, key string, ps *Problems) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			prepare(c, t, key, ps)
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice {
+			for i, c := range n.Content {
+				prepare(c, t.Elem(), fmt.Sprintf("%s[%d]", key, i), ps)
+			}
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				prepare(n.Content[i+1], t.Elem(), joinKey(key, n.Content[i].Value), ps)
+			}
+		case reflect.Struct:
+			prepareStruct(n, t, key, ps)
+		}
+	}
+}
+
+func prepareStruct(n *yaml.Node, t reflect.Type, key string, ps *Problems) {
+	fields := yamlFields(t)
+	fromFile := map[string]*yaml.Node{} // setting -> key node rewritten from <setting>_file
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		sub := joinKey(key, k.Value)
+		if f, ok := fields[k.Value]; ok {
+			prepare(v, f.Type, sub, ps)
+			continue
+		}
+		base := strings.TrimSuffix(k.Value, "_file")
+		if f, ok := fields[base]; ok && base != k.Value && f.Type.Kind() == reflect.String {
+			secret, err := readSecretFile(v.Value)
+			if err != nil {
+				*ps = append(*ps, Problem{Line: k.Line, Key: sub, Msg: err.Error()})
+				continue
+			}
+			k.Value = base
+			*v = yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: secret, Line: v.Line, Column: v.Column}
+			fromFile[base] = k
+			continue
+		}
+		*ps = append(*ps, Problem{Line: k.Line, Key: sub, Msg: "unknown setting"})
+	}
+	if len(fromFile) == 0 {
+		return
+	}
+	// Drop the settings replaced by a file so the mapping has no duplicate keys
+	content := n.Content[:0]
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if file, ok := fromFile[k.Value]; ok && file != k {
+			continue
+		}
+		content = append(content, k, v)
+	}
+	n.Content = content
+}
+
+// applyEnv sets the settings that have a TASK_HERALD_<SECTION>_<FIELD>
+// environment variable, e.g. TASK_HERALD_POLL_INTERVAL or
+// TASK_HERALD_NTFY_TOKEN. For string settings without a _file setting of
+// their own, <VAR>_FILE names a file holding the value and wins over <VAR>.
+// Lists are comma-separated; lists of sections and maps can't be set.
+func applyEnv(v reflect.Value, key, env string, ps *Problems) {
+	t := v.Type()
+	fields := yamlFields(t)
+	for name, f := range fields {
+		fv := v.FieldByIndex(f.Index)
+		sub, subEnv := joinKey(key, name), env+strings.ToUpper(name)
+		if fv.Kind() == reflect.Struct {
+			applyEnv(fv, sub, subEnv+"_", ps)
+			continue
+		}
+		value, ok := os.LookupEnv(subEnv)
+		if _, own := fields[name+"_file"]; !own && fv.Kind() == reflect.String {
+			if path, set := os.LookupEnv(subEnv + "_FILE"); set {
+				secret, err := readSecretFile(path)
+				if err != nil {
+					*ps = append(*ps, Problem{Key: sub, Msg: fmt.Sprintf("%s_FILE: %v", subEnv, err)})
+					continue
+				}
+				value, ok = secret, true
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := setScalar(fv, value); err != nil {
+			*ps = append(*ps, Problem{Key: sub, Msg: fmt.Sprintf("%s: %v", subEnv, err)})
+		}
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setScalar parses s into a string, bool, number, duration or string list
+func setScalar(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		var list []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("can't be set from the environment")
+	}
+	return nil
+}
+
+// yamlFields maps the YAML names of a struct's fields to the fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// readSecretFile returns the contents of a secret file without surrounding
+// whitespace, such as the trailing newline
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func joinKey(key, name string) string {
+	if key == "" {
+		return name
+	}
+	return key + "." + name
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func writeSecret(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("HERALD_TOPIC", "tasks")
+	t.Setenv("HERALD_EMPTY", "")
+	t.Setenv("HERALD_SECRET", `p#ss: "w'rd" #tail`)
+	t.Setenv("HERALD_MULTI", "a\nb: c")
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(`# ${NOT_EXPANDED} in comments
+topic: ${HERALD_TOPIC}-${HERALD_EMPTY:-home} # see ${NOT_EXPANDED}
+literal: "$${HERALD_TOPIC}"
+secret: ${HERALD_SECRET}
+quoted: '${HERALD_SECRET}'
+multi: ${HERALD_MULTI}
+missing: ${HERALD_MISSING}
+optional: "${HERALD_MISSING:-}"
+`), &root); err != nil {
+		t.Fatal(err)
+	}
+	var problems Problems
+	interpolate(&root, &problems)
+	var got map[string]string
+	if err := root.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"topic":    "tasks-home",
+		"literal":  "${HERALD_TOPIC}",
+		"secret":   `p#ss: "w'rd" #tail`,
+		"quoted":   `p#ss: "w'rd" #tail`,
+		"multi":    "a\nb: c",
+		"missing":  "",
+		"optional": "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if len(problems) != 1 || problems[0].Line != 7 || !strings.Contains(problems[0].Msg, "HERALD_MISSING is not set") {
+		t.Errorf("expected the unset variable to be reported on line 7, got %v", problems)
+	}
+}
+
+func TestParse_InterpolatedTypes(t *testing.T) {
+	t.Setenv("HERALD_PORT", "8080")
+	t.Setenv("HERALD_TOKEN", "tok#en: x")
+	cfg, problems := Parse([]byte(validConfig + "  token: ${HERALD_TOKEN}\nhttp:\n  port: ${HERALD_PORT}\n"))
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if cfg.HTTP.Port != 8080 || cfg.Ntfy.Token != "tok#en: x" {
+		t.Errorf("expected port 8080 and the whole token, got %d and %q", cfg.HTTP.Port, cfg.Ntfy.Token)
+	}
+}
+
+func TestParse_SecretFiles(t *testing.T) {
+	token := writeSecret(t, "file-token\n")
+	hook := writeSecret(t, "https://hooks.example.com/T0/B0/secret")
+	data := validConfig + `  token: inline-token
+  token_file: ` + token + `
+webhook:
+  url_file: ` + hook + `
+smtp:
+  password_file: /nonexistent/password
+  port_file: /etc/hostname
+`
+	cfg, problems := Parse([]byte(data))
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"line 10: smtp.password_file: open /nonexistent/password: no such file or directory",
+		"line 11: smtp.port_file: unknown setting",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if cfg.Ntfy.Token != "file-token" {
+		t.Errorf("expected token_file to win over token, got %q", cfg.Ntfy.Token)
+	}
+	if cfg.Webhook.URL != "https://hooks.example.com/T0/B0/secret" {
+		t.Errorf("expected the webhook URL from its file, got %q", cfg.Webhook.URL)
+	}
+}
+
+func TestParse_EnvOverrides(t *testing.T) {
+	t.Setenv("TASK_HERALD_POLL_INTERVAL", "1m")
+	t.Setenv("TASK_HERALD_NTFY_TOPIC", "from-env")
+	t.Setenv("TASK_HERALD_NTFY_ACTIONS_ENABLED", "true")
+	t.Setenv("TASK_HERALD_SMTP_TO", "a@example.com, b@example.com")
+	t.Setenv("TASK_HERALD_HTTP_ADDR", "127.0.0.1:8080")
+	t.Setenv("TASK_HERALD_HTTP_TLS_CERT_FILE", "/etc/cert.pem")
+	t.Setenv("TASK_HERALD_HTTP_TLS_KEY_FILE", "/etc/key.pem")
+	cfg, problems := Parse([]byte(validConfig))
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if cfg.PollInterval != time.Minute || cfg.Ntfy.Topic != "from-env" || !cfg.Ntfy.ActionsEnabled || cfg.HTTP.Addr != "127.0.0.1:8080" {
+		t.Errorf("expected the environment to override the file, got %+v", cfg)
+	}
+	if strings.Join(cfg.SMTP.To, "|") != "a@example.com|b@example.com" {
+		t.Errorf("expected a comma-separated list, got %q", cfg.SMTP.To)
+	}
+	// Settings with a _file setting of their own take the path, not the contents
+	if cfg.HTTP.TLSCertFile != "/etc/cert.pem" || cfg.HTTP.TLSCert != "" {
+		t.Errorf("expected TASK_HERALD_HTTP_TLS_CERT_FILE to set tls_cert_file, got %+v", cfg.HTTP)
+	}
+
+	t.Setenv("TASK_HERALD_HTTP_PORT", "eighty")
+	t.Setenv("TASK_HERALD_ROUTES", "work")
+	_, problems = Parse([]byte(validConfig))
+	if got := problems.Error(); !strings.Contains(got, "http.port: TASK_HERALD_HTTP_PORT") || !strings.Contains(got, "routes: TASK_HERALD_ROUTES: can't be set from the environment") {
+		t.Errorf("expected invalid overrides to be reported, got %v", got)
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	t.Setenv("HERALD_TOKEN", "interpolated")
+	data := validConfig + "  token: ${HERALD_TOKEN}\n"
+	token := func() string {
+		t.Helper()
+		cfg, problems := Parse([]byte(data))
+		if len(problems) != 0 {
+			t.Fatalf("unexpected problems: %v", problems)
+		}
+		return cfg.Ntfy.Token
+	}
+	if got := token(); got != "interpolated" {
+		t.Errorf("expected the interpolated value, got %q", got)
+	}
+	data += "  token_file: " + writeSecret(t, "yaml-file") + "\n"
+	if got := token(); got != "yaml-file" {
+		t.Errorf("expected token_file to win over token, got %q", got)
+	}
+	t.Setenv("TASK_HERALD_NTFY_TOKEN", "env")
+	if got := token(); got != "env" {
+		t.Errorf("expected the environment to win over the file, got %q", got)
+	}
+	t.Setenv("TASK_HERALD_NTFY_TOKEN_FILE", writeSecret(t, "env-file"))
+	if got := token(); got != "env-file" {
+		t.Errorf("expected TASK_HERALD_NTFY_TOKEN_FILE to win, got %q", got)
+	}
+}
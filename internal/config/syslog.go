@@ -0,0 +1,38 @@
+//go:build !windows && !plan9
+
+package config
+
+import (
+	"log/slog"
+	"log/syslog"
+	"strings"
+)
+
+// syslogSink writes lines to the local syslog daemon with the severity of
+// their level
+type syslogSink struct{ w *syslog.Writer }
+
+func dialSyslog() (*syslogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, logIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogSink{w}, nil
+}
+
+func (s *syslogSink) write(level slog.Level, line []byte) error {
+	msg := strings.TrimSuffix(string(line), "\n")
+	switch syslogPriority(level) {
+	case 3:
+		return s.w.Err(msg)
+	case 4:
+		return s.w.Warning(msg)
+	case 6:
+		return s.w.Info(msg)
+	}
+	return s.w.Debug(msg)
+}
+
+func (s *syslogSink) Close() error {
+	return s.w.Close()
+}
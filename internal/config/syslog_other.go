@@ -0,0 +1,18 @@
+//go:build windows || plan9
+
+package config
+
+import (
+	"errors"
+	"log/slog"
+)
+
+type syslogSink struct{}
+
+func dialSyslog() (*syslogSink, error) {
+	return nil, errors.New("not supported on this platform")
+}
+
+func (s *syslogSink) write(slog.Level, []byte) error { return nil }
+
+func (s *syslogSink) Close() error { return nil }
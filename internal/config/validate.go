@@ -0,0 +1,306 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an unknown or invalid setting in a config file
+type Problem struct {
+	Line int    // line in the config file; 0 if unknown
+	Key  string // setting, e.g. ntfy.url or routes[0].target.message
+	Msg  string
+}
+
+func (p Problem) String() string {
+	s := p.Msg
+	if p.Key != "" {
+		s = p.Key + ": " + s
+	}
+	if p.Line > 0 {
+		s = fmt.Sprintf("line %d: %s", p.Line, s)
+	}
+	return s
+}
+
+// Problems is the error returned for an invalid config file
+type Problems []Problem
+
+func (ps Problems) Error() string {
+	lines := make([]string, len(ps))
+	for i, p := range ps {
+		lines[i] = p.String()
+	}
+	return "invalid config: " + strings.Join(lines, "; ")
+}
+
+// Validator checks settings that only other packages understand, such as
+// templates and backend names
+type Validator func(cfg *Config) Problems
+
+var (
+	validatorsMu sync.RWMutex
+	validators   []Validator
+)
+
+// RegisterValidator adds a check run by Validate
+func RegisterValidator(v Validator) {
+	validatorsMu.Lock()
+	defer validatorsMu.Unlock()
+	validators = append(validators, v)
+}
+
+// ParseFile strictly decodes the config file at path and validates it. It
+// returns every problem found, with line numbers; err is only set if the
+// file can't be read.
+func ParseFile(path string) (*Config, Problems, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, problems := Parse(data)
+	return cfg, problems, nil
+}
+
+// Parse decodes and validates a config file. Settings are taken, from
+// lowest to highest precedence, from the YAML with ${VAR} interpolated,
+// from <name>_file keys, and from TASK_HERALD_* environment variables.
+// Unknown settings are problems; so is anything Validate rejects.
+func Parse(data []byte) (*Config, Problems) {
+	var cfg Config
+	var problems Problems
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return &cfg, append(problems, yamlProblem(err.Error()))
+	}
+	interpolate(&root, &problems)
+	prepare(&root, reflect.TypeOf(cfg), "", &problems)
+	if len(root.Content) > 0 {
+		if err := root.Decode(&cfg); err != nil {
+			var te *yaml.TypeError
+			if !errors.As(err, &te) {
+				return &cfg, append(problems, yamlProblem(err.Error()))
+			}
+			for _, msg := range te.Errors {
+				problems = append(problems, yamlProblem(msg))
+			}
+		}
+	}
+	var envProblems Problems
+	applyEnv(reflect.ValueOf(&cfg).Elem(), "", EnvPrefix, &envProblems)
+	sort.SliceStable(envProblems, func(i, j int) bool { return envProblems[i].Key < envProblems[j].Key })
+	problems = append(problems, envProblems...)
+	for _, p := range cfg.Validate() {
+		if p.Line == 0 && p.Key != "" {
+			p.Line = lineOf(&root, p.Key)
+		}
+		problems = append(problems, p)
+	}
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return &cfg, problems
+}
+
+var (
+	yamlLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+)
+
+// yamlProblem turns a yaml.v3 error message into a Problem
+func yamlProblem(msg string) Problem {
+	var p Problem
+	if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+		p.Line, _ = strconv.Atoi(m[1])
+		msg = m[2]
+	}
+	p.Msg = msg
+	return p
+}
+
+// lineOf returns the line of the setting key, e.g. routes[0].target.topic,
+// or of its closest parent present in the document
+func lineOf(root *yaml.Node, key string) int {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line := 0
+	for _, part := range strings.Split(key, ".") {
+		name, indexes := part, []int(nil)
+		for strings.HasSuffix(name, "]") {
+			i := strings.LastIndex(name, "[")
+			if i < 0 {
+				break
+			}
+			idx, err := strconv.Atoi(name[i+1 : len(name)-1])
+			if err != nil {
+				break
+			}
+			indexes = append([]int{idx}, indexes...)
+			name = name[:i]
+		}
+		next := mappingValue(n, name)
+		if next == nil {
+			return line
+		}
+		line, n = next.Line, next
+		for _, idx := range indexes {
+			if n.Kind != yaml.SequenceNode || idx >= len(n.Content) {
+				return line
+			}
+			n = n.Content[idx]
+			line = n.Line
+		}
+	}
+	return line
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			// Report the key's line; a block value starts on the next one
+			v := *n.Content[i+1]
+			v.Line = n.Content[i].Line
+			return &v
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings that can be checked without building
+// anything from them, then runs the registered validators
+func (c *Config) Validate() Problems {
+	var ps Problems
+	add := func(key, format string, a ...interface{}) {
+		ps = append(ps, Problem{Key: key, Msg: fmt.Sprintf(format, a...)})
+	}
+
+	if c.PollInterval <= 0 {
+		add("poll_interval", "must be a positive duration, e.g. 30s")
+	}
+	for key, d := range map[string]time.Duration{
+		"sync_interval":    c.SyncInterval,
+		"reload.interval":  c.Reload.Interval,
+		"catch_up.grace":   c.CatchUp.Grace,
+		"catch_up.max_age": c.CatchUp.MaxAge,
+	} {
+		if d < 0 {
+			add(key, "must not be negative")
+		}
+	}
+	for i, e := range c.Escalations {
+		for j, s := range e.Steps {
+			if s.After <= 0 {
+				add(fmt.Sprintf("escalations[%d].steps[%d].after", i, j), "must be a positive duration")
+			}
+		}
+	}
+	if c.LogLevel != "" && ParseLogLevel(c.LogLevel).String() != strings.ToLower(c.LogLevel) {
+		add("log_level", "unknown level %q (want error, warn, info, debug or verbose)", c.LogLevel)
+	}
+	switch c.Logging.Format {
+	case "", LogText, LogJSON:
+	default:
+		add("logging.format", "unknown format %q (want text or json)", c.Logging.Format)
+	}
+	switch c.Logging.Output {
+	case "", LogStdout, LogStderr, LogSyslog, LogJournald:
+	case LogFile:
+		if c.Logging.File.Path == "" {
+			add("logging.file.path", "required for the file output")
+		}
+	default:
+		add("logging.output", "unknown output %q (want stdout, stderr, file, syslog or journald)", c.Logging.Output)
+	}
+	if c.Logging.File.MaxSizeMB < 0 || c.Logging.File.MaxBackups < 0 {
+		add("logging.file", "max_size_mb and max_backups must not be negative")
+	}
+	for component, level := range c.Logging.Levels {
+		key := "logging.levels." + component
+		if !slices.Contains(LogComponents, component) {
+			add(key, "unknown component (want %s)", strings.Join(LogComponents, ", "))
+		} else if ParseLogLevel(level).String() != strings.ToLower(level) {
+			add(key, "unknown level %q (want error, warn, info, debug or verbose)", level)
+		}
+	}
+	if _, err := c.Location(); err != nil {
+		add("timezone", "unknown timezone %q", c.Timezone)
+	}
+
+	if c.Notifier == "" || c.Notifier == "ntfy" {
+		if c.Ntfy.URL == "" {
+			add("ntfy.url", "required")
+		}
+		if c.Ntfy.Topic == "" && c.Ntfy.TopicFile == "" {
+			add("ntfy.topic", "required (or ntfy.topic_file)")
+		}
+	}
+	for key, u := range map[string]string{
+		"ntfy.url":          c.Ntfy.URL,
+		"gotify.url":        c.Gotify.URL,
+		"webhook.url":       c.Webhook.URL,
+		"matrix.homeserver": c.Matrix.Homeserver,
+		"pushover.url":      c.Pushover.URL,
+	} {
+		if err := checkURL(u); err != nil {
+			add(key, "%v", err)
+		}
+	}
+
+	switch c.Source.Type {
+	case "", "task", "taskchampion":
+	case "file", "dir":
+		if c.Source.Path == "" {
+			add("source.path", "required for the %s source", c.Source.Type)
+		}
+	default:
+		add("source.type", "unknown task source %q (want task, taskchampion, file or dir)", c.Source.Type)
+	}
+
+	if (c.HTTP.TLSCert == "") != (c.HTTP.TLSKey == "") {
+		add("http.tls_cert", "tls_cert and tls_key must be set together")
+	}
+	if (c.HTTP.TLSCertFile == "") != (c.HTTP.TLSKeyFile == "") {
+		add("http.tls_cert_file", "tls_cert_file and tls_key_file must be set together")
+	}
+	if c.HTTP.Port < 0 || c.HTTP.Port > 65535 {
+		add("http.port", "must be between 0 and 65535")
+	}
+
+	validatorsMu.RLock()
+	defer validatorsMu.RUnlock()
+	for _, v := range validators {
+		ps = append(ps, v(c)...)
+	}
+	sort.SliceStable(ps, func(i, j int) bool { return ps[i].Key < ps[j].Key })
+	return ps
+}
+
+// checkURL reports whether a set URL is an absolute http(s) URL
+func checkURL(s string) error {
+	if s == "" {
+		return nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q: want http(s)://host", s)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validConfig = `poll_interval: 30s
+ntfy:
+  url: https://ntfy.sh
+  topic: tasks
+`
+
+func TestParse_Valid(t *testing.T) {
+	cfg, problems := Parse([]byte(validConfig))
+	if len(problems) != 0 {
+		t.Fatalf("unexpected problems: %v", problems)
+	}
+	if cfg.Ntfy.Topic != "tasks" {
+		t.Fatalf("expected the config to be decoded, got %+v", cfg)
+	}
+}
+
+func TestParse_Problems(t *testing.T) {
+	data := validConfig + `sync_interval: -5m
+log_level: loud
+web:
+  listen: ":8080"
+gotify:
+  url: "gotify.example.com"
+http:
+  tls_cert: /etc/cert.pem
+routes:
+  - name: work
+    target:
+      topicc: work
+logging:
+  output: file
+  levels:
+    scheduler: debug
+source:
+  type: dir
+`
+	_, problems := Parse([]byte(data))
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`line 5: sync_interval: must not be negative`,
+		`line 6: log_level: unknown level "loud" (want error, warn, info, debug or verbose)`,
+		`line 7: web: unknown setting`,
+		`line 10: gotify.url: invalid URL "gotify.example.com": want http(s)://host`,
+		`line 12: http.tls_cert: tls_cert and tls_key must be set together`,
+		`line 16: routes[0].target.topicc: unknown setting`,
+		`line 17: logging.file.path: required for the file output`,
+		`line 20: logging.levels.scheduler: unknown component (want app, poller, sync, notify, http, hook, task)`,
+		`line 21: source.path: required for the dir source`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParse_Required(t *testing.T) {
+	_, problems := Parse([]byte("ntfy:\n  url: https://ntfy.sh\n"))
+	got := problems.Error()
+	for _, want := range []string{"poll_interval: must be a positive duration", "line 1: ntfy.topic: required"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestParse_SyntaxError(t *testing.T) {
+	_, problems := Parse([]byte("poll_interval: 30s\nntfy: [\n"))
+	if len(problems) != 1 || problems[0].Line == 0 {
+		t.Fatalf("expected one syntax error with a line, got %v", problems)
+	}
+}
+
+func TestLoadConfig_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(path, []byte(validConfig+"web:\n  listen: \":8080\"\n"), 0o644)
+	_, err := LoadConfig(path)
+	var problems Problems
+	if !errors.As(err, &problems) || len(problems) != 1 || problems[0].Line != 5 {
+		t.Fatalf("expected the unknown setting to be reported, got %v", err)
+	}
+}
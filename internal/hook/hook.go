@@ -0,0 +1,219 @@
+// Package hook connects Taskwarrior hooks to the running daemon. The hook
+// side (task-herald hook on-add|on-modify) echoes the task back to
+// Taskwarrior and forwards it over a Unix socket; the daemon side listens on
+// that socket and applies each forwarded task immediately.
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/taskwarrior"
+)
+
+// Taskwarrior hook events handled by Run
+const (
+	EventAdd    = "on-add"
+	EventModify = "on-modify"
+)
+
+// Timeouts used when forwarding so a stopped or hung daemon never blocks Taskwarrior
+var (
+	dialTimeout  = 500 * time.Millisecond
+	writeTimeout = time.Second
+)
+
+var hookLog = config.Logger(config.LogHook)
+
+// DefaultSocketPath returns the socket used when none is configured:
+// $XDG_RUNTIME_DIR/task-herald.sock, or a per-user file in the temp dir
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "task-herald.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("task-herald-%d.sock", os.Getuid()))
+}
+
+// Run implements a Taskwarrior hook. It reads the task JSON for event from
+// in (one line for on-add; the original and modified task for on-modify),
+// writes the task Taskwarrior should store to out unchanged and forwards it
+// to the daemon on socket. A daemon that is not running is not an error:
+// the poller picks the change up later.
+func Run(event string, in io.Reader, out io.Writer, socket string) error {
+	var lines [][]byte
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading task: %w", err)
+	}
+	var task []byte
+	switch event {
+	case EventAdd:
+		if len(lines) != 1 {
+			return fmt.Errorf("%s: expected 1 task, got %d", event, len(lines))
+		}
+		task = lines[0]
+	case EventModify:
+		if len(lines) != 2 {
+			return fmt.Errorf("%s: expected 2 tasks, got %d", event, len(lines))
+		}
+		task = lines[1]
+	default:
+		return fmt.Errorf("unsupported hook event %q (want %s or %s)", event, EventAdd, EventModify)
+	}
+	if !json.Valid(task) {
+		return fmt.Errorf("%s: invalid task JSON", event)
+	}
+	if _, err := fmt.Fprintf(out, "%s\n", task); err != nil {
+		return err
+	}
+	// Best effort; Taskwarrior must not fail because the daemon is down
+	_ = Forward(socket, task)
+	return nil
+}
+
+// Forward sends a single task JSON object to the daemon listening on socket
+func Forward(socket string, task []byte) error {
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(append(bytes.TrimSpace(task), '\n'))
+	return err
+}
+
+// Listen accepts forwarded tasks on a Unix socket, calling handle for each
+// one, until the returned close function is called. The socket is only
+// accessible to the current user; a stale socket file is replaced.
+func Listen(socket string, handle func(taskwarrior.Task)) (func() error, error) {
+	if err := removeStaleSocket(socket); err != nil {
+		return nil, err
+	}
+	ln, err := listenUnix(socket)
+	if err != nil {
+		return nil, err
+	}
+	// The umask already restricted the socket; chmod in case it was ignored
+	if err := os.Chmod(socket, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					hookLog.Error("Accept failed", "error", err)
+				}
+				return
+			}
+			go serve(conn, handle)
+		}
+	}()
+	return ln.Close, nil
+}
+
+// serve decodes newline-delimited task JSON from one hook connection
+func serve(conn net.Conn, handle func(taskwarrior.Task)) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	for {
+		var task taskwarrior.Task
+		if err := dec.Decode(&task); err != nil {
+			if err != io.EOF {
+				hookLog.Warn("Invalid task from hook", "error", err)
+			}
+			return
+		}
+		if task.UUID == "" {
+			hookLog.Warn("Ignoring forwarded task without uuid")
+			continue
+		}
+		hookLog.Debug("Received task", "task_uuid", task.UUID, "status", task.Status)
+		handle(task)
+	}
+}
+
+// removeStaleSocket removes a socket file left behind by a daemon that is no
+// longer running; a socket with a live listener is an error
+func removeStaleSocket(socket string) error {
+	fi, err := os.Stat(socket)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", socket)
+	}
+	if conn, err := net.DialTimeout("unix", socket, dialTimeout); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another task-herald", socket)
+	}
+	return os.Remove(socket)
+}
+
+// script is the hook file installed into the Taskwarrior hooks directory
+func script(binary, socket, event string) string {
+	return fmt.Sprintf("#!/bin/sh\n# Installed by task-herald hook install\nexec %s hook --socket %s %s \"$@\"\n",
+		shellQuote(binary), shellQuote(socket), event)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Install writes on-add and on-modify hook scripts into hooksDir that run
+// binary as a hook forwarding to socket, and returns their paths. Existing
+// files with different content are only replaced when force is set.
+func Install(hooksDir, binary, socket string, force bool) ([]string, error) {
+	if err := os.MkdirAll(hooksDir, 0o755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, event := range []string{EventAdd, EventModify} {
+		path := filepath.Join(hooksDir, event+".task-herald")
+		content := script(binary, socket, event)
+		if existing, err := os.ReadFile(path); err == nil && string(existing) != content && !force {
+			return written, fmt.Errorf("%s already exists; use --force to replace it", path)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o755); err != nil {
+			return written, err
+		}
+		// WriteFile keeps the mode of an existing file
+		if err := os.Chmod(path, 0o755); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// DefaultHooksDir returns the Taskwarrior hooks directory: ~/.task/hooks
+func DefaultHooksDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".task", "hooks")
+	}
+	return filepath.Join(home, ".task", "hooks")
+}
@@ -0,0 +1,142 @@
+package hook
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"task-herald/internal/taskwarrior"
+)
+
+func listen(t *testing.T) (string, chan taskwarrior.Task) {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "h.sock")
+	got := make(chan taskwarrior.Task, 4)
+	closeFn, err := Listen(socket, func(task taskwarrior.Task) { got <- task })
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { closeFn() })
+	return socket, got
+}
+
+func receive(t *testing.T, got chan taskwarrior.Task) taskwarrior.Task {
+	t.Helper()
+	select {
+	case task := <-got:
+		return task
+	case <-time.After(2 * time.Second):
+		t.Fatal("no task forwarded")
+	}
+	return taskwarrior.Task{}
+}
+
+func TestRun_OnAddEchoesAndForwards(t *testing.T) {
+	socket, got := listen(t)
+	in := `{"uuid":"u1","description":"new","status":"pending","due":"20250901T090000Z"}` + "\n"
+	var out bytes.Buffer
+	if err := Run(EventAdd, strings.NewReader(in), &out, socket); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != in {
+		t.Fatalf("task not echoed unchanged: %q", out.String())
+	}
+	task := receive(t, got)
+	if task.UUID != "u1" || task.Due != "20250901T090000Z" {
+		t.Fatalf("unexpected forwarded task: %+v", task)
+	}
+}
+
+func TestRun_OnModifyForwardsModifiedTask(t *testing.T) {
+	socket, got := listen(t)
+	in := `{"uuid":"u1","description":"old","status":"pending"}` + "\n" + `{"uuid":"u1","description":"done","status":"completed"}` + "\n"
+	var out bytes.Buffer
+	if err := Run(EventModify, strings.NewReader(in), &out, socket); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if !strings.Contains(out.String(), `"completed"`) || strings.Contains(out.String(), `"old"`) {
+		t.Fatalf("expected only the modified task echoed, got %q", out.String())
+	}
+	if task := receive(t, got); task.Status != "completed" {
+		t.Fatalf("expected modified task forwarded, got %+v", task)
+	}
+}
+
+func TestRun_DaemonNotRunning(t *testing.T) {
+	var out bytes.Buffer
+	socket := filepath.Join(t.TempDir(), "missing.sock")
+	if err := Run(EventAdd, strings.NewReader(`{"uuid":"u1"}`), &out, socket); err != nil {
+		t.Fatalf("expected no error without a daemon, got %v", err)
+	}
+	if strings.TrimSpace(out.String()) != `{"uuid":"u1"}` {
+		t.Fatalf("task not echoed: %q", out.String())
+	}
+}
+
+func TestRun_InvalidInput(t *testing.T) {
+	cases := []struct{ event, in string }{
+		{EventAdd, ""},
+		{EventAdd, "not json"},
+		{EventModify, `{"uuid":"u1"}`},
+		{"on-exit", `{"uuid":"u1"}`},
+	}
+	for _, c := range cases {
+		if err := Run(c.event, strings.NewReader(c.in), &bytes.Buffer{}, "unused"); err == nil {
+			t.Errorf("%s %q: expected error", c.event, c.in)
+		}
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "h.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave the socket file behind like a crashed daemon
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	closeFn, err := Listen(socket, func(taskwarrior.Task) {})
+	if err != nil {
+		t.Fatalf("expected stale socket to be replaced, got %v", err)
+	}
+	defer closeFn()
+	if fi, err := os.Stat(socket); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected socket with mode 0600, got %v %v", fi, err)
+	}
+	if _, err := Listen(socket, func(taskwarrior.Task) {}); err == nil {
+		t.Fatal("expected error for a socket in use")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	paths, err := Install(dir, "/usr/bin/task-herald", "/run/user/1000/task-herald.sock", false)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if len(paths) != 2 || filepath.Base(paths[0]) != "on-add.task-herald" || filepath.Base(paths[1]) != "on-modify.task-herald" {
+		t.Fatalf("unexpected hook files: %v", paths)
+	}
+	data, _ := os.ReadFile(paths[1])
+	if !strings.Contains(string(data), `exec '/usr/bin/task-herald' hook --socket '/run/user/1000/task-herald.sock' on-modify "$@"`) {
+		t.Fatalf("unexpected hook script:\n%s", data)
+	}
+	if fi, _ := os.Stat(paths[0]); fi.Mode().Perm() != 0o755 {
+		t.Fatalf("hook not executable: %v", fi.Mode())
+	}
+	// Reinstalling the same hooks is fine; replacing a different file needs force
+	if _, err := Install(dir, "/usr/bin/task-herald", "/run/user/1000/task-herald.sock", false); err != nil {
+		t.Fatalf("reinstall: %v", err)
+	}
+	if _, err := Install(dir, "/opt/task-herald", "/tmp/x.sock", false); err == nil {
+		t.Fatal("expected error replacing a different hook without force")
+	}
+	if _, err := Install(dir, "/opt/task-herald", "/tmp/x.sock", true); err != nil {
+		t.Fatalf("forced install: %v", err)
+	}
+}
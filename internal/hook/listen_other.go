@@ -0,0 +1,9 @@
+//go:build windows || plan9
+
+package hook
+
+import "net"
+
+func listenUnix(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
@@ -0,0 +1,17 @@
+//go:build !windows && !plan9
+
+package hook
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenUnix creates the socket with a umask that leaves it accessible to
+// the current user only, so nobody can connect before Listen chmods it. The
+// umask is process-wide; files created meanwhile are at most more private.
+func listenUnix(socket string) (net.Listener, error) {
+	old := syscall.Umask(0o077)
+	defer syscall.Umask(old)
+	return net.Listen("unix", socket)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	c := NewCounter("test_events_total", "Events by\nkind.", "kind")
+	c.Inc("a")
+	c.Add(2, `b"c`)
+	g := NewGauge("test_items", "Items.")
+	g.Set(3)
+	h := NewHistogram("test_duration_seconds", "Durations.", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+
+	var out strings.Builder
+	if err := Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 2.55
+test_duration_seconds_count 3
+# HELP test_events_total Events by\nkind.
+# TYPE test_events_total counter
+test_events_total{kind="a"} 1
+test_events_total{kind="b\"c"} 2
+# HELP test_items Items.
+# TYPE test_items gauge
+test_items 3
+`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("got\n%s\nwant it to contain\n%s", out.String(), want)
+	}
+	if c.Value("a") != 1 || g.Value() != 3 || h.Count() != 3 {
+		t.Errorf("unexpected values %v %v %v", c.Value("a"), g.Value(), h.Count())
+	}
+}
+
+func TestHandler(t *testing.T) {
+	NewCounter("test_requests_total", "Requests.")
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected response %d %v", rec.Code, rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "\ntest_requests_total 0\n") {
+		t.Errorf("expected the counter to be reported from the start, got\n%s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for POST, got %d", rec.Code)
+	}
+}
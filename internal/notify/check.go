@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"task-herald/internal/config"
+)
+
+func init() {
+	config.RegisterValidator(validateConfig)
+}
+
+// sampleTask has every field set, so templates referring to unknown fields
+// or methods fail to execute against it
+func sampleTask() TaskInfo {
+	at := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	return TaskInfo{
+		ID: "1", UUID: "00000000-0000-0000-0000-000000000001", Description: "Sample task",
+		Tags: []string{"tag"}, Due: &at, Scheduled: &at, Wait: &at, Until: &at, NotificationDate: &at,
+		Project: "project", Priority: "H", Status: "pending", Urgency: 1, Annotations: []string{"note"},
+		UDAs: map[string]interface{}{},
+	}
+}
+
+// CheckTemplate reports whether tmpl parses and executes against data
+func CheckTemplate(tmpl string, funcs template.FuncMap, data interface{}) error {
+	t, err := template.New("check").Funcs(funcs).Parse(tmpl)
+	if err != nil {
+		return err
+	}
+	return t.Execute(io.Discard, data)
+}
+
+// validateConfig checks backend names and every template in cfg
+func validateConfig(cfg *config.Config) config.Problems {
+	var ps config.Problems
+	check := func(key, tmpl string, funcs template.FuncMap, data interface{}) {
+		if tmpl == "" {
+			return
+		}
+		if err := CheckTemplate(tmpl, funcs, data); err != nil {
+			ps = append(ps, config.Problem{Key: key, Msg: fmt.Sprintf("invalid template: %v", templateError(err))})
+		}
+	}
+	backend := func(key, name string) {
+		if name == "" {
+			return
+		}
+		registryMu.RLock()
+		_, ok := registry[name]
+		registryMu.RUnlock()
+		if !ok {
+			ps = append(ps, config.Problem{Key: key, Msg: fmt.Sprintf("unknown notifier backend %q (available: %s)", name, strings.Join(Backends(), ", "))})
+		}
+	}
+	task := sampleTask()
+	digest := Digest{Title: "Digest", Tasks: []TaskInfo{task}}
+	target := func(key string, t config.RouteTarget) {
+		backend(key+".backend", t.Backend)
+		check(key+".message", t.Message, nil, task)
+		for _, h := range sortedKeys(t.Headers) {
+			check(key+".headers."+h, t.Headers[h], nil, task)
+		}
+	}
+
+	backend("notifier", cfg.Notifier)
+	check("notification_message", cfg.NotificationMessage, nil, task)
+	for _, h := range sortedKeys(cfg.Ntfy.Headers) {
+		check("ntfy.headers."+h, cfg.Ntfy.Headers[h], nil, task)
+	}
+	check("webhook.body", cfg.Webhook.Body, webhookFuncs, WebhookPayload{Title: "title", Message: "message", Tags: []string{"tag"}})
+	check("catch_up.digest_message", cfg.CatchUp.DigestMessage, nil, digest)
+	for i, r := range cfg.Routes {
+		target(fmt.Sprintf("routes[%d].target", i), r.Target)
+	}
+	for i, d := range cfg.Digests {
+		check(fmt.Sprintf("digests[%d].message", i), d.Message, nil, digest)
+		backend(fmt.Sprintf("digests[%d].target.backend", i), d.Target.Backend)
+	}
+	for i, e := range cfg.Escalations {
+		for j, s := range e.Steps {
+			target(fmt.Sprintf("escalations[%d].steps[%d].target", i, j), s.Target)
+		}
+	}
+	return ps
+}
+
+// templatePrefix is what text/template puts before errors of the check
+// template, e.g. `template: check:1:2: executing "check" `
+var templatePrefix = regexp.MustCompile(`^template: check:\d+(:\d+)?: (executing "check" )?`)
+
+func templateError(err error) string {
+	return templatePrefix.ReplaceAllString(err.Error(), "")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
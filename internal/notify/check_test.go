@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestValidateConfig_Templates(t *testing.T) {
+	data := `poll_interval: 30s
+notification_message: "{{.Description}} {{.UDA \"estimate\"}} {{.Due.Format \"15:04\"}}"
+ntfy:
+  url: https://ntfy.sh
+  topic: tasks
+  headers:
+    X-Title: "{{.Projekt}}"
+webhook:
+  body: '{"text": {{json .Message}}}'
+catch_up:
+  digest_message: "{{range .Tasks}}{{.Description}}{{end"
+routes:
+  - name: work
+    target:
+      backend: pigeon
+      message: "{{.Description | shout}}"
+`
+	_, problems := config.Parse([]byte(data))
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`line 7: ntfy.headers.X-Title: invalid template: at <.Projekt>: can't evaluate field Projekt in type notify.TaskInfo`,
+		`line 11: catch_up.digest_message: invalid template: unclosed action`,
+		`line 15: routes[0].target.backend: unknown notifier backend "pigeon"`,
+		`line 16: routes[0].target.message: invalid template: function "shout" not defined`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	for i := range want {
+		if !strings.HasPrefix(got[i], want[i]) {
+			t.Errorf("problem %d = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"task-herald/internal/config"
+)
+
+// GotifySender sends messages to a Gotify server
+type GotifySender struct {
+	cfg    config.GotifyConfig
+	logger func(format string, v ...interface{})
+}
+
+func init() {
+	RegisterCheck("gotify", checkGotify)
+	Register("gotify", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		if err := checkGotify(cfg); err != nil {
+			return nil, err
+		}
+		return &GotifySender{cfg: cfg.Gotify, logger: logger}, nil
+	})
+}
+
+func checkGotify(cfg *config.Config) error {
+	if cfg.Gotify.URL == "" || cfg.Gotify.Token == "" {
+		return fmt.Errorf("gotify: url and token are required")
+	}
+	return nil
+}
+
+// Send posts the message to /message; ntfy priorities 1-5 map to Gotify 2-10
+func (g *GotifySender) Send(ctx context.Context, message string, headers map[string]string) error {
+	m := headerMeta(headers)
+	payload := map[string]interface{}{
+		"message":  message,
+		"priority": m.Priority * 2,
+	}
+	if m.Title != "" {
+		payload["title"] = m.Title
+	}
+	if m.Click != "" {
+		payload["extras"] = map[string]interface{}{
+			"client::notification": map[string]interface{}{"click": map[string]string{"url": m.Click}},
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	url := strings.TrimRight(g.cfg.URL, "/") + "/message"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", g.cfg.Token)
+	return doRequest(req, "gotify", g.logger)
+}
+
+// doRequest sends req and treats non-2xx responses as errors
+func doRequest(req *http.Request, backend string, logger func(format string, v ...interface{})) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		if logger != nil {
+			logger("%s: failed to send notification: %v", backend, err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		if logger != nil {
+			logger("%s server returned status: %s", backend, resp.Status)
+		}
+		return fmt.Errorf("%s server returned status: %s", backend, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestGotifySender_Send(t *testing.T) {
+	var gotPath, gotKey string
+	var got map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotKey = r.Header.Get("X-Gotify-Key")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(200)
+	}))
+	defer srv.Close()
+
+	s, err := New("gotify", &config.Config{Gotify: config.GotifyConfig{URL: srv.URL + "/", Token: "apptoken"}}, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.Send(context.Background(), "hello", map[string]string{"X-Title": "proj", "X-Default": "high"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if gotPath != "/message" || gotKey != "apptoken" {
+		t.Fatalf("unexpected request: path=%q key=%q", gotPath, gotKey)
+	}
+	if got["message"] != "hello" || got["title"] != "proj" || got["priority"] != float64(8) {
+		t.Fatalf("unexpected payload: %v", got)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(401) }))
+	defer bad.Close()
+	s, _ = New("gotify", &config.Config{Gotify: config.GotifyConfig{URL: bad.URL, Token: "x"}}, nil)
+	if err := s.Send(context.Background(), "x", nil); err == nil {
+		t.Fatal("expected error for non-2xx response")
+	}
+}
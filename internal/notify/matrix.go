@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"task-herald/internal/config"
+)
+
+// MatrixSender posts messages to a Matrix room via the client-server API
+type MatrixSender struct {
+	cfg    config.MatrixConfig
+	logger func(format string, v ...interface{})
+}
+
+func init() {
+	RegisterCheck("matrix", checkMatrix)
+	Register("matrix", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		if err := checkMatrix(cfg); err != nil {
+			return nil, err
+		}
+		return &MatrixSender{cfg: cfg.Matrix, logger: logger}, nil
+	})
+}
+
+func checkMatrix(cfg *config.Config) error {
+	m := cfg.Matrix
+	if m.Homeserver == "" || m.AccessToken == "" || m.RoomID == "" {
+		return fmt.Errorf("matrix: homeserver, access_token and room_id are required")
+	}
+	return nil
+}
+
+// Send PUTs an m.room.message event with a unique transaction ID
+func (m *MatrixSender) Send(ctx context.Context, message string, headers map[string]string) error {
+	meta := headerMeta(headers)
+	msgtype := m.cfg.MsgType
+	if msgtype == "" {
+		msgtype = "m.text"
+	}
+	body := message
+	formatted := strings.ReplaceAll(html.EscapeString(message), "\n", "<br>")
+	if meta.Title != "" {
+		body = meta.Title + "\n" + message
+		formatted = "<strong>" + html.EscapeString(meta.Title) + "</strong><br>" + formatted
+	}
+	content := map[string]string{
+		"msgtype":        msgtype,
+		"body":           body,
+		"format":         "org.matrix.custom.html",
+		"formatted_body": formatted,
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	txn := make([]byte, 8)
+	if _, err := rand.Read(txn); err != nil {
+		return err
+	}
+	endpoint := fmt.Sprintf("%s/_matrix/client/v3/rooms/%s/send/m.room.message/%s",
+		strings.TrimRight(m.cfg.Homeserver, "/"), url.PathEscape(m.cfg.RoomID), hex.EncodeToString(txn))
+	req, err := http.NewRequestWithContext(ctx, "PUT", endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.cfg.AccessToken)
+	return doRequest(req, "matrix", m.logger)
+}
@@ -0,0 +1,47 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestMatrixSender_Send(t *testing.T) {
+	var paths []string
+	var got map[string]string
+	var gotAuth, gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.EscapedPath())
+		gotAuth = r.Header.Get("Authorization")
+		gotMethod = r.Method
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"event_id":"$1"}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Matrix: config.MatrixConfig{Homeserver: srv.URL, AccessToken: "mtok", RoomID: "!room:example.org"}}
+	s, err := New("matrix", cfg, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Send(context.Background(), "a <b>", map[string]string{"X-Title": "proj"}); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+	}
+	prefix := "/_matrix/client/v3/rooms/%21room:example.org/send/m.room.message/"
+	if !strings.HasPrefix(paths[0], prefix) || paths[0] == paths[1] {
+		t.Fatalf("expected unique transaction paths under %s, got %v", prefix, paths)
+	}
+	if gotMethod != "PUT" || gotAuth != "Bearer mtok" {
+		t.Fatalf("unexpected request: %s auth=%q", gotMethod, gotAuth)
+	}
+	if got["msgtype"] != "m.text" || got["body"] != "proj\na <b>" || got["formatted_body"] != "<strong>proj</strong><br>a &lt;b&gt;" {
+		t.Fatalf("unexpected content: %v", got)
+	}
+}
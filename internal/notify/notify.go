@@ -25,7 +25,7 @@ func (n *Notifier) Send(ctx context.Context, message string, headers map[string]
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer([]byte(message)))
 	if err != nil {
 		if n.logger != nil {
-			n.logger("[notify] failed to create request: %v", err)
+			n.logger("ntfy: failed to create request: %v", err)
 		}
 		return err
 	}
@@ -40,17 +40,17 @@ func (n *Notifier) Send(ctx context.Context, message string, headers map[string]
 	for k, v := range headers {
 		req.Header.Set(k, v)
 	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		if n.logger != nil {
-			n.logger("[notify] failed to send notification: %v", err)
+			n.logger("ntfy: failed to send notification: %v", err)
 		}
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode >= 300 {
 		if n.logger != nil {
-			n.logger("[notify] ntfy server returned status: %s", resp.Status)
+			n.logger("ntfy server returned status: %s", resp.Status)
 		}
 		return fmt.Errorf("ntfy server returned status: %s", resp.Status)
 	}
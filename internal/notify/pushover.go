@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"task-herald/internal/config"
+)
+
+// DefaultPushoverURL is the Pushover messages API endpoint
+const DefaultPushoverURL = "https://api.pushover.net/1/messages.json"
+
+// PushoverSender sends messages to Pushover or a compatible API
+type PushoverSender struct {
+	cfg    config.PushoverConfig
+	logger func(format string, v ...interface{})
+}
+
+func init() {
+	RegisterCheck("pushover", checkPushover)
+	Register("pushover", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		if err := checkPushover(cfg); err != nil {
+			return nil, err
+		}
+		return &PushoverSender{cfg: cfg.Pushover, logger: logger}, nil
+	})
+}
+
+func checkPushover(cfg *config.Config) error {
+	if cfg.Pushover.Token == "" || cfg.Pushover.User == "" {
+		return fmt.Errorf("pushover: token and user are required")
+	}
+	return nil
+}
+
+// Send posts a form-encoded message; ntfy priorities 1-5 map to Pushover -2..2.
+// Emergency priority (2) requires retry/expire, so max maps to 1.
+func (p *PushoverSender) Send(ctx context.Context, message string, headers map[string]string) error {
+	m := headerMeta(headers)
+	prio := m.Priority - 3
+	if prio > 1 {
+		prio = 1
+	}
+	form := url.Values{}
+	form.Set("token", p.cfg.Token)
+	form.Set("user", p.cfg.User)
+	form.Set("message", message)
+	form.Set("priority", strconv.Itoa(prio))
+	if m.Title != "" {
+		form.Set("title", m.Title)
+	}
+	if m.Click != "" {
+		form.Set("url", m.Click)
+	}
+	if p.cfg.Device != "" {
+		form.Set("device", p.cfg.Device)
+	}
+	endpoint := p.cfg.URL
+	if endpoint == "" {
+		endpoint = DefaultPushoverURL
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return doRequest(req, "pushover", p.logger)
+}
@@ -0,0 +1,45 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestPushoverSender_Send(t *testing.T) {
+	var form map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = map[string]string{}
+		for k := range r.PostForm {
+			form[k] = r.PostForm.Get(k)
+		}
+		_, _ = w.Write([]byte(`{"status":1}`))
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{Pushover: config.PushoverConfig{URL: srv.URL, Token: "app", User: "usr", Device: "phone"}}
+	s, err := New("pushover", cfg, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.Send(context.Background(), "hello", map[string]string{"X-Title": "proj", "X-Default": "max", "X-Click": "https://example.com"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := map[string]string{"token": "app", "user": "usr", "message": "hello", "title": "proj", "priority": "1", "url": "https://example.com", "device": "phone"}
+	for k, v := range want {
+		if form[k] != v {
+			t.Errorf("form[%s] = %q, want %q", k, form[k], v)
+		}
+	}
+
+	if err := s.Send(context.Background(), "low", map[string]string{"X-Default": "min"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if form["priority"] != "-2" {
+		t.Errorf("expected priority -2 for min, got %q", form["priority"])
+	}
+}
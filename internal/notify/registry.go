@@ -0,0 +1,168 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task-herald/internal/config"
+)
+
+// Sender is implemented by every notification backend. Headers use the ntfy
+// names (X-Title, X-Default/X-Priority, X-Click, X-Tags); backends other than
+// ntfy translate the ones they support and ignore the rest.
+type Sender interface {
+	Send(ctx context.Context, message string, headers map[string]string) error
+}
+
+// SendTimeout bounds a single send, so a hung backend cannot stall the
+// notifications queued behind it
+const SendTimeout = 30 * time.Second
+
+// httpClient is used by the HTTP backends; its timeout applies even when
+// the caller's context has no deadline
+var httpClient = &http.Client{Timeout: SendTimeout}
+
+// Factory builds a backend from the configuration
+type Factory func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error)
+
+// Checker reports settings a backend is missing without building it
+type Checker func(cfg *config.Config) error
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{}
+	checks     = map[string]Checker{}
+)
+
+// Register makes a backend available under name
+func Register(name string, f Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// RegisterCheck sets the settings check of the backend registered under name
+func RegisterCheck(name string, c Checker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	checks[name] = c
+}
+
+// Backends returns the registered backend names in sorted order
+func Backends() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the backend registered under name
+func New(name string, cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+	registryMu.RLock()
+	f, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown notifier backend %q (available: %s)", name, strings.Join(Backends(), ", "))
+	}
+	return f(cfg, logger)
+}
+
+// Check validates the settings of the backend registered under name without
+// building it, so nothing is opened or connected. Backends without a check
+// only need to be registered.
+func Check(name string, cfg *config.Config) error {
+	registryMu.RLock()
+	_, ok := registry[name]
+	c := checks[name]
+	registryMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown notifier backend %q (available: %s)", name, strings.Join(Backends(), ", "))
+	}
+	if c == nil {
+		return nil
+	}
+	return c(cfg)
+}
+
+func init() {
+	RegisterCheck("ntfy", checkNtfy)
+	Register("ntfy", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		if err := checkNtfy(cfg); err != nil {
+			return nil, err
+		}
+		return NewNotifier(cfg.Ntfy, logger), nil
+	})
+}
+
+func checkNtfy(cfg *config.Config) error {
+	if cfg.Ntfy.URL == "" {
+		return fmt.Errorf("ntfy: url is required")
+	}
+	return nil
+}
+
+// meta is the backend-neutral view of the ntfy-style headers
+type meta struct {
+	Title    string
+	Priority int // 1 (min) to 5 (max), 3 is default
+	Click    string
+	Tags     []string
+}
+
+// headerMeta extracts title, priority, click URL and tags from ntfy-style headers
+func headerMeta(headers map[string]string) meta {
+	m := meta{Priority: 3}
+	get := func(names ...string) string {
+		for _, n := range names {
+			for k, v := range headers {
+				if strings.EqualFold(k, n) && v != "" {
+					return v
+				}
+			}
+		}
+		return ""
+	}
+	m.Title = get("X-Title", "Title")
+	m.Click = get("X-Click", "Click")
+	if p := get("X-Priority", "Priority", "X-Default"); p != "" {
+		m.Priority = parsePriority(p)
+	}
+	if t := get("X-Tags", "Tags"); t != "" {
+		for _, tag := range strings.Split(t, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				m.Tags = append(m.Tags, tag)
+			}
+		}
+	}
+	return m
+}
+
+// parsePriority maps ntfy priority names or numbers to 1-5
+func parsePriority(p string) int {
+	switch strings.ToLower(strings.TrimSpace(p)) {
+	case "min":
+		return 1
+	case "low":
+		return 2
+	case "default", "":
+		return 3
+	case "high":
+		return 4
+	case "max", "urgent":
+		return 5
+	}
+	if n, err := strconv.Atoi(p); err == nil && n >= 1 && n <= 5 {
+		return n
+	}
+	return 3
+}
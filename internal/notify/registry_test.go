@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestBackends_Registered(t *testing.T) {
+	want := []string{"gotify", "matrix", "ntfy", "pushover", "smtp", "webhook"}
+	got := Backends()
+	if len(got) != len(want) {
+		t.Fatalf("Backends() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Backends() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestNew_UnknownAndInvalid(t *testing.T) {
+	if _, err := New("pigeon", &config.Config{}, nil); err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+	// every backend rejects an empty configuration
+	for _, name := range Backends() {
+		if _, err := New(name, &config.Config{}, nil); err == nil {
+			t.Errorf("%s: expected error for empty config", name)
+		}
+	}
+	n, err := New("ntfy", &config.Config{Ntfy: config.NtfyConfig{URL: "https://ntfy.example.com", Topic: "t"}}, nil)
+	if err != nil {
+		t.Fatalf("New(ntfy): %v", err)
+	}
+	if _, ok := n.(*Notifier); !ok {
+		t.Fatalf("expected *Notifier, got %T", n)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check("pigeon", &config.Config{}); err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+	// Check rejects what New rejects
+	for _, name := range Backends() {
+		if err := Check(name, &config.Config{}); err == nil {
+			t.Errorf("%s: expected error for empty config", name)
+		}
+	}
+	if err := Check("gotify", &config.Config{Gotify: config.GotifyConfig{URL: "https://gotify.example.com", Token: "t"}}); err != nil {
+		t.Fatalf("Check(gotify): %v", err)
+	}
+}
+
+func TestHeaderMeta(t *testing.T) {
+	m := headerMeta(map[string]string{"X-Title": "proj", "X-Default": "max", "x-click": "https://example.com", "X-Tags": "a, b"})
+	if m.Title != "proj" || m.Priority != 5 || m.Click != "https://example.com" || len(m.Tags) != 2 {
+		t.Fatalf("unexpected meta: %+v", m)
+	}
+	if headerMeta(nil).Priority != 3 {
+		t.Fatal("expected default priority 3")
+	}
+	for in, want := range map[string]int{"min": 1, "low": 2, "high": 4, "urgent": 5, "2": 2, "9": 3, "bogus": 3} {
+		if got := parsePriority(in); got != want {
+			t.Errorf("parsePriority(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+)
+
+// SMTPSender sends notifications as plain-text email
+type SMTPSender struct {
+	cfg    config.SMTPConfig
+	logger func(format string, v ...interface{})
+}
+
+func init() {
+	RegisterCheck("smtp", checkSMTP)
+	Register("smtp", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		if err := checkSMTP(cfg); err != nil {
+			return nil, err
+		}
+		return &SMTPSender{cfg: cfg.SMTP, logger: logger}, nil
+	})
+}
+
+func checkSMTP(cfg *config.Config) error {
+	s := cfg.SMTP
+	if s.Host == "" || s.From == "" || len(s.To) == 0 {
+		return fmt.Errorf("smtp: host, from and to are required")
+	}
+	return nil
+}
+
+// Send delivers the message to all recipients. The title becomes the
+// subject and priority 4-5 sets the X-Priority/Importance headers.
+func (s *SMTPSender) Send(ctx context.Context, message string, headers map[string]string) error {
+	m := headerMeta(headers)
+	subject := m.Title
+	if subject == "" {
+		subject = "Task reminder"
+	}
+	var b strings.Builder
+	b.WriteString("From: " + s.cfg.From + "\r\n")
+	b.WriteString("To: " + strings.Join(s.cfg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	if m.Priority >= 4 {
+		b.WriteString("X-Priority: 1\r\nImportance: high\r\n")
+	}
+	b.WriteString("\r\n")
+	body := message
+	if m.Click != "" {
+		body += "\n\n" + m.Click
+	}
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	if err := s.deliver(ctx, []byte(b.String())); err != nil {
+		if s.logger != nil {
+			s.logger("smtp: failed to send notification: %v", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// deliver runs the SMTP transaction, upgrading with STARTTLS when offered
+func (s *SMTPSender) deliver(ctx context.Context, msg []byte) error {
+	port := s.cfg.Port
+	if port == 0 {
+		port = 587
+		if s.cfg.ImplicitTLS {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(port))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if s.cfg.ImplicitTLS {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: s.cfg.Host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(SendTimeout)
+	}
+	conn.SetDeadline(deadline)
+	c, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if !s.cfg.ImplicitTLS {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(&tls.Config{ServerName: s.cfg.Host}); err != nil {
+				return err
+			}
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range s.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
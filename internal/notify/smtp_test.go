@@ -0,0 +1,118 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+// fakeSMTPServer accepts one message and records the envelope and data
+type fakeSMTPServer struct {
+	ln    net.Listener
+	from  string
+	rcpts []string
+	data  string
+	done  chan struct{}
+}
+
+func startFakeSMTP(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTPServer{ln: ln, done: make(chan struct{})}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	write := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	write("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		upper := strings.ToUpper(cmd)
+		switch {
+		case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+			write("250 localhost")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<> ")
+			write("250 OK")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			s.rcpts = append(s.rcpts, strings.Trim(cmd[len("RCPT TO:"):], "<> "))
+			write("250 OK")
+		case upper == "DATA":
+			write("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.data = b.String()
+			write("250 queued")
+		case upper == "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 OK")
+		}
+	}
+}
+
+func TestSMTPSender_Send(t *testing.T) {
+	srv := startFakeSMTP(t)
+	host, port, _ := net.SplitHostPort(srv.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	cfg := &config.Config{SMTP: config.SMTPConfig{Host: host, Port: p, From: "herald@example.com", To: []string{"a@example.com", "b@example.com"}}}
+	s, err := New("smtp", cfg, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := s.Send(context.Background(), "water the plants\nnow", map[string]string{"X-Title": "home", "X-Default": "max"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	<-srv.done
+
+	if srv.from != "herald@example.com" || len(srv.rcpts) != 2 {
+		t.Fatalf("unexpected envelope: from=%q rcpts=%v", srv.from, srv.rcpts)
+	}
+	for _, want := range []string{"Subject: home\r\n", "To: a@example.com, b@example.com\r\n", "X-Priority: 1\r\n", "\r\n\r\nwater the plants\r\nnow\r\n"} {
+		if !strings.Contains(srv.data, want) {
+			t.Errorf("message missing %q:\n%s", want, srv.data)
+		}
+	}
+}
+
+func TestSMTPSender_ConnectionRefused(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().(*net.TCPAddr)
+	ln.Close()
+	s := &SMTPSender{cfg: config.SMTPConfig{Host: "127.0.0.1", Port: addr.Port, From: "a@b", To: []string{"c@d"}}}
+	if err := s.Send(context.Background(), "x", nil); err == nil {
+		t.Fatal("expected error when the server is unreachable")
+	}
+}
@@ -2,6 +2,9 @@ package notify
 
 import (
 	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 	"time"
 )
@@ -12,9 +15,30 @@ type TaskInfo struct {
 	Description      string
 	Tags             []string
 	Due              *time.Time
+	Scheduled        *time.Time
+	Wait             *time.Time
+	Until            *time.Time
 	NotificationDate *time.Time
 	Project          string
 	Priority         string
+	Status           string
+	Urgency          float64
+	Annotations      []string
+	// UDAs holds the task's user defined attributes as exported by
+	// Taskwarrior; use {{.UDA "name"}} for a printable value
+	UDAs map[string]interface{}
+}
+
+// UDA returns a user defined attribute formatted for display, or "" if unset
+func (t TaskInfo) UDA(name string) string {
+	v, ok := t.UDAs[name]
+	if !ok || v == nil {
+		return ""
+	}
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
 }
 
 const DefaultMessage = `🔔 Task Reminder: {{.Description}}
@@ -39,3 +63,35 @@ func RenderMessage(task TaskInfo, tmpl string) (string, error) {
 	}
 	return buf.String(), nil
 }
+
+// Digest is the template data for a message summarizing several tasks
+type Digest struct {
+	Title string
+	Tasks []TaskInfo
+}
+
+const DefaultDigestMessage = `📬 {{.Title}} ({{len .Tasks}})
+{{range .Tasks}}• {{.Description}}{{if .Project}} [{{.Project}}]{{end}}{{if .NotificationDate}} ({{.NotificationDate.Format "2006-01-02 15:04"}}){{end}}
+{{end}}`
+
+// DefaultAgendaMessage is the default template of scheduled digests
+const DefaultAgendaMessage = `📋 {{.Title}} ({{len .Tasks}})
+{{range .Tasks}}• {{.Description}}{{if .Project}} [{{.Project}}]{{end}}{{if .Due}} (due {{.Due.Format "2006-01-02 15:04"}}){{end}}
+{{else}}Nothing to do.
+{{end}}`
+
+// RenderDigest renders a digest of several tasks with the given template
+func RenderDigest(digest Digest, tmpl string) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultDigestMessage
+	}
+	t, err := template.New("digest").Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, digest); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
@@ -103,3 +103,45 @@ func TestRenderMessage_MalformedTemplate(t *testing.T) {
 		t.Fatalf("expected error for malformed template, got nil")
 	}
 }
+
+func TestRenderDigest(t *testing.T) {
+	at := time.Date(2025, 8, 31, 7, 0, 0, 0, time.UTC)
+	digest := Digest{
+		Title: "Missed notifications",
+		Tasks: []TaskInfo{
+			{ID: "1", Description: "water plants", Project: "home", NotificationDate: &at},
+			{ID: "2", Description: "call bob"},
+		},
+	}
+	got, err := RenderDigest(digest, "")
+	if err != nil {
+		t.Fatalf("RenderDigest default failed: %v", err)
+	}
+	want := "📬 Missed notifications (2)\n• water plants [home] (2025-08-31 07:00)\n• call bob"
+	if got != want {
+		t.Fatalf("unexpected digest:\n got: %q\nwant: %q", got, want)
+	}
+
+	got, err = RenderDigest(digest, "{{range .Tasks}}{{.ID}};{{end}}")
+	if err != nil {
+		t.Fatalf("RenderDigest custom failed: %v", err)
+	}
+	if got != "1;2;" {
+		t.Fatalf("unexpected custom digest: %q", got)
+	}
+
+	if _, err := RenderDigest(digest, "{{.Nope"); err == nil {
+		t.Fatal("expected error for malformed template")
+	}
+}
+
+func TestRenderMessage_UDAs(t *testing.T) {
+	task := TaskInfo{Description: "review", UDAs: map[string]interface{}{"estimate": float64(2.5), "reviewer": "sam"}}
+	got, err := RenderMessage(task, `{{.Description}} for {{.UDA "reviewer"}} ({{.UDA "estimate"}}h){{.UDA "missing"}}`)
+	if err != nil {
+		t.Fatalf("RenderMessage: %v", err)
+	}
+	if got != "review for sam (2.5h)" {
+		t.Fatalf("unexpected message: %q", got)
+	}
+}
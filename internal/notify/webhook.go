@@ -0,0 +1,98 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+
+	"task-herald/internal/config"
+)
+
+// WebhookPayload is the JSON body sent by the webhook backend and the data
+// available to a custom body template
+type WebhookPayload struct {
+	Title    string            `json:"title,omitempty"`
+	Message  string            `json:"message"`
+	Priority int               `json:"priority"`
+	Click    string            `json:"click,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+	Headers  map[string]string `json:"-"`
+}
+
+// WebhookSender POSTs each notification as JSON (or a templated body) to a URL
+type WebhookSender struct {
+	cfg    config.WebhookConfig
+	body   *template.Template
+	logger func(format string, v ...interface{})
+}
+
+// webhookFuncs are available in webhook body templates; json encodes a value
+// so it can be embedded in a JSON document
+var webhookFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func init() {
+	RegisterCheck("webhook", func(cfg *config.Config) error {
+		_, err := NewWebhookSender(cfg.Webhook, nil)
+		return err
+	})
+	Register("webhook", func(cfg *config.Config, logger func(format string, v ...interface{})) (Sender, error) {
+		return NewWebhookSender(cfg.Webhook, logger)
+	})
+}
+
+// NewWebhookSender validates the config and parses the body template
+func NewWebhookSender(cfg config.WebhookConfig, logger func(format string, v ...interface{})) (*WebhookSender, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook: url is required")
+	}
+	w := &WebhookSender{cfg: cfg, logger: logger}
+	if cfg.Body != "" {
+		t, err := template.New("webhook").Funcs(webhookFuncs).Parse(cfg.Body)
+		if err != nil {
+			return nil, fmt.Errorf("webhook: invalid body template: %w", err)
+		}
+		w.body = t
+	}
+	return w, nil
+}
+
+// Send renders the body and sends it with the configured method and headers
+func (w *WebhookSender) Send(ctx context.Context, message string, headers map[string]string) error {
+	m := headerMeta(headers)
+	payload := WebhookPayload{Title: m.Title, Message: message, Priority: m.Priority, Click: m.Click, Tags: m.Tags, Headers: headers}
+	var body []byte
+	if w.body != nil {
+		var buf bytes.Buffer
+		if err := w.body.Execute(&buf, payload); err != nil {
+			return fmt.Errorf("webhook: render body: %w", err)
+		}
+		body = buf.Bytes()
+	} else {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = b
+	}
+	method := w.cfg.Method
+	if method == "" {
+		method = "POST"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	return doRequest(req, "webhook", w.logger)
+}
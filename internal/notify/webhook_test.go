@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestWebhookSender_DefaultJSON(t *testing.T) {
+	var got WebhookPayload
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	s, err := NewWebhookSender(config.WebhookConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer hook"}}, nil)
+	if err != nil {
+		t.Fatalf("NewWebhookSender: %v", err)
+	}
+	if err := s.Send(context.Background(), "hello", map[string]string{"X-Title": "proj", "X-Default": "max"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.Message != "hello" || got.Title != "proj" || got.Priority != 5 || gotAuth != "Bearer hook" {
+		t.Fatalf("unexpected payload %+v auth %q", got, gotAuth)
+	}
+}
+
+func TestWebhookSender_TemplatedBody(t *testing.T) {
+	var gotBody, gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotMethod = r.Method
+	}))
+	defer srv.Close()
+
+	s, err := NewWebhookSender(config.WebhookConfig{
+		URL:    srv.URL,
+		Method: "PUT",
+		Body:   `{"text": {{json .Message}}, "channel": "chores", "title": {{json .Title}}}`,
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewWebhookSender: %v", err)
+	}
+	if err := s.Send(context.Background(), "say \"hi\"\nnow", map[string]string{"X-Title": "p"}); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	want := `{"text": "say \"hi\"\nnow", "channel": "chores", "title": "p"}`
+	if gotBody != want || gotMethod != "PUT" {
+		t.Fatalf("unexpected request %s %q", gotMethod, gotBody)
+	}
+
+	if _, err := NewWebhookSender(config.WebhookConfig{URL: srv.URL, Body: "{{.Message"}, nil); err == nil {
+		t.Fatal("expected error for invalid body template")
+	}
+}
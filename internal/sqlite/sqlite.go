@@ -0,0 +1,365 @@
+// Package sqlite is a minimal, read-only reader for SQLite 3 database files.
+// It supports what task-herald needs to read a TaskChampion replica without
+// cgo or a driver: scanning the rows of ordinary (rowid) tables in UTF-8
+// databases. It does not evaluate SQL.
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+)
+
+const headerMagic = "SQLite format 3\x00"
+
+// B-tree page types
+const (
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+)
+
+// ErrBusy is returned by Open when the database is being written to and no
+// consistent snapshot could be read
+var ErrBusy = errors.New("sqlite: database is busy")
+
+// ErrWAL is returned by Open when the database has write-ahead log content
+// that was not checkpointed into the database file yet; WAL frames are not read
+var ErrWAL = errors.New("sqlite: uncheckpointed WAL content is not supported")
+
+// DB is an in-memory snapshot of a database file
+type DB struct {
+	data     []byte
+	pageSize int
+	usable   int              // page size minus reserved bytes
+	tables   map[string]int64 // table name -> root page
+}
+
+// Open reads a consistent snapshot of the database at path. A write that
+// happens while reading is detected through the file change counter and the
+// read is retried. Databases with uncheckpointed WAL content are rejected.
+func Open(path string) (*DB, error) {
+	if fi, err := os.Stat(path + "-wal"); err == nil && fi.Size() > 0 {
+		return nil, fmt.Errorf("%s: %w", path, ErrWAL)
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 20 * time.Millisecond)
+		}
+		// A non-empty rollback journal means a write transaction is in progress
+		if fi, err := os.Stat(path + "-journal"); err == nil && fi.Size() > 0 {
+			continue
+		}
+		before, err := readCounter(path)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		after, err := readCounter(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < 100 || before != after || binary.BigEndian.Uint32(data[24:28]) != before {
+			continue
+		}
+		return Parse(data)
+	}
+	return nil, ErrBusy
+}
+
+// readCounter returns the file change counter from the database header
+func readCounter(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var hdr [28]byte
+	if _, err := f.ReadAt(hdr[:], 0); err != nil {
+		return 0, fmt.Errorf("sqlite: reading header: %w", err)
+	}
+	return binary.BigEndian.Uint32(hdr[24:28]), nil
+}
+
+// Parse parses a database file image
+func Parse(data []byte) (*DB, error) {
+	if len(data) < 100 || string(data[:16]) != headerMagic {
+		return nil, errors.New("sqlite: not a SQLite 3 database")
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("sqlite: invalid page size %d", pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(data[56:60]); enc > 1 {
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d (only UTF-8)", enc)
+	}
+	db := &DB{
+		data:     data,
+		pageSize: pageSize,
+		usable:   pageSize - int(data[20]),
+		tables:   map[string]int64{},
+	}
+	// sqlite_schema(type, name, tbl_name, rootpage, sql) is rooted at page 1
+	err := db.scan(1, func(_ int64, row []interface{}) error {
+		if len(row) >= 4 && row[0] == "table" {
+			name, _ := row[1].(string)
+			root, _ := row[3].(int64)
+			db.tables[strings.ToLower(name)] = root
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: reading schema: %w", err)
+	}
+	return db, nil
+}
+
+// HasTable reports whether the database has a table with the given name
+func (db *DB) HasTable(name string) bool {
+	_, ok := db.tables[strings.ToLower(name)]
+	return ok
+}
+
+// Scan calls fn for every row of a table in rowid order. Values are nil,
+// int64, float64, string or []byte. A column declared INTEGER PRIMARY KEY
+// is stored as NULL; its value is the rowid.
+func (db *DB) Scan(table string, fn func(rowid int64, row []interface{}) error) error {
+	root, ok := db.tables[strings.ToLower(table)]
+	if !ok {
+		return fmt.Errorf("sqlite: no such table: %s", table)
+	}
+	return db.scan(root, fn)
+}
+
+// page returns the bytes of a 1-based page number
+func (db *DB) page(n int64) ([]byte, error) {
+	start := (n - 1) * int64(db.pageSize)
+	if n < 1 || start+int64(db.pageSize) > int64(len(db.data)) {
+		return nil, fmt.Errorf("page %d out of range", n)
+	}
+	return db.data[start : start+int64(db.pageSize)], nil
+}
+
+// scan walks the table b-tree rooted at page root
+func (db *DB) scan(root int64, fn func(rowid int64, row []interface{}) error) error {
+	return db.walk(root, 0, fn)
+}
+
+func (db *DB) walk(pgno int64, depth int, fn func(rowid int64, row []interface{}) error) error {
+	if depth > 64 {
+		return errors.New("b-tree too deep (corrupt database?)")
+	}
+	page, err := db.page(pgno)
+	if err != nil {
+		return err
+	}
+	hdr := 0
+	if pgno == 1 {
+		hdr = 100
+	}
+	if hdr+8 > len(page) {
+		return fmt.Errorf("page %d: truncated header", pgno)
+	}
+	kind := page[hdr]
+	ncells := int(binary.BigEndian.Uint16(page[hdr+3 : hdr+5]))
+	ptrs := hdr + 8
+	if kind == pageInteriorTable {
+		ptrs = hdr + 12
+	}
+	if ptrs+2*ncells > len(page) {
+		return fmt.Errorf("page %d: truncated cell pointers", pgno)
+	}
+	for i := 0; i < ncells; i++ {
+		off := int(binary.BigEndian.Uint16(page[ptrs+2*i:]))
+		if off >= len(page) {
+			return fmt.Errorf("page %d: cell offset out of range", pgno)
+		}
+		cell := page[off:]
+		switch kind {
+		case pageInteriorTable:
+			if len(cell) < 4 {
+				return fmt.Errorf("page %d: truncated cell", pgno)
+			}
+			if err := db.walk(int64(binary.BigEndian.Uint32(cell)), depth+1, fn); err != nil {
+				return err
+			}
+		case pageLeafTable:
+			rowid, payload, err := db.leafCell(cell)
+			if err != nil {
+				return fmt.Errorf("page %d: %w", pgno, err)
+			}
+			row, err := decodeRecord(payload)
+			if err != nil {
+				return fmt.Errorf("page %d row %d: %w", pgno, rowid, err)
+			}
+			if err := fn(rowid, row); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("page %d: not a table b-tree page (type %#x)", pgno, kind)
+		}
+	}
+	if kind == pageInteriorTable {
+		return db.walk(int64(binary.BigEndian.Uint32(page[hdr+8:])), depth+1, fn)
+	}
+	return nil
+}
+
+// leafCell decodes a table leaf cell, following overflow pages
+func (db *DB) leafCell(cell []byte) (int64, []byte, error) {
+	size, n := varint(cell)
+	if n == 0 {
+		return 0, nil, errors.New("bad payload size")
+	}
+	cell = cell[n:]
+	rowid, n := varint(cell)
+	if n == 0 {
+		return 0, nil, errors.New("bad rowid")
+	}
+	cell = cell[n:]
+	total := int(size)
+	local := db.localPayload(total)
+	if local > len(cell) {
+		return 0, nil, errors.New("truncated payload")
+	}
+	if local == total {
+		return int64(rowid), cell[:total], nil
+	}
+	payload := make([]byte, 0, total)
+	payload = append(payload, cell[:local]...)
+	if len(cell) < local+4 {
+		return 0, nil, errors.New("truncated overflow pointer")
+	}
+	next := int64(binary.BigEndian.Uint32(cell[local:]))
+	for len(payload) < total {
+		if next == 0 {
+			return 0, nil, errors.New("overflow chain too short")
+		}
+		page, err := db.page(next)
+		if err != nil {
+			return 0, nil, err
+		}
+		chunk := page[4:db.usable]
+		if rest := total - len(payload); len(chunk) > rest {
+			chunk = chunk[:rest]
+		}
+		payload = append(payload, chunk...)
+		next = int64(binary.BigEndian.Uint32(page))
+	}
+	return int64(rowid), payload, nil
+}
+
+// localPayload returns how many payload bytes of a table leaf cell are stored on the page
+func (db *DB) localPayload(total int) int {
+	u := db.usable
+	maxLocal := u - 35
+	if total <= maxLocal {
+		return total
+	}
+	minLocal := (u-12)*32/255 - 23
+	k := minLocal + (total-minLocal)%(u-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+// varint decodes a SQLite variable-length integer, returning the value and
+// the number of bytes read (0 if b is too short)
+func varint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
+
+// decodeRecord decodes a record into its column values
+func decodeRecord(rec []byte) ([]interface{}, error) {
+	hdrSize, n := varint(rec)
+	if n == 0 || int(hdrSize) > len(rec) || int(hdrSize) < n {
+		return nil, errors.New("bad record header")
+	}
+	header := rec[n:hdrSize]
+	body := rec[hdrSize:]
+	var row []interface{}
+	for len(header) > 0 {
+		st, n := varint(header)
+		if n == 0 {
+			return nil, errors.New("bad serial type")
+		}
+		header = header[n:]
+		size := serialSize(st)
+		if size > len(body) {
+			return nil, errors.New("truncated record")
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case st == 0:
+			row = append(row, nil)
+		case st >= 1 && st <= 6:
+			row = append(row, signed(v))
+		case st == 7:
+			row = append(row, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case st == 8:
+			row = append(row, int64(0))
+		case st == 9:
+			row = append(row, int64(1))
+		case st >= 12 && st%2 == 0:
+			row = append(row, bytes.Clone(v))
+		case st >= 13:
+			row = append(row, string(v))
+		default:
+			return nil, fmt.Errorf("reserved serial type %d", st)
+		}
+	}
+	return row, nil
+}
+
+// serialSize returns the body size in bytes of a serial type
+func serialSize(st uint64) int {
+	switch {
+	case st <= 4:
+		return int(st)
+	case st == 5:
+		return 6
+	case st == 6 || st == 7:
+		return 8
+	case st < 12:
+		return 0
+	default:
+		return int((st - 12) / 2)
+	}
+}
+
+// signed decodes a big-endian two's complement integer of 1-8 bytes
+func signed(b []byte) int64 {
+	var v int64
+	if b[0]&0x80 != 0 {
+		v = -1
+	}
+	for _, c := range b {
+		v = v<<8 | int64(c)
+	}
+	return v
+}
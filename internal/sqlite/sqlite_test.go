@@ -0,0 +1,160 @@
+package sqlite
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openFixture(t *testing.T) *DB {
+	t.Helper()
+	db, err := Open(filepath.Join("testdata", "types.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return db
+}
+
+func TestScan_Types(t *testing.T) {
+	db := openFixture(t)
+	rows := map[int64][]interface{}{}
+	var order []int64
+	err := db.Scan("t", func(rowid int64, row []interface{}) error {
+		rows[rowid] = row
+		order = append(order, rowid)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(rows) != 8+391 {
+		t.Fatalf("expected 399 rows, got %d", len(rows))
+	}
+	for i := 1; i < len(order); i++ {
+		if order[i] <= order[i-1] {
+			t.Fatalf("rows not in rowid order at %d", i)
+		}
+	}
+	// the INTEGER PRIMARY KEY column is stored as NULL
+	if rows[1][0] != nil {
+		t.Errorf("expected NULL id column, got %v", rows[1][0])
+	}
+	checks := []struct {
+		rowid int64
+		col   int
+		want  interface{}
+	}{
+		{1, 1, int64(0)}, {1, 2, 1.5}, {1, 3, "hello"},
+		{2, 1, int64(-1)}, {2, 2, nil}, {2, 3, ""},
+		{3, 1, int64(1)}, {3, 2, -2.25}, {3, 3, "héllo ✓"},
+		{4, 1, int64(200)}, {5, 1, int64(-70000)}, {6, 1, int64(1<<31 - 1)},
+		{7, 1, int64(1 << 40)}, {8, 1, int64(-1 << 62)},
+		{400, 1, int64(400)}, {400, 3, "row 400"},
+	}
+	for _, c := range checks {
+		if got := rows[c.rowid][c.col]; got != c.want {
+			t.Errorf("row %d col %d: got %#v, want %#v", c.rowid, c.col, got, c.want)
+		}
+	}
+	if b, _ := rows[1][4].([]byte); !bytes.Equal(b, []byte{0x00, 0xff}) {
+		t.Errorf("unexpected blob: %#v", rows[1][4])
+	}
+	if s, _ := rows[4][3].(string); len(s) != 3003 || !strings.HasSuffix(s, "end") {
+		t.Errorf("overflow text not reassembled: len %d", len(s))
+	}
+}
+
+func TestScan_EmptyAndMissingTable(t *testing.T) {
+	db := openFixture(t)
+	n := 0
+	if err := db.Scan("empty", func(int64, []interface{}) error { n++; return nil }); err != nil || n != 0 {
+		t.Fatalf("expected no rows, got %d (%v)", n, err)
+	}
+	if !db.HasTable("T") || db.HasTable("t_s") {
+		t.Error("HasTable should only report tables, case-insensitively")
+	}
+	if err := db.Scan("missing", func(int64, []interface{}) error { return nil }); err == nil {
+		t.Error("expected error for a missing table")
+	}
+}
+
+func TestScan_StopsOnCallbackError(t *testing.T) {
+	db := openFixture(t)
+	stop := fmt.Errorf("stop")
+	n := 0
+	err := db.Scan("t", func(int64, []interface{}) error {
+		n++
+		if n == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || n != 3 {
+		t.Fatalf("expected scan to stop after 3 rows, got %d (%v)", n, err)
+	}
+}
+
+func TestOpen_Errors(t *testing.T) {
+	dir := t.TempDir()
+	notDB := filepath.Join(dir, "x.db")
+	os.WriteFile(notDB, bytes.Repeat([]byte("x"), 200), 0o644)
+	if _, err := Open(notDB); err == nil {
+		t.Error("expected error for a non-database file")
+	}
+	if _, err := Open(filepath.Join(dir, "missing.db")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+	data, _ := os.ReadFile(filepath.Join("testdata", "types.db"))
+	walDB := filepath.Join(dir, "wal.db")
+	os.WriteFile(walDB, data, 0o644)
+	os.WriteFile(walDB+"-wal", []byte("pending frames"), 0o644)
+	if _, err := Open(walDB); !errors.Is(err, ErrWAL) {
+		t.Errorf("expected ErrWAL for uncheckpointed WAL content, got %v", err)
+	}
+}
+
+func TestOpen_BusyJournal(t *testing.T) {
+	dir := t.TempDir()
+	data, _ := os.ReadFile(filepath.Join("testdata", "types.db"))
+	path := filepath.Join(dir, "busy.db")
+	os.WriteFile(path, data, 0o644)
+	os.WriteFile(path+"-journal", []byte("hot"), 0o644)
+	if _, err := Open(path); err != ErrBusy {
+		t.Fatalf("expected ErrBusy while a journal exists, got %v", err)
+	}
+}
+
+func TestParse_Corrupt(t *testing.T) {
+	data, _ := os.ReadFile(filepath.Join("testdata", "types.db"))
+	// truncate to the first page: the schema points at pages that don't exist
+	db, err := Parse(data[:512])
+	if err != nil {
+		return
+	}
+	if err := db.Scan("t", func(int64, []interface{}) error { return nil }); err == nil {
+		t.Fatal("expected error scanning a truncated database")
+	}
+}
+
+func TestVarint(t *testing.T) {
+	cases := []struct {
+		in   []byte
+		want uint64
+		n    int
+	}{
+		{[]byte{0x00}, 0, 1},
+		{[]byte{0x7f}, 127, 1},
+		{[]byte{0x81, 0x00}, 128, 2},
+		{[]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, ^uint64(0), 9},
+		{[]byte{0x81}, 0, 0},
+	}
+	for _, c := range cases {
+		if got, n := varint(c.in); got != c.want || n != c.n {
+			t.Errorf("varint(%x) = %d, %d; want %d, %d", c.in, got, n, c.want, c.n)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+# Regenerates types.db, the fixture for the sqlite package tests:
+#   cd internal/sqlite/testdata && rm -f types.db && python3 gen_types.py
+# A small page size forces interior pages and overflow chains.
+import sqlite3
+
+c = sqlite3.connect('types.db')
+c.execute('PRAGMA page_size=512')
+c.execute('CREATE TABLE t (id INTEGER PRIMARY KEY, i INTEGER, f REAL, s TEXT, b BLOB)')
+c.execute('CREATE TABLE empty (x TEXT)')
+c.execute('CREATE INDEX t_s ON t(s)')
+rows = [
+    (1, 0, 1.5, 'hello', b'\x00\xff'),
+    (2, -1, None, '', None),
+    (3, 1, -2.25, 'héllo ✓', b''),
+    (4, 200, None, 'x' * 3000 + 'end', None),
+    (5, -70000, None, None, None),
+    (6, 2**31 - 1, None, None, None),
+    (7, 2**40, None, None, None),
+    (8, -2**62, None, None, None),
+]
+c.executemany('INSERT INTO t VALUES (?,?,?,?,?)', rows)
+for k in range(10, 401):
+    c.execute('INSERT INTO t VALUES (?,?,?,?,?)', (k, k, None, 'row %d' % k, None))
+c.commit()
+c.close()
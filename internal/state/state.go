@@ -0,0 +1,242 @@
+package state
+
+import (
+	"strings"
+	"time"
+)
+
+// Notification records a notification sent for a task
+type Notification struct {
+	UUID           string    `json:"uuid"`
+	SentAt         time.Time `json:"sent_at"`
+	FirstSentAt    time.Time `json:"first_sent_at,omitempty"`
+	Count          int       `json:"count"`
+	Escalations    int       `json:"escalations,omitempty"` // escalation steps sent
+	AcknowledgedAt time.Time `json:"acknowledged_at,omitempty"`
+	DroppedAt      time.Time `json:"dropped_at,omitempty"` // missed and deliberately not sent
+	// Deferred is set while the notification is held back by quiet hours or
+	// do-not-disturb; it is sent at DeferredUntil, or when do-not-disturb is
+	// turned off if DeferredUntil is zero
+	Deferred      bool      `json:"deferred,omitempty"`
+	DeferredUntil time.Time `json:"deferred_until,omitempty"`
+}
+
+// Snooze records a task snoozed via the API
+type Snooze struct {
+	Until     time.Time `json:"until"`
+	SnoozedAt time.Time `json:"snoozed_at"`
+}
+
+// DND records that do-not-disturb was turned on via the API
+type DND struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until,omitempty"` // zero until turned off
+}
+
+// State is the notification state persisted across restarts. It is not safe
+// for concurrent use; callers serialize access.
+type State struct {
+	// Notifications is keyed by UUID|notification_date
+	Notifications map[string]*Notification `json:"notifications"`
+	// Snoozes is keyed by task UUID
+	Snoozes map[string]Snooze `json:"snoozes"`
+	// DND is set while do-not-disturb is on
+	DND *DND `json:"dnd,omitempty"`
+}
+
+// New returns an empty State
+func New() *State {
+	return &State{
+		Notifications: make(map[string]*Notification),
+		Snoozes:       make(map[string]Snooze),
+	}
+}
+
+// ensure initializes nil maps, e.g. after decoding an older state file
+func (s *State) ensure() {
+	if s.Notifications == nil {
+		s.Notifications = make(map[string]*Notification)
+	}
+	if s.Snoozes == nil {
+		s.Snoozes = make(map[string]Snooze)
+	}
+}
+
+// LastSent returns when the notification with the given key was last sent
+func (s *State) LastSent(key string) (time.Time, bool) {
+	n, ok := s.Notifications[key]
+	if !ok || n.SentAt.IsZero() {
+		return time.Time{}, false
+	}
+	return n.SentAt, true
+}
+
+// MarkSent records that the notification with the given key was sent at t
+func (s *State) MarkSent(key, uuid string, t time.Time) {
+	n, ok := s.Notifications[key]
+	if !ok {
+		n = &Notification{UUID: uuid}
+		s.Notifications[key] = n
+	}
+	if n.FirstSentAt.IsZero() {
+		n.FirstSentAt = t
+	}
+	n.SentAt = t
+	n.Count++
+	n.Deferred = false
+	n.DeferredUntil = time.Time{}
+}
+
+// FirstSent returns when the notification with the given key was first sent
+func (s *State) FirstSent(key string) (time.Time, bool) {
+	n, ok := s.Notifications[key]
+	switch {
+	case !ok || n.SentAt.IsZero():
+		return time.Time{}, false
+	case n.FirstSentAt.IsZero():
+		// State saved before first sends were recorded
+		return n.SentAt, true
+	}
+	return n.FirstSentAt, true
+}
+
+// Escalations returns how many escalation steps of the notification were sent
+func (s *State) Escalations(key string) int {
+	if n, ok := s.Notifications[key]; ok {
+		return n.Escalations
+	}
+	return 0
+}
+
+// MarkEscalated records that escalation steps up to level were sent at t
+func (s *State) MarkEscalated(key, uuid string, level int, t time.Time) {
+	s.MarkSent(key, uuid, t)
+	s.Notifications[key].Escalations = level
+}
+
+// MarkDropped records that a missed notification was deliberately not sent
+func (s *State) MarkDropped(key, uuid string, t time.Time) {
+	n, ok := s.Notifications[key]
+	if !ok {
+		n = &Notification{UUID: uuid}
+		s.Notifications[key] = n
+	}
+	n.DroppedAt = t
+	n.Deferred = false
+	n.DeferredUntil = time.Time{}
+}
+
+// Handled reports whether the notification was sent or dropped
+func (s *State) Handled(key string) bool {
+	n, ok := s.Notifications[key]
+	return ok && (!n.SentAt.IsZero() || !n.DroppedAt.IsZero())
+}
+
+// Acknowledge marks the notification with the given key as acknowledged
+func (s *State) Acknowledge(key, uuid string, t time.Time) {
+	n, ok := s.Notifications[key]
+	if !ok {
+		n = &Notification{UUID: uuid}
+		s.Notifications[key] = n
+	}
+	if n.AcknowledgedAt.IsZero() {
+		n.AcknowledgedAt = t
+	}
+}
+
+// Acknowledged reports whether the notification with the given key was acknowledged
+func (s *State) Acknowledged(key string) bool {
+	n, ok := s.Notifications[key]
+	return ok && !n.AcknowledgedAt.IsZero()
+}
+
+// Defer records that the notification with the given key is held back
+// until t; a zero t holds it until ReleaseDeferrals
+func (s *State) Defer(key, uuid string, until time.Time) {
+	n, ok := s.Notifications[key]
+	if !ok {
+		n = &Notification{UUID: uuid}
+		s.Notifications[key] = n
+	}
+	n.Deferred = true
+	n.DeferredUntil = until
+}
+
+// Deferred returns until when the notification with the given key is held
+// back. A zero time means until ReleaseDeferrals.
+func (s *State) Deferred(key string) (time.Time, bool) {
+	n, ok := s.Notifications[key]
+	if !ok || !n.Deferred {
+		return time.Time{}, false
+	}
+	return n.DeferredUntil, true
+}
+
+// ReleaseDeferrals ends all deferrals at t at the latest, e.g. when
+// do-not-disturb is turned off, and returns the number of changed entries
+func (s *State) ReleaseDeferrals(t time.Time) int {
+	changed := 0
+	for _, n := range s.Notifications {
+		if n.Deferred && (n.DeferredUntil.IsZero() || n.DeferredUntil.After(t)) {
+			n.DeferredUntil = t
+			changed++
+		}
+	}
+	return changed
+}
+
+// Keys returns the notification keys recorded for a task
+func (s *State) Keys(uuid string) []string {
+	var keys []string
+	for k, n := range s.Notifications {
+		if n.UUID == uuid || strings.HasPrefix(k, uuid+"|") {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Snooze records that a task was snoozed until the given time
+func (s *State) Snooze(uuid string, until, at time.Time) {
+	s.Snoozes[uuid] = Snooze{Until: until, SnoozedAt: at}
+}
+
+// Snoozed returns until when a task was snoozed
+func (s *State) Snoozed(uuid string) (time.Time, bool) {
+	sn, ok := s.Snoozes[uuid]
+	return sn.Until, ok
+}
+
+// Forget removes the entries of a single task, e.g. one completed or deleted
+// via the hook, and returns the number of removed entries
+func (s *State) Forget(uuid string) int {
+	removed := 0
+	for _, k := range s.Keys(uuid) {
+		delete(s.Notifications, k)
+		removed++
+	}
+	if _, ok := s.Snoozes[uuid]; ok {
+		delete(s.Snoozes, uuid)
+		removed++
+	}
+	return removed
+}
+
+// Prune removes entries of tasks not in live (e.g. completed or deleted
+// tasks) and returns the number of removed entries
+func (s *State) Prune(live map[string]struct{}) int {
+	removed := 0
+	for k, n := range s.Notifications {
+		if _, ok := live[n.UUID]; !ok {
+			delete(s.Notifications, k)
+			removed++
+		}
+	}
+	for uuid := range s.Snoozes {
+		if _, ok := live[uuid]; !ok {
+			delete(s.Snoozes, uuid)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,144 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestState_SentAndAcknowledged(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 8, 31, 14, 30, 0, 0, time.UTC)
+
+	if _, ok := s.LastSent("u1|d1"); ok {
+		t.Fatal("expected no send recorded")
+	}
+	s.MarkSent("u1|d1", "u1", now)
+	s.MarkSent("u1|d1", "u1", now.Add(time.Minute))
+	got, ok := s.LastSent("u1|d1")
+	if !ok || !got.Equal(now.Add(time.Minute)) {
+		t.Fatalf("unexpected last sent: %v %v", got, ok)
+	}
+	if s.Notifications["u1|d1"].Count != 2 {
+		t.Fatalf("expected count 2, got %d", s.Notifications["u1|d1"].Count)
+	}
+
+	if s.Acknowledged("u1|d1") {
+		t.Fatal("expected not acknowledged")
+	}
+	s.Acknowledge("u1|d1", "u1", now)
+	s.Acknowledge("u1|d1", "u1", now.Add(time.Hour))
+	if !s.Acknowledged("u1|d1") || !s.Notifications["u1|d1"].AcknowledgedAt.Equal(now) {
+		t.Fatalf("expected first acknowledgement to be kept: %+v", s.Notifications["u1|d1"])
+	}
+
+	// acknowledging before sending records the entry without a send
+	s.Acknowledge("u2|d2", "u2", now)
+	if _, ok := s.LastSent("u2|d2"); ok {
+		t.Fatal("acknowledgement must not count as a send")
+	}
+	if keys := s.Keys("u2"); len(keys) != 1 || keys[0] != "u2|d2" {
+		t.Fatalf("unexpected keys: %v", keys)
+	}
+}
+
+func TestState_Forget(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.MarkSent("u1|d1", "u1", now)
+	s.MarkSent("u1|d2", "u1", now)
+	s.MarkSent("u2|d2", "u2", now)
+	s.Snooze("u1", now.Add(time.Hour), now)
+	s.Snooze("u2", now.Add(time.Hour), now)
+
+	if removed := s.Forget("u1"); removed != 3 {
+		t.Fatalf("expected 3 removed entries, got %d", removed)
+	}
+	if len(s.Keys("u1")) != 0 || len(s.Notifications) != 1 || len(s.Snoozes) != 1 {
+		t.Fatalf("unexpected state after forget: %+v", s)
+	}
+}
+
+func TestState_Prune(t *testing.T) {
+	s := New()
+	now := time.Now()
+	s.MarkSent("u1|d1", "u1", now)
+	s.MarkSent("u2|d2", "u2", now)
+	s.Snooze("u2", now.Add(time.Hour), now)
+	s.Snooze("u3", now.Add(time.Hour), now)
+
+	removed := s.Prune(map[string]struct{}{"u1": {}})
+	if removed != 3 {
+		t.Fatalf("expected 3 removed entries, got %d", removed)
+	}
+	if _, ok := s.Notifications["u1|d1"]; !ok {
+		t.Fatal("live task entry was pruned")
+	}
+	if len(s.Notifications) != 1 || len(s.Snoozes) != 0 {
+		t.Fatalf("unexpected state after prune: %+v", s)
+	}
+}
+
+func TestState_Dropped(t *testing.T) {
+	s := New()
+	if s.Handled("u1|d1") {
+		t.Fatal("expected unknown notification not to be handled")
+	}
+	s.MarkDropped("u1|d1", "u1", time.Now())
+	if !s.Handled("u1|d1") {
+		t.Fatal("expected dropped notification to be handled")
+	}
+	if _, ok := s.LastSent("u1|d1"); ok {
+		t.Fatal("dropped notification must not count as sent")
+	}
+}
+
+func TestState_Deferrals(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 9, 3, 23, 0, 0, 0, time.UTC)
+	morning := now.Add(8 * time.Hour)
+
+	s.Defer("u1|d1", "u1", morning)
+	s.Defer("u2|d2", "u2", time.Time{})
+	if until, ok := s.Deferred("u1|d1"); !ok || !until.Equal(morning) {
+		t.Fatalf("unexpected deferral: %v %v", until, ok)
+	}
+	if until, ok := s.Deferred("u2|d2"); !ok || !until.IsZero() {
+		t.Fatalf("expected an open-ended deferral, got %v %v", until, ok)
+	}
+
+	if n := s.ReleaseDeferrals(now); n != 2 {
+		t.Fatalf("expected both deferrals released, got %d", n)
+	}
+	if until, _ := s.Deferred("u2|d2"); !until.Equal(now) {
+		t.Fatalf("expected deferral to end at release, got %v", until)
+	}
+
+	s.MarkSent("u1|d1", "u1", now)
+	if _, ok := s.Deferred("u1|d1"); ok {
+		t.Fatal("expected sending to clear the deferral")
+	}
+}
+
+func TestState_Escalations(t *testing.T) {
+	s := New()
+	now := time.Date(2025, 9, 3, 10, 0, 0, 0, time.UTC)
+	if _, ok := s.FirstSent("u1|d1"); ok {
+		t.Fatal("expected no first send")
+	}
+	s.MarkSent("u1|d1", "u1", now)
+	s.MarkEscalated("u1|d1", "u1", 2, now.Add(30*time.Minute))
+	if first, ok := s.FirstSent("u1|d1"); !ok || !first.Equal(now) {
+		t.Fatalf("expected the first send to be kept, got %v %v", first, ok)
+	}
+	if last, _ := s.LastSent("u1|d1"); !last.Equal(now.Add(30 * time.Minute)) {
+		t.Fatalf("expected the escalation to count as a send, got %v", last)
+	}
+	if n := s.Escalations("u1|d1"); n != 2 {
+		t.Fatalf("expected 2 escalation steps, got %d", n)
+	}
+	// State files written before first sends were recorded
+	s.Notifications["u2|d2"] = &Notification{UUID: "u2", SentAt: now}
+	if first, ok := s.FirstSent("u2|d2"); !ok || !first.Equal(now) {
+		t.Fatalf("expected the last send as fallback, got %v %v", first, ok)
+	}
+}
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store loads and saves the notification State
+type Store interface {
+	Load() (*State, error)
+	Save(*State) error
+}
+
+// StateFileName is the file FileStore keeps under its directory
+const StateFileName = "state.json"
+
+// Open returns the Store for a backend name. Supported backends are "file"
+// (a JSON file under dir), the default, and "memory", which forgets the
+// state on restart and must be chosen explicitly.
+func Open(backend, dir string) (Store, error) {
+	if backend == "" {
+		backend = "file"
+	}
+	switch backend {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		if dir == "" {
+			return nil, errors.New("file state store requires a state dir")
+		}
+		return NewFileStore(dir)
+	default:
+		return nil, fmt.Errorf("unknown state backend %q", backend)
+	}
+}
+
+// FileStore persists State as JSON. Saves are atomic (write + rename).
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStore creates dir if needed and returns a store for dir/state.json
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{path: filepath.Join(dir, StateFileName)}, nil
+}
+
+// Path returns the state file path
+func (f *FileStore) Path() string {
+	return f.path
+}
+
+// Load reads the state file, returning an empty State if it does not exist
+func (f *FileStore) Load() (*State, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := New()
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", f.path, err)
+	}
+	s.ensure()
+	return s, nil
+}
+
+// Save writes the state to a temporary file and renames it over the state file
+func (f *FileStore) Save(s *State) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), StateFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// MemoryStore keeps the state in memory only; it is lost on restart
+type MemoryStore struct {
+	mu    sync.Mutex
+	saved []byte
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Load returns a copy of the last saved state
+func (m *MemoryStore) Load() (*State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := New()
+	if m.saved == nil {
+		return s, nil
+	}
+	if err := json.Unmarshal(m.saved, s); err != nil {
+		return nil, err
+	}
+	s.ensure()
+	return s, nil
+}
+
+// Save keeps a copy of the state
+func (m *MemoryStore) Save(s *State) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved = b
+	return nil
+}
@@ -0,0 +1,101 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileStore_RoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "nested")
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+
+	// missing file loads as empty state
+	s, err := fs.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(s.Notifications) != 0 {
+		t.Fatalf("expected empty state, got %+v", s)
+	}
+
+	now := time.Date(2025, 8, 31, 14, 30, 0, 0, time.UTC)
+	s.MarkSent("u1|d1", "u1", now)
+	s.Acknowledge("u1|d1", "u1", now.Add(time.Minute))
+	s.Snooze("u1", now.Add(time.Hour), now)
+	if err := fs.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// a new store on the same dir sees the saved state
+	fs2, _ := NewFileStore(dir)
+	got, err := fs2.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	sent, ok := got.LastSent("u1|d1")
+	if !ok || !sent.Equal(now) || !got.Acknowledged("u1|d1") {
+		t.Fatalf("unexpected loaded notification: %+v", got.Notifications["u1|d1"])
+	}
+	if !got.Snoozes["u1"].Until.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected loaded snooze: %+v", got.Snoozes)
+	}
+
+	// no temporary files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != StateFileName {
+		t.Fatalf("unexpected files in state dir: %v", entries)
+	}
+}
+
+func TestFileStore_CorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, StateFileName), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fs, _ := NewFileStore(dir)
+	if _, err := fs.Load(); err == nil {
+		t.Fatal("expected error for corrupt state file")
+	}
+}
+
+func TestMemoryStore_RoundTrip(t *testing.T) {
+	m := NewMemoryStore()
+	s, _ := m.Load()
+	s.MarkSent("u1|d1", "u1", time.Now())
+	if err := m.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// mutations after saving are not visible
+	s.MarkSent("u2|d2", "u2", time.Now())
+	got, _ := m.Load()
+	if len(got.Notifications) != 1 {
+		t.Fatalf("expected 1 saved notification, got %d", len(got.Notifications))
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if st, err := Open("memory", ""); err != nil {
+		t.Fatalf("Open: %v", err)
+	} else if _, ok := st.(*MemoryStore); !ok {
+		t.Fatalf("expected memory store, got %T", st)
+	}
+	if _, err := Open("", ""); err == nil {
+		t.Fatal("expected error for the default file store without dir")
+	}
+	if st, err := Open("", t.TempDir()); err != nil {
+		t.Fatalf("Open: %v", err)
+	} else if _, ok := st.(*FileStore); !ok {
+		t.Fatalf("expected file store with dir, got %T", st)
+	}
+	if _, err := Open("file", ""); err == nil {
+		t.Fatal("expected error for file store without dir")
+	}
+	if _, err := Open("bolt", ""); err == nil {
+		t.Fatal("expected error for unknown backend")
+	}
+}
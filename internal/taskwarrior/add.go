@@ -0,0 +1,113 @@
+package taskwarrior
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NewTask describes a task to be created with AddTask.
+type NewTask struct {
+	Description string
+	Project     string
+	Tags        []string
+	Annotations []string
+	// UDAs are written as name:value modifications (e.g. the configured
+	// notification date UDA). Empty values are skipped.
+	UDAs map[string]string
+}
+
+// ErrAnnotate is returned by AddTask, along with the UUID, when the task was
+// created but not all of its annotations could be added
+var ErrAnnotate = errors.New("task annotate failed")
+
+// createdUUIDPattern matches the "Created task <uuid>." line printed by
+// Taskwarrior when the new-uuid verbosity token is enabled.
+var createdUUIDPattern = regexp.MustCompile(`Created task ([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})`)
+
+// AddTask runs 'task add' for the given task, annotates it and returns the
+// UUID of the created task. If annotations fail the UUID is returned with an
+// ErrAnnotate error, since the task exists.
+func AddTask(t NewTask) (string, error) {
+	desc := strings.TrimSpace(t.Description)
+	if desc == "" {
+		return "", fmt.Errorf("description is required")
+	}
+	cmdArgs := []string{"rc.verbose=new-uuid", "add"}
+	if t.Project != "" {
+		cmdArgs = append(cmdArgs, "project:"+t.Project)
+	}
+	for _, tag := range t.Tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "+")
+		if tag == "" {
+			continue
+		}
+		cmdArgs = append(cmdArgs, "+"+tag)
+	}
+	names := make([]string, 0, len(t.UDAs))
+	for name := range t.UDAs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == "" || t.UDAs[name] == "" {
+			continue
+		}
+		cmdArgs = append(cmdArgs, name+":"+t.UDAs[name])
+	}
+	// Everything after -- is taken verbatim as the description
+	cmdArgs = append(cmdArgs, "--", desc)
+	cmd := taskCommand(cmdArgs...)
+	cmdStr := "task " + strings.Join(cmdArgs, " ")
+	taskLog.Debug("Running task add", "command", cmdStr)
+	output, err := cmd.CombinedOutput()
+	taskLog.Debug("task add output", "output", string(output))
+	if err != nil {
+		taskLog.Error("task add failed", "command", cmdStr, "error", err, "output", string(output))
+		return "", fmt.Errorf("task add failed: %w", err)
+	}
+	uuid, err := parseCreatedUUID(string(output))
+	if err != nil {
+		taskLog.Error("task add: no created task", "error", err, "output", string(output))
+		return "", err
+	}
+	annotations, failed := 0, 0
+	for _, ann := range t.Annotations {
+		if strings.TrimSpace(ann) == "" {
+			continue
+		}
+		annotations++
+		if err := AnnotateTask(uuid, ann); err != nil {
+			failed++
+		}
+	}
+	if failed > 0 {
+		taskLog.Warn("Added task without all annotations", "task_uuid", uuid, "failed", failed)
+		return uuid, fmt.Errorf("%w: %d of %d annotations not added", ErrAnnotate, failed, annotations)
+	}
+	taskLog.Info("Added task", "task_uuid", uuid)
+	return uuid, nil
+}
+
+// AnnotateTask adds an annotation to the task with the given UUID.
+func AnnotateTask(uuid, annotation string) error {
+	cmdArgs := []string{uuid, "annotate", "--", annotation}
+	cmd := taskCommand(cmdArgs...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		taskLog.Error("task annotate failed", "task_uuid", uuid, "error", err, "output", string(output))
+		return fmt.Errorf("task annotate failed: %w", err)
+	}
+	return nil
+}
+
+// parseCreatedUUID extracts the UUID from 'task add' output
+func parseCreatedUUID(output string) (string, error) {
+	m := createdUUIDPattern.FindStringSubmatch(output)
+	if m == nil {
+		return "", fmt.Errorf("could not find created task UUID in output")
+	}
+	return strings.ToLower(m[1]), nil
+}
@@ -0,0 +1,107 @@
+package taskwarrior
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestAddTask_EmptyDescription(t *testing.T) {
+	if _, err := AddTask(NewTask{Description: "  "}); err == nil {
+		t.Error("expected error for empty description")
+	}
+}
+
+func TestAddTask_Success(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+
+	var calls [][]string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls = append(calls, args)
+		if len(calls) == 1 {
+			return exec.Command("echo", "Created task 8A1B2C3D-1111-2222-3333-444455556666.")
+		}
+		return exec.Command("echo", "Annotating task")
+	}
+
+	uuid, err := AddTask(NewTask{
+		Description: "project:fake is part of the description",
+		Project:     "home",
+		Tags:        []string{"+errand", "shop"},
+		Annotations: []string{"first", "second"},
+		UDAs:        map[string]string{"notification_date": "2025-08-31T14:30:00", "empty": ""},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if uuid != "8a1b2c3d-1111-2222-3333-444455556666" {
+		t.Fatalf("unexpected uuid: %q", uuid)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("expected add + 2 annotate calls, got %d", len(calls))
+	}
+	add := strings.Join(calls[0], " ")
+	want := "rc.confirmation=off rc.verbose=new-uuid add project:home +errand +shop notification_date:2025-08-31T14:30:00 -- project:fake is part of the description"
+	if add != want {
+		t.Fatalf("unexpected add args:\n got: %s\nwant: %s", add, want)
+	}
+	if strings.Join(calls[1], " ") != "rc.confirmation=off "+uuid+" annotate -- first" {
+		t.Fatalf("unexpected annotate args: %v", calls[1])
+	}
+}
+
+func TestAddTask_NoUUIDInOutput(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("echo", "Created task 12.")
+	}
+
+	if _, err := AddTask(NewTask{Description: "x"}); err == nil {
+		t.Error("expected error when output has no UUID")
+	}
+}
+
+func TestAddTask_CommandFailure(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+
+	if _, err := AddTask(NewTask{Description: "x"}); err == nil {
+		t.Error("expected error on command failure")
+	}
+}
+
+func TestAddTask_AnnotationFailure(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+
+	calls := 0
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		calls++
+		switch calls {
+		case 1:
+			return exec.Command("echo", "Created task 8a1b2c3d-1111-2222-3333-444455556666.")
+		case 2:
+			return exec.Command("false")
+		}
+		return exec.Command("echo", "Annotating task")
+	}
+
+	uuid, err := AddTask(NewTask{Description: "x", Annotations: []string{"first", "second"}})
+	if uuid != "8a1b2c3d-1111-2222-3333-444455556666" {
+		t.Fatalf("expected the created uuid despite the failure, got %q", uuid)
+	}
+	if !errors.Is(err, ErrAnnotate) || !strings.Contains(err.Error(), "1 of 2") {
+		t.Fatalf("expected ErrAnnotate for 1 of 2 annotations, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected the remaining annotation to be added, got %d calls", calls)
+	}
+}
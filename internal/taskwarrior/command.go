@@ -0,0 +1,58 @@
+package taskwarrior
+
+import (
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"task-herald/internal/config"
+)
+
+// taskCommand returns a task command with the configured rc overrides in
+// front of args. Confirmation prompts are always turned off, since the
+// daemon has no terminal to answer them.
+func taskCommand(args ...string) *exec.Cmd {
+	var tw config.TaskwarriorConfig
+	if cfg := config.Get(); cfg != nil {
+		tw = cfg.Taskwarrior
+	}
+	cmd := execCommand("task", append(rcOverrides(tw), args...)...)
+	if tw.TaskRC != "" {
+		cmd.Env = append(os.Environ(), "TASKRC="+tw.TaskRC)
+	}
+	return cmd
+}
+
+// rcOverrides returns the rc.<name>=<value> arguments for tw
+func rcOverrides(tw config.TaskwarriorConfig) []string {
+	args := []string{"rc.confirmation=off"}
+	if tw.DataLocation != "" {
+		args = append(args, "rc.data.location="+tw.DataLocation)
+	}
+	overrides := make([]string, 0, len(tw.RC))
+	for name, value := range tw.RC {
+		overrides = append(overrides, "rc."+strings.TrimPrefix(name, "rc.")+"="+value)
+	}
+	sort.Strings(overrides)
+	return append(args, overrides...)
+}
+
+// DataLocation returns the Taskwarrior data directory the task command uses:
+// the configured data_location, else what Taskwarrior reports, else
+// $TASKDATA or ~/.task
+func DataLocation() string {
+	if cfg := config.Get(); cfg != nil && cfg.Taskwarrior.DataLocation != "" {
+		return cfg.Taskwarrior.DataLocation
+	}
+	cmd := taskCommand("_get", "rc.data.location")
+	if out, err := cmd.Output(); err == nil {
+		if loc := strings.TrimSpace(string(out)); loc != "" {
+			return loc
+		}
+	}
+	if dir := os.Getenv("TASKDATA"); dir != "" {
+		return dir
+	}
+	return "~/.task"
+}
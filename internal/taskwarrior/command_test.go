@@ -0,0 +1,43 @@
+package taskwarrior
+
+import (
+	"os/exec"
+	"task-herald/internal/config"
+	"testing"
+)
+
+func TestDataLocation(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	defer config.Set(&config.Config{})
+
+	config.Set(&config.Config{Taskwarrior: config.TaskwarriorConfig{DataLocation: "/srv/task"}})
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatal("task should not run when data_location is configured")
+		return nil
+	}
+	if got := DataLocation(); got != "/srv/task" {
+		t.Errorf("configured location: got %q", got)
+	}
+
+	config.Set(&config.Config{})
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.Command("echo", "/home/alice/.local/share/task")
+	}
+	if got := DataLocation(); got != "/home/alice/.local/share/task" {
+		t.Errorf("reported location: got %q", got)
+	}
+	if len(gotArgs) != 3 || gotArgs[1] != "_get" || gotArgs[2] != "rc.data.location" {
+		t.Errorf("unexpected args: %v", gotArgs)
+	}
+
+	t.Setenv("TASKDATA", "/tmp/taskdata")
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+	if got := DataLocation(); got != "/tmp/taskdata" {
+		t.Errorf("TASKDATA fallback: got %q", got)
+	}
+}
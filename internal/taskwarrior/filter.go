@@ -0,0 +1,359 @@
+package taskwarrior
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"task-herald/internal/util"
+)
+
+// Filter is a compiled Taskwarrior-style filter expression such as
+//
+//	project:work +urgent -someday priority.not:L or (due.before:2025-09-01 and status:pending)
+//
+// Supported terms are +tag, -tag, attribute:value with the Taskwarrior
+// modifiers (is, isnt/not, has/contains, hasnt, startswith/left,
+// endswith/right, any, none, before/below, after/above), bare words (matched
+// against the description), and/or/not (or !), and parentheses. Adjacent
+// terms are joined with and. project:value also matches subprojects, as in
+// Taskwarrior.
+type Filter struct {
+	src  string
+	root filterNode
+}
+
+type filterNode interface {
+	match(t *Task) bool
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ inner filterNode }
+type matchAll struct{}
+
+func (n andNode) match(t *Task) bool { return n.left.match(t) && n.right.match(t) }
+func (n orNode) match(t *Task) bool  { return n.left.match(t) || n.right.match(t) }
+func (n notNode) match(t *Task) bool { return !n.inner.match(t) }
+func (matchAll) match(*Task) bool    { return true }
+
+// tagTerm matches +tag / -tag
+type tagTerm struct {
+	tag     string
+	present bool
+}
+
+func (n tagTerm) match(t *Task) bool {
+	return t.HasTag(n.tag) == n.present
+}
+
+// attrTerm matches attribute[.modifier]:value
+type attrTerm struct {
+	attr     string
+	modifier string
+	value    string
+}
+
+// wordTerm matches a bare word against the description
+type wordTerm struct{ word string }
+
+func (n wordTerm) match(t *Task) bool {
+	return strings.Contains(strings.ToLower(t.Description), strings.ToLower(n.word))
+}
+
+// ParseFilter compiles a Taskwarrior-style filter expression. An empty
+// filter matches every task.
+func ParseFilter(s string) (*Filter, error) {
+	tokens, err := splitFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	if len(tokens) == 0 {
+		return &Filter{src: s, root: matchAll{}}, nil
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("filter %q: unexpected %q", s, p.tokens[p.pos])
+	}
+	return &Filter{src: s, root: root}, nil
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Match reports whether the task matches the filter
+func (f *Filter) Match(t Task) bool {
+	if f == nil || f.root == nil {
+		return true
+	}
+	return f.root.match(&t)
+}
+
+// splitFilter splits an expression into terms, honouring single and double
+// quotes and treating parentheses as separate tokens
+func splitFilter(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inToken := false
+	var quote rune
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			inToken = false
+		}
+	}
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inToken = true
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			cur.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("filter %q: unterminated quote", s)
+	}
+	flush()
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok == "" || tok == ")" || strings.EqualFold(tok, "or") {
+			return left, nil
+		}
+		if strings.EqualFold(tok, "and") {
+			p.pos++
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of filter")
+	case strings.EqualFold(tok, "not") || tok == "!":
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	case tok == "(":
+		p.pos++
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return inner, nil
+	case tok == ")" || strings.EqualFold(tok, "and") || strings.EqualFold(tok, "or"):
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+	p.pos++
+	return parseTerm(tok)
+}
+
+// filterModifiers maps Taskwarrior attribute modifiers (and synonyms) to their canonical name
+var filterModifiers = map[string]string{
+	"":           "",
+	"is":         "is",
+	"equals":     "is",
+	"isnt":       "isnt",
+	"not":        "isnt",
+	"has":        "has",
+	"contains":   "has",
+	"hasnt":      "hasnt",
+	"startswith": "startswith",
+	"left":       "startswith",
+	"endswith":   "endswith",
+	"right":      "endswith",
+	"any":        "any",
+	"none":       "none",
+	"before":     "before",
+	"below":      "before",
+	"under":      "before",
+	"after":      "after",
+	"above":      "after",
+	"over":       "after",
+}
+
+func parseTerm(tok string) (filterNode, error) {
+	if len(tok) > 1 && (tok[0] == '+' || tok[0] == '-') && !strings.ContainsAny(tok, ":=") {
+		return tagTerm{tag: tok[1:], present: tok[0] == '+'}, nil
+	}
+	i := strings.IndexAny(tok, ":=")
+	if i <= 0 {
+		return wordTerm{word: tok}, nil
+	}
+	name, value := tok[:i], tok[i+1:]
+	attr, mod := name, ""
+	if j := strings.Index(name, "."); j > 0 {
+		if canonical, ok := filterModifiers[strings.ToLower(name[j+1:])]; ok {
+			attr, mod = name[:j], canonical
+		}
+	}
+	return attrTerm{attr: strings.ToLower(attr), modifier: mod, value: value}, nil
+}
+
+func (n attrTerm) match(t *Task) bool {
+	actual, present := t.Attribute(n.attr)
+	switch n.modifier {
+	case "any":
+		return present && actual != ""
+	case "none":
+		return !present || actual == ""
+	}
+	if n.attr == "tags" {
+		return n.matchTags(t)
+	}
+	a, v := strings.ToLower(actual), strings.ToLower(n.value)
+	switch n.modifier {
+	case "":
+		if v == "" {
+			return a == ""
+		}
+		if n.attr == "project" {
+			// project:work matches work and work.* like Taskwarrior
+			return a == v || strings.HasPrefix(a, v+".")
+		}
+		if n.attr == "description" {
+			return strings.Contains(a, v)
+		}
+		return a == v
+	case "is":
+		return a == v
+	case "isnt":
+		return a != v
+	case "has":
+		return strings.Contains(a, v)
+	case "hasnt":
+		return !strings.Contains(a, v)
+	case "startswith":
+		return strings.HasPrefix(a, v)
+	case "endswith":
+		return strings.HasSuffix(a, v)
+	case "before", "after":
+		if !present || actual == "" {
+			return false
+		}
+		c, ok := compareValues(actual, n.value)
+		if !ok {
+			return false
+		}
+		if n.modifier == "before" {
+			return c < 0
+		}
+		return c > 0
+	}
+	return false
+}
+
+// matchTags handles tags:x (has tag), tags.not:x and tags.none:
+func (n attrTerm) matchTags(t *Task) bool {
+	has := t.HasTag(n.value)
+	switch n.modifier {
+	case "isnt", "hasnt":
+		return !has
+	default:
+		return has
+	}
+}
+
+// compareValues compares two attribute values as dates, then numbers, then
+// priorities (L < M < H)
+func compareValues(a, b string) (int, bool) {
+	if ta, err := parseFilterDate(a); err == nil {
+		if tb, err := parseFilterDate(b); err == nil {
+			return ta.Compare(tb), true
+		}
+	}
+	if fa, err := strconv.ParseFloat(a, 64); err == nil {
+		if fb, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case fa < fb:
+				return -1, true
+			case fa > fb:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	prio := map[string]int{"": 0, "l": 1, "m": 2, "h": 3}
+	pa, oka := prio[strings.ToLower(a)]
+	pb, okb := prio[strings.ToLower(b)]
+	if oka && okb {
+		return pa - pb, true
+	}
+	return 0, false
+}
+
+// parseFilterDate parses a date attribute or filter value. Filter values may
+// be relative (eow, now+1d); dates without a zone are in the configured timezone.
+func parseFilterDate(s string) (time.Time, error) {
+	return util.ParseDate(s, time.Now(), util.Location())
+}
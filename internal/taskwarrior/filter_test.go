@@ -0,0 +1,71 @@
+package taskwarrior
+
+import "testing"
+
+func TestParseFilter_Match(t *testing.T) {
+	task := Task{
+		ID:          3,
+		UUID:        "abc-123",
+		Description: "Pay the electricity bill",
+		Project:     "home.bills",
+		Priority:    "H",
+		Status:      "pending",
+		Tags:        []string{"household", "money"},
+		Due:         "20250901T090000Z",
+		UDAs:        map[string]interface{}{"estimate": float64(3)},
+	}
+	cases := []struct {
+		filter string
+		want   bool
+	}{
+		{"", true},
+		{"+household", true},
+		{"+work", false},
+		{"-work", true},
+		{"-money", false},
+		{"project:home", true},
+		{"project:home.bills", true},
+		{"project:hom", false},
+		{"project.is:home", false},
+		{"project.startswith:hom", true},
+		{"priority:H", true},
+		{"priority.not:H", false},
+		{"priority.above:M", true},
+		{"priority.below:M", false},
+		{"+household and priority:L", false},
+		{"+work or +money", true},
+		{"+work or (+household priority:H)", true},
+		{"not +work", true},
+		{"! +money", false},
+		{"bill", true},
+		{"description.has:electricity", true},
+		{"description:'electricity bill'", true},
+		{"due.before:2025-09-02", true},
+		{"due.after:2025-09-02", false},
+		{"due.any:", true},
+		{"wait.none:", true},
+		{"wait.any:", false},
+		{"estimate.above:2", true},
+		{"tags:money", true},
+		{"tags.not:money", false},
+		{"status:pending", true},
+		{"uuid:abc-123", true},
+	}
+	for _, c := range cases {
+		f, err := ParseFilter(c.filter)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q): %v", c.filter, err)
+		}
+		if got := f.Match(task); got != c.want {
+			t.Errorf("filter %q: got %v, want %v", c.filter, got, c.want)
+		}
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, s := range []string{"(+work", "+work)", "and +work", "+work or", "description:'open", "not"} {
+		if _, err := ParseFilter(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+}
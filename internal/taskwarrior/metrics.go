@@ -0,0 +1,33 @@
+package taskwarrior
+
+import "task-herald/internal/metrics"
+
+// Sync outcomes
+const (
+	syncSuccess     = "success"
+	syncFailure     = "failure"
+	syncUnconfirmed = "unconfirmed" // task sync exited 0 without confirming the sync
+)
+
+var (
+	pollsTotal = metrics.NewCounter("task_herald_polls_total",
+		"Polls of the task source by result (success or failure).", "result")
+	exportDuration = metrics.NewHistogram("task_herald_export_duration_seconds",
+		"Time taken by task export.", metrics.DefBuckets)
+	parseFailures = metrics.NewCounter("task_herald_parse_failures_total",
+		"Task data that could not be parsed, by source (task, file or taskchampion).", "source")
+	syncRuns = metrics.NewCounter("task_herald_sync_runs_total",
+		"Runs of task sync by outcome (success, failure or unconfirmed).", "outcome")
+	lastSyncSuccess = metrics.NewGauge("task_herald_last_sync_success_timestamp_seconds",
+		"Unix time of the last successful task sync.")
+)
+
+func init() {
+	// Report every outcome from the start so alerts on them work
+	for _, result := range []string{"success", "failure"} {
+		pollsTotal.Add(0, result)
+	}
+	for _, outcome := range []string{syncSuccess, syncFailure, syncUnconfirmed} {
+		syncRuns.Add(0, outcome)
+	}
+}
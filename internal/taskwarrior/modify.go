@@ -1,9 +1,8 @@
 package taskwarrior
 
 import (
+	"fmt"
 	"strings"
-
-	"task-herald/internal/config"
 )
 
 // ModifyTask runs a Taskwarrior modify command for a given UUID and arguments.
@@ -11,24 +10,42 @@ import (
 // Returns true if the modification succeeded, false otherwise.
 func ModifyTask(uuid string, args ...string) bool {
 	if strings.TrimSpace(uuid) == "" {
-		config.Log(config.ERROR, "ModifyTask: empty UUID")
+		taskLog.Error("task modify: empty UUID")
 		return false
 	}
 	cmdArgs := append([]string{uuid, "modify"}, args...)
-	cmd := execCommand("task", cmdArgs...)
+	cmd := taskCommand(cmdArgs...)
 	cmdStr := "task " + strings.Join(cmdArgs, " ")
-	config.Log(config.DEBUG, "ModifyTask running: %s", cmdStr)
+	taskLog.Debug("Running task modify", "task_uuid", uuid, "command", cmdStr)
 	output, err := cmd.CombinedOutput()
-	config.Log(config.DEBUG, "ModifyTask response: %s", string(output))
+	taskLog.Debug("task modify output", "task_uuid", uuid, "output", string(output))
 	if err != nil {
-		config.Log(config.ERROR, "ModifyTask failed: %s\nError: %v\nOutput: %s", cmdStr, err, string(output))
+		taskLog.Error("task modify failed", "task_uuid", uuid, "command", cmdStr, "error", err, "output", string(output))
 		return false
 	}
 	// Check output for confirmation of modification
 	if !strings.Contains(string(output), "Modified") && !strings.Contains(string(output), "modification") {
-		config.Log(config.WARN, "ModifyTask: command ran but did not confirm modification: %s\nOutput: %s", cmdStr, string(output))
+		taskLog.Warn("task modify did not confirm the modification", "task_uuid", uuid, "command", cmdStr, "output", string(output))
 		return false
 	}
-	config.Log(config.INFO, "ModifyTask succeeded: %s", cmdStr)
+	taskLog.Info("Modified task", "task_uuid", uuid, "command", cmdStr)
 	return true
 }
+
+// CompleteTask marks the task with the given UUID as done.
+func CompleteTask(uuid string) error {
+	if strings.TrimSpace(uuid) == "" {
+		return fmt.Errorf("empty UUID")
+	}
+	cmdArgs := []string{uuid, "done"}
+	cmd := taskCommand(cmdArgs...)
+	cmdStr := "task " + strings.Join(cmdArgs, " ")
+	taskLog.Debug("Running task done", "task_uuid", uuid, "command", cmdStr)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		taskLog.Error("task done failed", "task_uuid", uuid, "command", cmdStr, "error", err, "output", string(output))
+		return fmt.Errorf("task done failed: %w", err)
+	}
+	taskLog.Info("Completed task", "task_uuid", uuid)
+	return nil
+}
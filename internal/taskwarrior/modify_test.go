@@ -2,6 +2,7 @@ package taskwarrior
 
 import (
 	"os/exec"
+	"strings"
 	"testing"
 )
 
@@ -56,3 +57,30 @@ func TestModifyTask_Success(t *testing.T) {
 		t.Error("expected ModifyTask to return true on successful modification")
 	}
 }
+
+func TestCompleteTask(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		return exec.Command("echo", "Completed 1 task.")
+	}
+	if err := CompleteTask("uuid-4"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(gotArgs, " ") != "rc.confirmation=off uuid-4 done" {
+		t.Fatalf("unexpected args: %v", gotArgs)
+	}
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+	if err := CompleteTask("uuid-4"); err == nil {
+		t.Error("expected error on command failure")
+	}
+	if err := CompleteTask(" "); err == nil {
+		t.Error("expected error for empty UUID")
+	}
+}
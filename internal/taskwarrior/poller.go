@@ -5,159 +5,206 @@ import (
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"strings"
 	"task-herald/internal/config"
+	"task-herald/internal/util"
 	"time"
 )
 
 // execCommand is used for testability. In production, it is exec.Command.
 var execCommand = exec.Command
 
+// Loggers of the components that log from this package
+var (
+	pollLog = config.Logger(config.LogPoller)
+	taskLog = config.Logger(config.LogTask)
+)
+
 type Task struct {
-	ID               int      `json:"id"`
-	UUID             string   `json:"uuid"`
-	Description      string   `json:"description"`
-	NotificationDate string   `json:"notification_date"`
-	Tags             []string `json:"tags"`
-	Priority         string   `json:"priority"`
-	Project          string   `json:"project"`
-	Status           string   `json:"status"`
+	ID               int          `json:"id"`
+	UUID             string       `json:"uuid"`
+	Description      string       `json:"description"`
+	NotificationDate string       `json:"notification_date"`
+	Tags             []string     `json:"tags"`
+	Priority         string       `json:"priority"`
+	Project          string       `json:"project"`
+	Status           string       `json:"status"`
+	Due              string       `json:"due"`
+	Scheduled        string       `json:"scheduled"`
+	Wait             string       `json:"wait"`
+	Until            string       `json:"until"`
+	Entry            string       `json:"entry"`
+	Modified         string       `json:"modified"`
+	Start            string       `json:"start"`
+	End              string       `json:"end"`
+	Recur            string       `json:"recur"`
+	Parent           string       `json:"parent"`
+	Urgency          float64      `json:"urgency"`
+	Depends          StringList   `json:"depends"`
+	Annotations      []Annotation `json:"annotations"`
+	// UDAs holds any exported attributes not covered by the fields above,
+	// as decoded from JSON (string, float64, ...); see the UDA accessors
+	UDAs map[string]interface{} `json:"-"`
 }
 
-// ParseNotificationDate parses the NotificationDate string into a time.Time object.
-func (t *Task) ParseNotificationDate() (time.Time, error) {
-	// Try Taskwarrior's default date format: "2006-01-02 15:04:05"
-	if t.NotificationDate == "" {
-		return time.Time{}, fmt.Errorf("NotificationDate is empty")
+// Annotation is a timestamped note on a task
+type Annotation struct {
+	Entry       string `json:"entry"`
+	Description string `json:"description"`
+}
+
+// StringList decodes a JSON array of strings or a comma-separated string
+// (older Taskwarrior versions export depends that way)
+type StringList []string
+
+// UnmarshalJSON accepts ["a","b"] and "a,b"
+func (l *StringList) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*l = list
+		return nil
 	}
-	layouts := []string{
-		"2006-01-02 15:04:05",
-		time.RFC3339,
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04",
-		"2006-01-02T15:04",
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
 	}
-	var lastErr error
-	for _, layout := range layouts {
-		parsed, err := time.Parse(layout, t.NotificationDate)
-		if err == nil {
-			return parsed, nil
+	*l = nil
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			*l = append(*l, part)
 		}
-		lastErr = err
 	}
-	return time.Time{}, fmt.Errorf("could not parse NotificationDate '%s': %v", t.NotificationDate, lastErr)
+	return nil
 }
 
-func ExportIncompleteTasks() ([]Task, error) {
-	// Get pending tasks
-	pendingCmd := execCommand("task", "status:pending", "export", "rc.json.array=on")
-	var pendingOut bytes.Buffer
-	pendingCmd.Stdout = &pendingOut
-	pendingCmd.Stderr = &pendingOut
-	err := pendingCmd.Run()
-	pendingRaw := pendingOut.String()
-	config.Log(config.DEBUG, "DEBUG: Raw task export (pending): %s", pendingRaw)
-	if err != nil {
-		return nil, err
+// knownTaskFields are decoded into Task struct fields rather than UDAs
+var knownTaskFields = map[string]struct{}{
+	"id": {}, "uuid": {}, "description": {}, "notification_date": {},
+	"tags": {}, "priority": {}, "project": {}, "status": {},
+	"due": {}, "scheduled": {}, "wait": {}, "until": {},
+	"entry": {}, "modified": {}, "start": {}, "end": {}, "recur": {},
+	"parent": {}, "urgency": {}, "depends": {}, "annotations": {},
+	// internal recurrence bookkeeping, not user attributes
+	"mask": {}, "imask": {},
+}
+
+// IsTaskField reports whether name is a built-in task attribute rather than a UDA
+func IsTaskField(name string) bool {
+	_, ok := knownTaskFields[strings.ToLower(name)]
+	return ok
+}
+
+// UnmarshalJSON decodes the known task fields and collects the rest into UDAs
+func (t *Task) UnmarshalJSON(data []byte) error {
+	type plain Task
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
 	}
-	start := -1
-	end := -1
-	for i, c := range pendingRaw {
-		if c == '[' && start == -1 {
-			start = i
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		if _, known := knownTaskFields[k]; known {
+			continue
 		}
-		if c == ']' {
-			end = i
+		if p.UDAs == nil {
+			p.UDAs = make(map[string]interface{})
 		}
+		p.UDAs[k] = v
 	}
-	if start == -1 || end == -1 || end <= start {
-		config.Log(config.DEBUG, "DEBUG: Could not find JSON array in pending output")
-		return nil, fmt.Errorf("could not find JSON array in pending output")
+	*t = Task(p)
+	return nil
+}
+
+// ParseNotificationDate parses the NotificationDate string into a time.Time
+// object; see util.ParseNotificationDate
+func (t *Task) ParseNotificationDate() (time.Time, error) {
+	if t.NotificationDate == "" {
+		return time.Time{}, fmt.Errorf("NotificationDate is empty")
 	}
-	pendingPart := pendingRaw[start : end+1]
-	var pendingTasks []Task
-	if err := json.Unmarshal([]byte(pendingPart), &pendingTasks); err != nil {
-		config.Log(config.DEBUG, "DEBUG: JSON unmarshal error (pending): %v", err)
-		return nil, err
+	return util.ParseNotificationDate(t.NotificationDate)
+}
+
+// exportStatusFilter restricts exports to the tasks the daemon schedules
+var exportStatusFilter = []string{"(", "status:pending", "or", "status:waiting", ")"}
+
+// exportArgs returns the task arguments exporting the pending and waiting
+// tasks that match the configured filter
+func exportArgs() ([]string, error) {
+	args := []string{"rc.json.array=on", "rc.verbose=nothing"}
+	args = append(args, exportStatusFilter...)
+	if cfg := config.Get(); cfg != nil && strings.TrimSpace(cfg.Taskwarrior.Filter) != "" {
+		words, err := splitFilter(cfg.Taskwarrior.Filter)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "(")
+		args = append(args, words...)
+		args = append(args, ")")
 	}
+	return append(args, "export"), nil
+}
 
-	// Get waiting tasks
-	waitingCmd := execCommand("task", "status:waiting", "export", "rc.json.array=on")
-	var waitingOut bytes.Buffer
-	waitingCmd.Stdout = &waitingOut
-	waitingCmd.Stderr = &waitingOut
-	err = waitingCmd.Run()
-	waitingRaw := waitingOut.String()
-	config.Log(config.DEBUG, "DEBUG: Raw task export (waiting): %s", waitingRaw)
+// ExportIncompleteTasks exports the pending and waiting tasks matching the
+// configured filter with a single task export. Only stdout is parsed as
+// JSON; anything Taskwarrior prints on stderr is logged.
+func ExportIncompleteTasks() ([]Task, error) {
+	args, err := exportArgs()
 	if err != nil {
 		return nil, err
 	}
-	start = -1
-	end = -1
-	for i, c := range waitingRaw {
-		if c == '[' && start == -1 {
-			start = i
-		}
-		if c == ']' {
-			end = i
+	cmd := taskCommand(args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	start := time.Now()
+	err = cmd.Run()
+	exportDuration.Observe(time.Since(start).Seconds())
+	msg := strings.TrimSpace(stderr.String())
+	if err != nil {
+		if msg != "" {
+			return nil, fmt.Errorf("task export failed: %w: %s", err, msg)
 		}
+		return nil, fmt.Errorf("task export failed: %w", err)
 	}
-	if start == -1 || end == -1 || end <= start {
-		config.Log(config.DEBUG, "DEBUG: Could not find JSON array in waiting output")
-		return nil, fmt.Errorf("could not find JSON array in waiting output")
+	if msg != "" {
+		pollLog.Debug("task export wrote to stderr", "stderr", msg)
 	}
-	waitingPart := waitingRaw[start : end+1]
-	var waitingTasks []Task
-	if err := json.Unmarshal([]byte(waitingPart), &waitingTasks); err != nil {
-		config.Log(config.DEBUG, "DEBUG: JSON unmarshal error (waiting): %v", err)
-		return nil, err
-	}
-
-	// Combine both
-	allTasks := append(pendingTasks, waitingTasks...)
-	config.Log(config.DEBUG, "DEBUG: Parsed %d pending tasks, %d waiting tasks, %d total", len(pendingTasks), len(waitingTasks), len(allTasks))
-
-	// After combining all tasks, process +tag in description
-	for _, task := range allTasks {
-		config.Log(config.DEBUG, "Processing task: ID=%d, UUID=%s, Desc=\"%s\"", task.ID, task.UUID, task.Description)
-		tags := ExtractTags(task.Description)
-		if len(tags) == 0 {
-			continue
-		}
-		config.Log(config.INFO, "Found +tag(s) in task %s: %v", task.UUID, tags)
-		// Remove +tag from description
-		newDesc := RemoveTagsFromDescription(task.Description)
-		// Add tags and update description in Taskwarrior
-		for _, tag := range tags {
-			normTag := DashToCamel(tag)
-			config.Log(config.INFO, "Adding tag '+%s' (normalized: '+%s') to task %s", tag, normTag, task.UUID)
-			ModifyTask(task.UUID, "+"+normTag)
-		}
-		if newDesc != task.Description {
-			config.Log(config.VERBOSE, "Updating description for task %s: \"%s\" -> \"%s\"", task.UUID, task.Description, newDesc)
-			ModifyTask(task.UUID, "description:"+newDesc)
-		}
+	allTasks, err := decodeTasks(&stdout)
+	if err != nil {
+		parseFailures.Inc("task")
+		return nil, fmt.Errorf("could not parse task export output: %w", err)
 	}
+	pollLog.Debug("Exported pending and waiting tasks", "count", len(allTasks))
 	return allTasks, nil
 }
 
-// Poller polls Taskwarrior at the given interval and sends tasks to the channel.
-func Poller(interval time.Duration, out chan<- []Task, stop <-chan struct{}) {
+// Poller polls the task source at the given interval and sends tasks to the channel.
+func Poller(src TaskSource, interval time.Duration, out chan<- []Task, stop <-chan struct{}) {
 	// Fetch tasks immediately on startup
-	tasks, err := ExportIncompleteTasks()
-	if err == nil {
-		out <- tasks
-	}
+	poll(src, out)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
-			tasks, err := ExportIncompleteTasks()
-			if err == nil {
-				out <- tasks
-			}
+			poll(src, out)
 		case <-stop:
 			return
 		}
 	}
 }
+
+// poll loads the tasks once and sends them to out
+func poll(src TaskSource, out chan<- []Task) {
+	tasks, err := src.Tasks()
+	if err != nil {
+		pollsTotal.Inc("failure")
+		pollLog.Error("Failed to load tasks", "error", err)
+		return
+	}
+	pollsTotal.Inc("success")
+	out <- tasks
+}
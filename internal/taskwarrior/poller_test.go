@@ -1,7 +1,10 @@
 package taskwarrior
 
 import (
+	"encoding/json"
 	"os/exec"
+	"strings"
+	"task-herald/internal/config"
 	"testing"
 	"time"
 )
@@ -25,33 +28,19 @@ func TestPoller_SendsTasks_AndStops(t *testing.T) {
 	origExec := execCommand
 	defer func() { execCommand = origExec }()
 
-	pendingJSON := `[{"id":1,"uuid":"abc","description":"pending task","notification_date":"2025-08-31 14:30:00","tags":[],"priority":"H","project":"demo","status":"pending"}]`
-	waitingJSON := `[{"id":2,"uuid":"def","description":"waiting task","notification_date":"2025-08-31T14:30:00Z","tags":[],"priority":"L","project":"demo","status":"waiting"}]`
-	pendingOut := "" + pendingJSON
-	waitingOut := "" + waitingJSON
-
-	call := 0
-	execCommand = func(name string, args ...string) *exec.Cmd {
-		var out string
-		if call == 0 {
-			out = pendingOut
-		} else {
-			out = waitingOut
-		}
-		call++
-		return exec.Command("echo", out)
-	}
+	exportJSON := `[{"id":1,"uuid":"abc","description":"pending task","notification_date":"2025-08-31 14:30:00","tags":[],"priority":"H","project":"demo","status":"pending"},{"id":2,"uuid":"def","description":"waiting task","notification_date":"2025-08-31T14:30:00Z","tags":[],"priority":"L","project":"demo","status":"waiting"}]`
+	execCommand = fakeExecCommandHelper(exportJSON)
 
 	outCh := make(chan []Task, 4)
 	stop := make(chan struct{})
 
-	go Poller(10*time.Millisecond, outCh, stop)
+	go Poller(CLISource{}, 10*time.Millisecond, outCh, stop)
 
 	// Expect an initial immediate send
 	select {
 	case tasks := <-outCh:
-		if len(tasks) != 2 && len(tasks) != 1 { // combined may produce 2 tasks (pending+waiting)
-			t.Fatalf("expected at least 1 task, got %d", len(tasks))
+		if len(tasks) != 2 {
+			t.Fatalf("expected 2 tasks, got %d", len(tasks))
 		}
 	case <-time.After(200 * time.Millisecond):
 		t.Fatal("timeout waiting for initial poll result")
@@ -131,33 +120,113 @@ func TestExportIncompleteTasks_Success(t *testing.T) {
 	// Save and restore execCommand
 	origExec := execCommand
 	defer func() { execCommand = origExec }()
+	config.Set(&config.Config{})
 
-	pendingJSON := `[{"id":1,"uuid":"abc","description":"pending task","notification_date":"2025-08-31 14:30:00","tags":["foo"],"priority":"H","project":"demo","status":"pending"}]`
-	waitingJSON := `[{"id":2,"uuid":"def","description":"waiting task","notification_date":"2025-08-31T14:30:00Z","tags":["bar"],"priority":"L","project":"demo","status":"waiting"}]`
-	// Wrap in extra text to simulate real output
-	pendingOut := "Some log...\n" + pendingJSON + "\nMore log..."
-	waitingOut := "Other log...\n" + waitingJSON + "\nEnd log."
+	exportJSON := `[{"id":1,"uuid":"abc","description":"pending task","notification_date":"2025-08-31 14:30:00","tags":["foo"],"priority":"H","project":"demo","status":"pending"},
+{"id":2,"uuid":"def","description":"waiting task","notification_date":"2025-08-31T14:30:00Z","tags":["bar"],"priority":"L","project":"demo","status":"waiting"}]`
 
-	call := 0
+	var calls [][]string
 	execCommand = func(name string, args ...string) *exec.Cmd {
-		var out string
-		if call == 0 {
-			out = pendingOut
-		} else {
-			out = waitingOut
-		}
-		call++
-		return exec.Command("echo", out)
+		calls = append(calls, args)
+		// Messages on stderr must not get in the way of the JSON on stdout
+		return exec.Command("sh", "-c", `echo "Some log... [not json]" >&2; printf '%s\n' "$1"; echo "More log..." >&2`, "sh", exportJSON)
 	}
 
 	tasks, err := ExportIncompleteTasks()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if len(calls) != 1 {
+		t.Fatalf("expected a single task export, got %d calls", len(calls))
+	}
+	want := "rc.confirmation=off rc.json.array=on rc.verbose=nothing ( status:pending or status:waiting ) export"
+	if got := strings.Join(calls[0], " "); got != want {
+		t.Errorf("unexpected export args:\n got: %s\nwant: %s", got, want)
+	}
 	if len(tasks) != 2 {
-		t.Errorf("expected 2 tasks, got %d", len(tasks))
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
 	}
 	if tasks[0].UUID != "abc" || tasks[1].UUID != "def" {
 		t.Errorf("unexpected tasks: %+v", tasks)
 	}
 }
+
+func TestExportIncompleteTasks_FilterAndOverrides(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	config.Set(&config.Config{Taskwarrior: config.TaskwarriorConfig{
+		Filter:       `notification_date.any: or +remind or description:"call mom"`,
+		DataLocation: "/srv/task",
+		TaskRC:       "/srv/taskrc",
+		RC:           map[string]string{"hooks": "off", "rc.gc": "off"},
+	}})
+	defer config.Set(&config.Config{})
+
+	var cmd *exec.Cmd
+	var gotArgs []string
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		gotArgs = args
+		cmd = exec.Command("echo", "[]")
+		return cmd
+	}
+	tasks, err := ExportIncompleteTasks()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tasks) != 0 {
+		t.Fatalf("expected no tasks, got %d", len(tasks))
+	}
+	want := "rc.confirmation=off rc.data.location=/srv/task rc.gc=off rc.hooks=off rc.json.array=on rc.verbose=nothing " +
+		"( status:pending or status:waiting ) ( notification_date.any: or +remind or description:call mom ) export"
+	if got := strings.Join(gotArgs, " "); got != want {
+		t.Errorf("unexpected export args:\n got: %s\nwant: %s", got, want)
+	}
+	if n := len(cmd.Env); n == 0 || cmd.Env[n-1] != "TASKRC=/srv/taskrc" {
+		t.Errorf("TASKRC not set in environment: %v", cmd.Env)
+	}
+}
+
+func TestExportIncompleteTasks_StderrInError(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	config.Set(&config.Config{})
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo 'Could not find the data directory' >&2; exit 2")
+	}
+	_, err := ExportIncompleteTasks()
+	if err == nil || !strings.Contains(err.Error(), "Could not find the data directory") {
+		t.Fatalf("expected error carrying stderr, got %v", err)
+	}
+}
+
+func TestTask_UnmarshalJSON_CollectsUDAs(t *testing.T) {
+	var task Task
+	data := `{"id":3,"uuid":"abc","description":"d","notification_repeat_enable":"true","notification_repeat_delay":"PT10M","urgency":4.2}`
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if task.ID != 3 || task.UUID != "abc" || task.Description != "d" {
+		t.Fatalf("known fields not decoded: %+v", task)
+	}
+	if task.UDAs["notification_repeat_enable"] != "true" || task.UDAs["notification_repeat_delay"] != "PT10M" {
+		t.Fatalf("UDAs not collected: %v", task.UDAs)
+	}
+	if _, ok := task.UDAs["uuid"]; ok {
+		t.Fatalf("known field leaked into UDAs: %v", task.UDAs)
+	}
+}
+
+func TestTask_UnmarshalJSON_Dates(t *testing.T) {
+	var task Task
+	data := `{"uuid":"abc","due":"20250901T090000Z","scheduled":"20250830T080000Z","wait":"20250829T000000Z","until":"20251001T000000Z"}`
+	if err := json.Unmarshal([]byte(data), &task); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if task.Due != "20250901T090000Z" || task.Scheduled != "20250830T080000Z" || task.Wait != "20250829T000000Z" || task.Until != "20251001T000000Z" {
+		t.Fatalf("dates not decoded: %+v", task)
+	}
+	if len(task.UDAs) != 0 {
+		t.Fatalf("dates leaked into UDAs: %v", task.UDAs)
+	}
+}
@@ -0,0 +1,166 @@
+package taskwarrior
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"task-herald/internal/config"
+)
+
+// TaskSource loads the current pending and waiting tasks
+type TaskSource interface {
+	Tasks() ([]Task, error)
+}
+
+// CLISource exports tasks with the task command
+type CLISource struct{}
+
+// Tasks runs task export
+func (CLISource) Tasks() ([]Task, error) {
+	return ExportIncompleteTasks()
+}
+
+// FileSource reads tasks from a static task export file, e.g. a snapshot
+// taken with 'task export > tasks.json'. The file is re-read on every poll.
+type FileSource struct {
+	Path string
+}
+
+// Tasks returns the pending and waiting tasks of the file
+func (s FileSource) Tasks() ([]Task, error) {
+	tasks, err := readTaskFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	return incomplete(tasks), nil
+}
+
+// DirSource reads tasks from every *.json file in a directory. A task that
+// appears in several files is taken from the last file in name order.
+type DirSource struct {
+	Dir string
+}
+
+// Tasks returns the pending and waiting tasks of all files in the directory
+func (s DirSource) Tasks() ([]Task, error) {
+	if _, err := os.Stat(s.Dir); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var all []Task
+	pos := map[string]int{}
+	for _, f := range files {
+		tasks, err := readTaskFile(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tasks {
+			if i, ok := pos[t.UUID]; ok && t.UUID != "" {
+				all[i] = t
+				continue
+			}
+			pos[t.UUID] = len(all)
+			all = append(all, t)
+		}
+	}
+	return incomplete(all), nil
+}
+
+// readTaskFile decodes a task export: a JSON array of tasks, or one task
+// object per line as written by older exports and Taskwarrior hooks
+func readTaskFile(path string) ([]Task, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tasks, err := decodeTasks(f)
+	if err != nil {
+		parseFailures.Inc("file")
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tasks, nil
+}
+
+// decodeTasks decodes a JSON array of tasks or a stream of task objects
+func decodeTasks(r io.Reader) ([]Task, error) {
+	br := bufio.NewReader(r)
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		break
+	}
+	dec := json.NewDecoder(br)
+	var tasks []Task
+	if b, _ := br.Peek(1); b[0] == '[' {
+		if err := dec.Decode(&tasks); err != nil {
+			return nil, err
+		}
+		return tasks, nil
+	}
+	for {
+		var t Task
+		err := dec.Decode(&t)
+		if err == io.EOF {
+			return tasks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+}
+
+// incomplete keeps pending and waiting tasks, like the task CLI source
+func incomplete(tasks []Task) []Task {
+	out := tasks[:0]
+	for _, t := range tasks {
+		switch t.Status {
+		case "", "pending", "waiting":
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// NewSource returns the task source selected by cfg.Type: "task" (default,
+// the task CLI), "taskchampion" (a Taskwarrior 3 database read directly),
+// "file" (a task export JSON file) or "dir" (a directory of export files).
+// dateUDAs names UDAs holding dates, which TaskChampion stores as epoch seconds.
+func NewSource(cfg config.SourceConfig, dateUDAs []string) (TaskSource, error) {
+	switch cfg.Type {
+	case "", "task":
+		return CLISource{}, nil
+	case "taskchampion":
+		return &TaskChampionSource{Path: TaskChampionPath(cfg.Path), DateUDAs: dateUDAs, Fallback: CLISource{}}, nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("source.path is required for the file source")
+		}
+		return FileSource{Path: cfg.Path}, nil
+	case "dir":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("source.path is required for the dir source")
+		}
+		return DirSource{Dir: cfg.Path}, nil
+	}
+	return nil, fmt.Errorf("unknown task source %q", cfg.Type)
+}
@@ -0,0 +1,84 @@
+package taskwarrior
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"task-herald/internal/config"
+)
+
+func TestFileSource_Tasks(t *testing.T) {
+	tasks, err := FileSource{Path: filepath.Join("testdata", "export.json")}.Tasks()
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected the pending and waiting task, got %+v", tasks)
+	}
+	if tasks[0].NotificationDate != "20250901T080000Z" || tasks[1].Status != "waiting" || tasks[1].Due != "20250930T170000Z" {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+}
+
+func TestDirSource_Tasks(t *testing.T) {
+	tasks, err := DirSource{Dir: filepath.Join("testdata", "snapshots")}.Tasks()
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	var descs []string
+	for _, task := range tasks {
+		descs = append(descs, task.Description)
+	}
+	// the later file completes "Water plants"
+	if strings.Join(descs, ",") != "Call plumber,Book flights" {
+		t.Fatalf("unexpected tasks: %v", descs)
+	}
+}
+
+func TestDecodeTasks(t *testing.T) {
+	cases := map[string]int{
+		"":       0,
+		"  \n[]": 0,
+		"\n[{\"uuid\":\"a\"},{\"uuid\":\"b\"}]\n":              2,
+		"{\"uuid\":\"a\"}\n{\"uuid\":\"b\"}\n{\"uuid\":\"c\"}": 3,
+	}
+	for in, want := range cases {
+		tasks, err := decodeTasks(strings.NewReader(in))
+		if err != nil || len(tasks) != want {
+			t.Errorf("decodeTasks(%q) = %d tasks, %v; want %d", in, len(tasks), err, want)
+		}
+	}
+	if _, err := decodeTasks(strings.NewReader("[{\"uuid\":")); err == nil {
+		t.Error("expected error for truncated JSON")
+	}
+}
+
+func TestFileAndDirSource_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := (FileSource{Path: filepath.Join(dir, "missing.json")}).Tasks(); err == nil {
+		t.Error("expected error for a missing file")
+	}
+	if _, err := (DirSource{Dir: filepath.Join(dir, "missing")}).Tasks(); err == nil {
+		t.Error("expected error for a missing directory")
+	}
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte("not json"), 0o644)
+	if _, err := (DirSource{Dir: dir}).Tasks(); err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("expected error naming the bad file, got %v", err)
+	}
+}
+
+func TestNewSource_FileAndDir(t *testing.T) {
+	if src, err := NewSource(config.SourceConfig{Type: "file", Path: "x.json"}, nil); err != nil || src != (FileSource{Path: "x.json"}) {
+		t.Errorf("file source: %+v %v", src, err)
+	}
+	if src, err := NewSource(config.SourceConfig{Type: "dir", Path: "snapshots"}, nil); err != nil || src != (DirSource{Dir: "snapshots"}) {
+		t.Errorf("dir source: %+v %v", src, err)
+	}
+	for _, typ := range []string{"file", "dir"} {
+		if _, err := NewSource(config.SourceConfig{Type: typ}, nil); err == nil {
+			t.Errorf("%s source without path: expected error", typ)
+		}
+	}
+}
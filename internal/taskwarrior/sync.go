@@ -6,8 +6,8 @@ import (
 	"time"
 )
 
-// logFunc allows tests to capture log output. Defaults to config.Log.
-var logFunc = config.Log
+// syncLog is overridable for testing
+var syncLog = config.Logger(config.LogSync)
 
 // SyncTaskwarrior runs 'task sync' every 5 minutes in a background goroutine.
 func SyncTaskwarrior(stop <-chan struct{}) {
@@ -29,16 +29,21 @@ func SyncTaskwarrior(stop <-chan struct{}) {
 
 // SyncOnce runs 'task sync' one time immediately.
 func SyncOnce() {
-	logFunc(config.INFO, "Running task sync...")
-	cmd := execCommand("task", "sync")
+	syncLog.Info("Running task sync")
+	cmd := taskCommand("sync")
 	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
 	if err != nil {
-		logFunc(config.ERROR, "task sync failed: %v\nOutput: %s", err, string(output))
+		syncRuns.Inc(syncFailure)
+		syncLog.Error("task sync failed", "error", err, "output", out)
 		return
 	}
-	if !strings.Contains(string(output), "Sync completed") && !strings.Contains(string(output), "synchronized") && !strings.Contains(string(output), "Syncing with sync server") {
-		logFunc(config.WARN, "task sync: command ran but did not confirm sync: %s", string(output))
+	if !strings.Contains(out, "Sync completed") && !strings.Contains(out, "synchronized") && !strings.Contains(out, "Syncing with sync server") {
+		syncRuns.Inc(syncUnconfirmed)
+		syncLog.Warn("task sync ran but did not confirm the sync", "output", out)
 		return
 	}
-	logFunc(config.INFO, "task sync succeeded: %s", string(output))
+	syncRuns.Inc(syncSuccess)
+	lastSyncSuccess.Set(float64(time.Now().Unix()))
+	syncLog.Info("task sync succeeded", "output", out)
 }
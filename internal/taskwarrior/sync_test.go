@@ -1,12 +1,50 @@
 package taskwarrior
 
 import (
+	"context"
+	"log/slog"
 	"os/exec"
+	"strings"
+	"sync"
 	"task-herald/internal/config"
 	"testing"
 	"time"
 )
 
+// recordHandler keeps the records logged through it
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler      { return h }
+
+func (h *recordHandler) len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.records)
+}
+
+// captureSyncLog sends the sync logger to a recordHandler until the test ends
+func captureSyncLog(t *testing.T) *recordHandler {
+	t.Helper()
+	orig := syncLog
+	t.Cleanup(func() { syncLog = orig })
+	h := &recordHandler{}
+	syncLog = slog.New(h)
+	return h
+}
+
 func TestSyncOnce_Success(t *testing.T) {
 	origExec := execCommand
 	defer func() { execCommand = origExec }()
@@ -33,29 +71,53 @@ func TestSyncOnce_Failure(t *testing.T) {
 func TestSyncOnce_Logs(t *testing.T) {
 	origExec := execCommand
 	defer func() { execCommand = origExec }()
-	origLog := logFunc
-	defer func() { logFunc = origLog }()
-
-	var entries []string
-	logFunc = func(level config.LogLevel, format string, a ...interface{}) {
-		entries = append(entries, format)
-	}
+	logs := captureSyncLog(t)
 
 	execCommand = func(name string, args ...string) *exec.Cmd {
 		return exec.Command("echo", "Sync completed")
 	}
 
 	SyncOnce()
-	if len(entries) == 0 {
+	if logs.len() == 0 {
 		t.Error("expected log entries from SyncOnce, got none")
 	}
 }
 
+func TestSyncOnce_FailureAttrs(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	logs := captureSyncLog(t)
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("sh", "-c", "echo 'Could not connect'; echo 'to server' >&2; exit 1")
+	}
+
+	SyncOnce()
+	logs.mu.Lock()
+	defer logs.mu.Unlock()
+	var failed *slog.Record
+	for i := range logs.records {
+		if logs.records[i].Level == slog.LevelError {
+			failed = &logs.records[i]
+		}
+	}
+	if failed == nil || strings.Contains(failed.Message, "\n") {
+		t.Fatalf("expected a single-line error record, got %+v", logs.records)
+	}
+	attrs := map[string]string{}
+	failed.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	if attrs["error"] != "exit status 1" || attrs["output"] != "Could not connect\nto server" {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
+
 func TestSyncTaskwarrior_Ticker(t *testing.T) {
 	origExec := execCommand
 	defer func() { execCommand = origExec }()
-	origLog := logFunc
-	defer func() { logFunc = origLog }()
+	logs := captureSyncLog(t)
 
 	// quick interval
 	cfg := &config.Config{SyncInterval: 10 * time.Millisecond}
@@ -67,12 +129,6 @@ func TestSyncTaskwarrior_Ticker(t *testing.T) {
 		return exec.Command("echo", "Sync completed")
 	}
 
-	// capture logs
-	var entries []string
-	logFunc = func(level config.LogLevel, format string, a ...interface{}) {
-		entries = append(entries, format)
-	}
-
 	stop := make(chan struct{})
 	go SyncTaskwarrior(stop)
 
@@ -82,7 +138,30 @@ func TestSyncTaskwarrior_Ticker(t *testing.T) {
 	if call == 0 {
 		t.Fatal("expected SyncOnce to be called at least once via ticker")
 	}
-	if len(entries) == 0 {
+	if logs.len() == 0 {
 		t.Error("expected log entries from SyncTaskwarrior, got none")
 	}
 }
+
+func TestSyncOnce_Metrics(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	success, failure := syncRuns.Value(syncSuccess), syncRuns.Value(syncFailure)
+
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("echo", "Sync completed")
+	}
+	SyncOnce()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		return exec.Command("false")
+	}
+	SyncOnce()
+
+	// The ticker of an earlier test may still finish a run, so count at least
+	if syncRuns.Value(syncSuccess)-success < 1 || syncRuns.Value(syncFailure)-failure < 1 {
+		t.Errorf("expected one successful and one failed sync run, got %v and %v", syncRuns.Value(syncSuccess)-success, syncRuns.Value(syncFailure)-failure)
+	}
+	if lastSyncSuccess.Value() == 0 {
+		t.Error("expected the time of the last successful sync")
+	}
+}
@@ -0,0 +1,153 @@
+package taskwarrior
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"task-herald/internal/util"
+)
+
+// HasTag reports whether the task has the given tag
+func (t *Task) HasTag(tag string) bool {
+	for _, tt := range t.Tags {
+		if tt == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Attribute returns a task attribute by its Taskwarrior name as a string,
+// falling back to UDAs. The boolean reports whether the attribute is set.
+func (t *Task) Attribute(name string) (string, bool) {
+	switch strings.ToLower(name) {
+	case "id":
+		return strconv.Itoa(t.ID), t.ID != 0
+	case "uuid":
+		return t.UUID, t.UUID != ""
+	case "description":
+		return t.Description, t.Description != ""
+	case "project":
+		return t.Project, t.Project != ""
+	case "priority":
+		return t.Priority, t.Priority != ""
+	case "status":
+		return t.Status, t.Status != ""
+	case "notification_date":
+		return t.NotificationDate, t.NotificationDate != ""
+	case "due":
+		return t.Due, t.Due != ""
+	case "scheduled":
+		return t.Scheduled, t.Scheduled != ""
+	case "wait":
+		return t.Wait, t.Wait != ""
+	case "until":
+		return t.Until, t.Until != ""
+	case "entry":
+		return t.Entry, t.Entry != ""
+	case "modified":
+		return t.Modified, t.Modified != ""
+	case "start":
+		return t.Start, t.Start != ""
+	case "end":
+		return t.End, t.End != ""
+	case "recur":
+		return t.Recur, t.Recur != ""
+	case "parent":
+		return t.Parent, t.Parent != ""
+	case "urgency":
+		return strconv.FormatFloat(t.Urgency, 'f', -1, 64), true
+	case "depends":
+		return strings.Join(t.Depends, ","), len(t.Depends) > 0
+	case "tags":
+		return strings.Join(t.Tags, ","), len(t.Tags) > 0
+	}
+	return t.UDAString(name)
+}
+
+// UDA returns the raw decoded value of a UDA
+func (t *Task) UDA(name string) (interface{}, bool) {
+	v, ok := t.UDAs[name]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return v, true
+}
+
+// UDAString returns a UDA formatted as a string; numbers use their shortest
+// representation. Empty strings count as unset.
+func (t *Task) UDAString(name string) (string, bool) {
+	v, ok := t.UDA(name)
+	if !ok {
+		return "", false
+	}
+	switch val := v.(type) {
+	case string:
+		return val, val != ""
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), true
+	default:
+		return fmt.Sprint(val), true
+	}
+}
+
+// UDANumber returns a numeric UDA; numeric strings are accepted
+func (t *Task) UDANumber(name string) (float64, bool) {
+	v, ok := t.UDA(name)
+	if !ok {
+		return 0, false
+	}
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// UDADate returns a date UDA (Taskwarrior exports dates as 20060102T150405Z)
+func (t *Task) UDADate(name string) (time.Time, bool) {
+	s, ok := t.UDAString(name)
+	if !ok {
+		return time.Time{}, false
+	}
+	d, err := util.ParseNotificationDate(s)
+	return d, err == nil
+}
+
+// UDADuration returns a duration UDA (Taskwarrior exports durations in ISO
+// 8601 form, e.g. PT10M); numbers are taken as seconds
+func (t *Task) UDADuration(name string) (time.Duration, bool) {
+	v, ok := t.UDA(name)
+	if !ok {
+		return 0, false
+	}
+	if f, isNum := v.(float64); isNum {
+		return time.Duration(f * float64(time.Second)), true
+	}
+	s, ok := t.UDAString(name)
+	if !ok {
+		return 0, false
+	}
+	d, err := util.ParseDuration(s)
+	return d, err == nil
+}
+
+// UDABool returns a UDA used as a flag: true/yes/on/1 or false/no/off/0
+func (t *Task) UDABool(name string) (bool, bool) {
+	s, ok := t.UDAString(name)
+	if !ok {
+		return false, false
+	}
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "yes", "on", "1", "y":
+		return true, true
+	case "false", "no", "off", "0", "n":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,90 @@
+package taskwarrior
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const exportedTask = `{"id":4,"uuid":"abc","description":"review","status":"pending","entry":"20250801T100000Z",
+"modified":"20250802T100000Z","urgency":7.5,"depends":"d1,d2","annotations":[{"entry":"20250801T100000Z","description":"see PR"}],
+"mask":"--","estimate":3,"reviewer":"sam","followup":"20250905T120000Z","nag":"PT45M","notification_repeat_enable":"yes"}`
+
+func TestTask_UnmarshalJSON_FullExport(t *testing.T) {
+	var task Task
+	if err := json.Unmarshal([]byte(exportedTask), &task); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if task.Entry != "20250801T100000Z" || task.Modified != "20250802T100000Z" || task.Urgency != 7.5 {
+		t.Fatalf("known fields not decoded: %+v", task)
+	}
+	if len(task.Depends) != 2 || task.Depends[1] != "d2" {
+		t.Fatalf("comma-separated depends not decoded: %v", task.Depends)
+	}
+	if len(task.Annotations) != 1 || task.Annotations[0].Description != "see PR" {
+		t.Fatalf("annotations not decoded: %+v", task.Annotations)
+	}
+	if len(task.UDAs) != 5 {
+		t.Fatalf("expected only the 5 UDAs collected, got %v", task.UDAs)
+	}
+}
+
+func TestStringList_Array(t *testing.T) {
+	var l StringList
+	if err := json.Unmarshal([]byte(`["a","b"]`), &l); err != nil || len(l) != 2 {
+		t.Fatalf("got %v %v", l, err)
+	}
+	if err := json.Unmarshal([]byte(`3`), &l); err == nil {
+		t.Fatal("expected error for a number")
+	}
+}
+
+func TestTask_UDAAccessors(t *testing.T) {
+	var task Task
+	if err := json.Unmarshal([]byte(exportedTask), &task); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if v, ok := task.UDAString("reviewer"); !ok || v != "sam" {
+		t.Errorf("UDAString: %q %v", v, ok)
+	}
+	if v, ok := task.UDAString("estimate"); !ok || v != "3" {
+		t.Errorf("UDAString of a number: %q %v", v, ok)
+	}
+	if v, ok := task.UDANumber("estimate"); !ok || v != 3 {
+		t.Errorf("UDANumber: %v %v", v, ok)
+	}
+	if _, ok := task.UDANumber("reviewer"); ok {
+		t.Error("UDANumber of a non-numeric string should fail")
+	}
+	if v, ok := task.UDADate("followup"); !ok || !v.Equal(time.Date(2025, 9, 5, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("UDADate: %v %v", v, ok)
+	}
+	if v, ok := task.UDADuration("nag"); !ok || v != 45*time.Minute {
+		t.Errorf("UDADuration: %v %v", v, ok)
+	}
+	if v, ok := task.UDADuration("estimate"); !ok || v != 3*time.Second {
+		t.Errorf("UDADuration of a number: %v %v", v, ok)
+	}
+	if v, ok := task.UDABool("notification_repeat_enable"); !ok || !v {
+		t.Errorf("UDABool: %v %v", v, ok)
+	}
+	if _, ok := task.UDABool("reviewer"); ok {
+		t.Error("UDABool of a non-flag should fail")
+	}
+	if _, ok := task.UDA("missing"); ok {
+		t.Error("missing UDA reported as set")
+	}
+}
+
+func TestTask_Attribute(t *testing.T) {
+	task := Task{ID: 2, Project: "p", Urgency: 1.5, Depends: StringList{"a", "b"}, UDAs: map[string]interface{}{"size": "L"}}
+	cases := map[string]string{"id": "2", "project": "p", "urgency": "1.5", "depends": "a,b", "size": "L"}
+	for name, want := range cases {
+		if got, ok := task.Attribute(name); !ok || got != want {
+			t.Errorf("Attribute(%q) = %q %v, want %q", name, got, ok, want)
+		}
+	}
+	if _, ok := task.Attribute("due"); ok {
+		t.Error("unset due reported as set")
+	}
+}
@@ -0,0 +1,202 @@
+package taskwarrior
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"task-herald/internal/sqlite"
+)
+
+// taskChampionFile is the database file name in the Taskwarrior 3 data directory
+const taskChampionFile = "taskchampion.sqlite3"
+
+// TaskChampionSource reads tasks directly from a Taskwarrior 3
+// (TaskChampion) SQLite replica, read-only, without running task. While the
+// replica has uncheckpointed WAL content, which is not read, tasks come from
+// Fallback if it is set.
+type TaskChampionSource struct {
+	Path     string
+	DateUDAs []string // UDAs converted from epoch seconds to Taskwarrior dates
+	Fallback TaskSource
+	now      func() time.Time
+	onWAL    atomic.Bool // Fallback is in use, the switch was logged
+}
+
+// TaskChampionPath resolves the replica path: path itself, path/taskchampion.sqlite3
+// if path is a directory, or taskchampion.sqlite3 in $TASKDATA or ~/.task
+func TaskChampionPath(path string) string {
+	if path == "" {
+		dir := os.Getenv("TASKDATA")
+		if dir == "" {
+			home, _ := os.UserHomeDir()
+			dir = filepath.Join(home, ".task")
+		}
+		return filepath.Join(dir, taskChampionFile)
+	}
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return filepath.Join(path, taskChampionFile)
+	}
+	return path
+}
+
+// Tasks returns the pending tasks of the replica; pending tasks with a wait
+// date in the future are reported as waiting, like task export does.
+// Urgency is not computed.
+func (s *TaskChampionSource) Tasks() ([]Task, error) {
+	db, err := sqlite.Open(s.Path)
+	if errors.Is(err, sqlite.ErrWAL) && s.Fallback != nil {
+		if !s.onWAL.Swap(true) {
+			pollLog.Warn("TaskChampion database has uncheckpointed WAL content; using task export until it is checkpointed", "path", s.Path)
+		}
+		return s.Fallback.Tasks()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.onWAL.Swap(false) {
+		pollLog.Info("TaskChampion WAL content was checkpointed; reading the database again", "path", s.Path)
+	}
+	ids := map[string]int{}
+	if db.HasTable("working_set") {
+		err := db.Scan("working_set", func(rowid int64, row []interface{}) error {
+			if len(row) >= 2 {
+				if uuid, ok := row[1].(string); ok {
+					ids[uuid] = int(rowid)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	dateUDAs := map[string]bool{}
+	for _, name := range s.DateUDAs {
+		dateUDAs[name] = true
+	}
+	var tasks []Task
+	err = db.Scan("tasks", func(_ int64, row []interface{}) error {
+		if len(row) < 2 {
+			return nil
+		}
+		uuid, _ := row[0].(string)
+		data, _ := row[1].(string)
+		var props map[string]string
+		if err := json.Unmarshal([]byte(data), &props); err != nil {
+			parseFailures.Inc("taskchampion")
+			pollLog.Warn("Skipping task with invalid data", "task_uuid", uuid, "error", err)
+			return nil
+		}
+		if props["status"] != "pending" {
+			return nil
+		}
+		tasks = append(tasks, taskFromProps(uuid, ids[uuid], props, dateUDAs, now()))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", s.Path, err)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if (tasks[i].ID == 0) != (tasks[j].ID == 0) {
+			return tasks[i].ID != 0
+		}
+		if tasks[i].ID != tasks[j].ID {
+			return tasks[i].ID < tasks[j].ID
+		}
+		return tasks[i].UUID < tasks[j].UUID
+	})
+	pollLog.Debug("Read pending tasks from TaskChampion", "count", len(tasks), "path", s.Path)
+	return tasks, nil
+}
+
+// taskFromProps converts TaskChampion task properties to a Task
+func taskFromProps(uuid string, id int, props map[string]string, dateUDAs map[string]bool, now time.Time) Task {
+	t := Task{ID: id, UUID: uuid, Status: props["status"]}
+	var annotations []string
+	for key, value := range props {
+		switch {
+		case strings.HasPrefix(key, "tag_"):
+			t.Tags = append(t.Tags, key[len("tag_"):])
+		case strings.HasPrefix(key, "dep_"):
+			t.Depends = append(t.Depends, key[len("dep_"):])
+		case strings.HasPrefix(key, "annotation_"):
+			annotations = append(annotations, key)
+		}
+		switch key {
+		case "status", "mask", "imask":
+		case "description":
+			t.Description = value
+		case "project":
+			t.Project = value
+		case "priority":
+			t.Priority = value
+		case "recur":
+			t.Recur = value
+		case "parent":
+			t.Parent = value
+		case "due":
+			t.Due = epochDate(value)
+		case "scheduled":
+			t.Scheduled = epochDate(value)
+		case "wait":
+			t.Wait = epochDate(value)
+		case "until":
+			t.Until = epochDate(value)
+		case "entry":
+			t.Entry = epochDate(value)
+		case "modified":
+			t.Modified = epochDate(value)
+		case "start":
+			t.Start = epochDate(value)
+		case "end":
+			t.End = epochDate(value)
+		case "notification_date":
+			t.NotificationDate = epochDate(value)
+		default:
+			if strings.HasPrefix(key, "tag_") || strings.HasPrefix(key, "dep_") || strings.HasPrefix(key, "annotation_") {
+				continue
+			}
+			if t.UDAs == nil {
+				t.UDAs = map[string]interface{}{}
+			}
+			if dateUDAs[key] {
+				value = epochDate(value)
+			}
+			t.UDAs[key] = value
+		}
+	}
+	sort.Strings(t.Tags)
+	sort.Strings(t.Depends)
+	sort.Strings(annotations)
+	for _, key := range annotations {
+		t.Annotations = append(t.Annotations, Annotation{Entry: epochDate(key[len("annotation_"):]), Description: props[key]})
+	}
+	if t.Wait != "" {
+		if wait, err := time.Parse("20060102T150405Z", t.Wait); err == nil && wait.After(now) {
+			t.Status = "waiting"
+		}
+	}
+	return t
+}
+
+// epochDate converts TaskChampion's epoch seconds to Taskwarrior's export
+// date format; other values are returned unchanged
+func epochDate(v string) string {
+	secs, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return v
+	}
+	return time.Unix(secs, 0).UTC().Format("20060102T150405Z")
+}
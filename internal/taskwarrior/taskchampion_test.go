@@ -0,0 +1,138 @@
+package taskwarrior
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"task-herald/internal/config"
+)
+
+func fixtureSource() *TaskChampionSource {
+	return &TaskChampionSource{
+		Path:     filepath.Join("testdata", "taskchampion.sqlite3"),
+		DateUDAs: []string{"followup"},
+		now:      func() time.Time { return time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC) },
+	}
+}
+
+func TestTaskChampionSource_Tasks(t *testing.T) {
+	tasks, err := fixtureSource().Tasks()
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("expected 3 pending tasks, got %d: %+v", len(tasks), tasks)
+	}
+	// ordered by working set id, tasks outside the working set last
+	if tasks[0].ID != 1 || tasks[1].ID != 2 || tasks[2].ID != 0 {
+		t.Fatalf("unexpected order: %d %d %d", tasks[0].ID, tasks[1].ID, tasks[2].ID)
+	}
+	if tasks[0].Status != "waiting" || tasks[2].Status != "pending" {
+		t.Errorf("expected future wait to be waiting and past wait pending, got %s and %s", tasks[0].Status, tasks[2].Status)
+	}
+
+	rent := tasks[1]
+	if rent.UUID != "11111111-1111-4111-8111-111111111111" || rent.Description != "Pay rent" || rent.Project != "home.bills" || rent.Priority != "H" {
+		t.Fatalf("unexpected task: %+v", rent)
+	}
+	if rent.Due != "20250901T090000Z" || rent.NotificationDate != "20250901T070000Z" || rent.Entry != "20250824T014640Z" {
+		t.Errorf("dates not converted: due %s notification %s entry %s", rent.Due, rent.NotificationDate, rent.Entry)
+	}
+	if len(rent.Tags) != 2 || rent.Tags[0] != "household" || rent.Tags[1] != "money" {
+		t.Errorf("unexpected tags: %v", rent.Tags)
+	}
+	if len(rent.Depends) != 1 || rent.Depends[0] != "33333333-3333-4333-8333-333333333333" {
+		t.Errorf("unexpected depends: %v", rent.Depends)
+	}
+	if len(rent.Annotations) != 1 || rent.Annotations[0].Description != "landlord changed IBAN" || rent.Annotations[0].Entry != "20250824T014730Z" {
+		t.Errorf("unexpected annotations: %+v", rent.Annotations)
+	}
+	if v, _ := rent.UDAString("reviewer"); v != "sam" {
+		t.Errorf("unexpected reviewer UDA: %v", rent.UDAs)
+	}
+	if v, ok := rent.UDANumber("estimate"); !ok || v != 3 {
+		t.Errorf("unexpected estimate UDA: %v", rent.UDAs)
+	}
+	if v, ok := rent.UDADate("followup"); !ok || !v.Equal(time.Unix(1756800000, 0)) {
+		t.Errorf("date UDA not converted: %v", rent.UDAs["followup"])
+	}
+}
+
+func TestTaskChampionPath(t *testing.T) {
+	dir := t.TempDir()
+	if got := TaskChampionPath(dir); got != filepath.Join(dir, "taskchampion.sqlite3") {
+		t.Errorf("directory: got %s", got)
+	}
+	file := filepath.Join(dir, "replica.db")
+	if got := TaskChampionPath(file); got != file {
+		t.Errorf("file: got %s", got)
+	}
+	t.Setenv("TASKDATA", dir)
+	if got := TaskChampionPath(""); got != filepath.Join(dir, "taskchampion.sqlite3") {
+		t.Errorf("TASKDATA: got %s", got)
+	}
+}
+
+func TestTaskChampionSource_MissingDatabase(t *testing.T) {
+	src := &TaskChampionSource{Path: filepath.Join(t.TempDir(), "missing.sqlite3")}
+	if _, err := src.Tasks(); !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
+
+// staticSource returns fixed tasks and counts the calls
+type staticSource struct {
+	tasks []Task
+	calls int
+}
+
+func (s *staticSource) Tasks() ([]Task, error) {
+	s.calls++
+	return s.tasks, nil
+}
+
+func TestTaskChampionSource_WALFallback(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "taskchampion.sqlite3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "taskchampion.sqlite3")
+	os.WriteFile(path, data, 0o644)
+	os.WriteFile(path+"-wal", []byte("pending frames"), 0o644)
+	fallback := &staticSource{tasks: []Task{{UUID: "from-export"}}}
+	src := fixtureSource()
+	src.Path, src.Fallback = path, fallback
+
+	for i := 0; i < 2; i++ {
+		tasks, err := src.Tasks()
+		if err != nil || len(tasks) != 1 || tasks[0].UUID != "from-export" {
+			t.Fatalf("expected the fallback tasks, got %+v %v", tasks, err)
+		}
+	}
+	// Once checkpointed the database is read again
+	os.WriteFile(path+"-wal", nil, 0o644)
+	tasks, err := src.Tasks()
+	if err != nil || len(tasks) != 3 || fallback.calls != 2 {
+		t.Fatalf("expected 3 tasks from the database, got %d %v (fallback calls %d)", len(tasks), err, fallback.calls)
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	if src, err := NewSource(config.SourceConfig{}, nil); err != nil {
+		t.Fatalf("default source: %v", err)
+	} else if _, ok := src.(CLISource); !ok {
+		t.Errorf("expected CLISource by default, got %T", src)
+	}
+	src, err := NewSource(config.SourceConfig{Type: "taskchampion", Path: "/data/tc.sqlite3"}, []string{"notification_date"})
+	if err != nil {
+		t.Fatalf("taskchampion source: %v", err)
+	}
+	if tc, ok := src.(*TaskChampionSource); !ok || tc.Path != "/data/tc.sqlite3" {
+		t.Errorf("unexpected source: %+v", src)
+	}
+	if _, err := NewSource(config.SourceConfig{Type: "carrier-pigeon"}, nil); err == nil {
+		t.Error("expected error for an unknown source")
+	}
+}
@@ -0,0 +1,47 @@
+# Regenerates taskchampion.sqlite3, a Taskwarrior 3 replica fixture:
+#   cd internal/taskwarrior/testdata && rm -f taskchampion.sqlite3 && python3 gen_taskchampion.py
+import json
+import sqlite3
+
+c = sqlite3.connect('taskchampion.sqlite3')
+c.executescript('''
+CREATE TABLE operations (id INTEGER PRIMARY KEY AUTOINCREMENT, data STRING);
+CREATE TABLE sync_meta (key STRING PRIMARY KEY, value STRING);
+CREATE TABLE tasks (uuid STRING PRIMARY KEY, data STRING);
+CREATE TABLE working_set (id INTEGER PRIMARY KEY, uuid STRING);
+''')
+tasks = {
+    '11111111-1111-4111-8111-111111111111': {
+        'status': 'pending', 'description': 'Pay rent', 'project': 'home.bills',
+        'priority': 'H', 'entry': '1756000000', 'modified': '1756000100',
+        'due': '1756717200', 'notification_date': '1756710000',
+        'tag_money': '', 'tag_household': '',
+        'annotation_1756000050': 'landlord changed IBAN',
+        'dep_33333333-3333-4333-8333-333333333333': '',
+        'estimate': '3', 'reviewer': 'sam', 'followup': '1756800000',
+    },
+    '22222222-2222-4222-8222-222222222222': {
+        'status': 'pending', 'description': 'Renew passport', 'entry': '1756000000',
+        'wait': '4102444800',  # 2100-01-01, still waiting
+    },
+    '33333333-3333-4333-8333-333333333333': {
+        'status': 'completed', 'description': 'Done already', 'end': '1756000200',
+    },
+    '44444444-4444-4444-8444-444444444444': {
+        'status': 'deleted', 'description': 'Deleted',
+    },
+    '55555555-5555-4555-8555-555555555555': {
+        'status': 'recurring', 'description': 'Recurring template', 'recur': 'weekly',
+        'mask': '--',
+    },
+    '66666666-6666-4666-8666-666666666666': {
+        'status': 'pending', 'description': 'Not in the working set', 'wait': '1000000000',
+    },
+}
+for uuid, data in tasks.items():
+    c.execute('INSERT INTO tasks VALUES (?, ?)', (uuid, json.dumps(data)))
+c.execute('INSERT INTO working_set VALUES (1, ?)', ('22222222-2222-4222-8222-222222222222',))
+c.execute('INSERT INTO working_set VALUES (2, ?)', ('11111111-1111-4111-8111-111111111111',))
+c.execute('INSERT INTO operations (data) VALUES (?)', ('{"UndoPoint":null}',))
+c.commit()
+c.close()
@@ -0,0 +1,238 @@
+package taskwarrior
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"task-herald/internal/config"
+	"task-herald/internal/util"
+)
+
+// Change collects the modifications the transforms of a pipeline make to a
+// task. Transforms consume words of the description and record what they
+// mean; the pipeline turns the result into one task modify.
+type Change struct {
+	Task    Task
+	Words   []string          // remaining description words
+	Tags    []string          // tags to add
+	Project string            // project to set
+	Attrs   map[string]string // other attributes to set, e.g. a date UDA
+	Now     time.Time         // relative dates are resolved against Now
+}
+
+// placeholderDescription replaces a description made only of shorthands;
+// Taskwarrior does not allow an empty one
+const placeholderDescription = "untitled"
+
+// Transform rewrites part of a task description into task attributes
+type Transform func(c *Change)
+
+var (
+	// shorthandTagPattern matches a whole word like +errand; words such as
+	// C++ or +1 are left alone
+	shorthandTagPattern = regexp.MustCompile(`^\+([a-zA-Z][a-zA-Z0-9_\-]*)$`)
+	// projectPattern matches a whole word like @home or @work.reports
+	projectPattern = regexp.MustCompile(`^@([a-zA-Z][a-zA-Z0-9_\-]*(?:\.[a-zA-Z0-9_\-]+)*)$`)
+	// dateShorthandPattern matches a whole word like !tomorrow or !2025-09-01T09:00
+	dateShorthandPattern = regexp.MustCompile(`^!([a-zA-Z0-9][a-zA-Z0-9:+\-]*)$`)
+)
+
+// TagTransform turns +tag words into tags. Dashed tags are converted to
+// camelCase, since Taskwarrior tags cannot contain dashes.
+func TagTransform(c *Change) {
+	c.Words = consume(c.Words, func(w string) bool {
+		m := shorthandTagPattern.FindStringSubmatch(w)
+		if m == nil {
+			return false
+		}
+		c.Tags = append(c.Tags, DashToCamel(m[1]))
+		return true
+	})
+}
+
+// ProjectTransform turns an @project word into the task project. Tasks that
+// already have a project are left alone.
+func ProjectTransform(c *Change) {
+	if c.Task.Project != "" {
+		return
+	}
+	c.Words = consume(c.Words, func(w string) bool {
+		m := projectPattern.FindStringSubmatch(w)
+		if m == nil || c.Project != "" {
+			return false
+		}
+		c.Project = m[1]
+		return true
+	})
+}
+
+// DateTransform returns a transform that turns a !date word, e.g. !tomorrow,
+// !eow, !friday+9h or !2025-09-01T09:00 (see util.ParseDate), into the given
+// date UDA. The date is resolved in the configured timezone and stored in UTC.
+func DateTransform(uda string) Transform {
+	return func(c *Change) {
+		c.Words = consume(c.Words, func(w string) bool {
+			m := dateShorthandPattern.FindStringSubmatch(w)
+			if m == nil {
+				return false
+			}
+			at, err := util.ParseDate(m[1], c.Now, util.Location())
+			if err != nil {
+				return false
+			}
+			if c.Attrs == nil {
+				c.Attrs = map[string]string{}
+			}
+			c.Attrs[uda] = at.UTC().Format("20060102T150405Z")
+			return true
+		})
+	}
+}
+
+// consume removes the words for which take returns true
+func consume(words []string, take func(string) bool) []string {
+	out := words[:0]
+	for _, w := range words {
+		if !take(w) {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Args returns the task modify arguments for the change, or nil if the
+// transforms did not change anything
+func (c *Change) Args() []string {
+	var args []string
+	for _, tag := range c.Tags {
+		if !c.Task.HasTag(tag) {
+			args = append(args, "+"+tag)
+		}
+	}
+	if c.Project != "" {
+		args = append(args, "project:"+c.Project)
+	}
+	names := make([]string, 0, len(c.Attrs))
+	for name := range c.Attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, name+":"+c.Attrs[name])
+	}
+	desc := strings.Join(c.Words, " ")
+	if len(args) == 0 && desc == strings.Join(strings.Fields(c.Task.Description), " ") {
+		return nil
+	}
+	// Shorthands are always removed, so the next poll does not apply them
+	// again; relative dates would otherwise move on every poll
+	if desc == "" {
+		desc = placeholderDescription
+	}
+	if desc != c.Task.Description {
+		args = append(args, "description:"+desc)
+	}
+	return args
+}
+
+// Pipeline applies transforms to polled tasks and writes the result back
+// with one task modify per task
+type Pipeline struct {
+	names      []string
+	transforms []Transform
+	dryRun     bool
+	logged     map[string]string // uuid -> last change logged in dry-run mode
+	now        func() time.Time
+	// modify runs task modify; it is ModifyTask except in tests
+	modify func(uuid string, args ...string) bool
+}
+
+// NewPipeline builds the pipeline configured in cfg, or returns nil if no
+// transforms are enabled. dateUDA is the UDA set by the dates transform.
+func NewPipeline(cfg config.TransformConfig, dateUDA string) (*Pipeline, error) {
+	if len(cfg.Steps) == 0 {
+		return nil, nil
+	}
+	p := &Pipeline{dryRun: cfg.DryRun, logged: map[string]string{}, now: time.Now, modify: ModifyTask}
+	for _, name := range cfg.Steps {
+		var t Transform
+		switch name {
+		case "tags":
+			t = TagTransform
+		case "project":
+			t = ProjectTransform
+		case "dates":
+			t = DateTransform(dateUDA)
+		default:
+			return nil, fmt.Errorf("unknown transform %q (want tags, project or dates)", name)
+		}
+		p.names = append(p.names, name)
+		p.transforms = append(p.transforms, t)
+	}
+	return p, nil
+}
+
+// String describes the pipeline for logging
+func (p *Pipeline) String() string {
+	s := strings.Join(p.names, ", ")
+	if p.dryRun {
+		s += " (dry run)"
+	}
+	return s
+}
+
+// Change runs the transforms over a task
+func (p *Pipeline) Change(task Task) *Change {
+	c := &Change{Task: task, Words: strings.Fields(task.Description), Now: p.now()}
+	for _, t := range p.transforms {
+		t(c)
+	}
+	return c
+}
+
+// Apply transforms each task and modifies the tasks that changed. In dry-run
+// mode the modifications are only logged, once per distinct change.
+func (p *Pipeline) Apply(tasks []Task) {
+	for _, task := range tasks {
+		if task.UUID == "" {
+			continue
+		}
+		args := p.Change(task).Args()
+		if len(args) == 0 {
+			delete(p.logged, task.UUID)
+			continue
+		}
+		cmd := "task " + task.UUID + " modify " + strings.Join(args, " ")
+		if p.dryRun {
+			if p.logged[task.UUID] != cmd {
+				pollLog.Info("Transform dry run", "task_uuid", task.UUID, "command", cmd)
+				p.logged[task.UUID] = cmd
+			}
+			continue
+		}
+		pollLog.Info("Transforming task", "task_uuid", task.UUID, "modifications", strings.Join(args, " "))
+		if !p.modify(task.UUID, args...) {
+			pollLog.Warn("Could not transform task", "task_uuid", task.UUID)
+		}
+	}
+}
+
+// TransformSource applies a transform pipeline to the tasks of another source
+type TransformSource struct {
+	TaskSource
+	Pipeline *Pipeline
+}
+
+// Tasks returns the tasks of the wrapped source after applying the pipeline.
+// The returned tasks are unchanged; modified tasks are picked up on the next poll.
+func (s TransformSource) Tasks() ([]Task, error) {
+	tasks, err := s.TaskSource.Tasks()
+	if err != nil {
+		return nil, err
+	}
+	s.Pipeline.Apply(tasks)
+	return tasks, nil
+}
@@ -0,0 +1,170 @@
+package taskwarrior
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"task-herald/internal/config"
+	"task-herald/internal/util"
+	"testing"
+	"time"
+)
+
+func TestPipeline_ChangeArgs(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	p, err := NewPipeline(config.TransformConfig{Steps: []string{"tags", "project", "dates"}}, "notification_date")
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+	// Wednesday
+	p.now = func() time.Time { return time.Date(2025, 9, 3, 14, 30, 0, 0, time.UTC) }
+	tests := []struct {
+		name string
+		task Task
+		want string
+	}{
+		{"no shorthands", Task{Description: "Buy milk"}, ""},
+		{"whitespace only", Task{Description: "Buy  milk "}, ""},
+		{"C++ and +1 untouched", Task{Description: "Learn C++ and +1 the review"}, ""},
+		{"tags", Task{Description: "Buy milk +errand +grocery-run"}, "+errand +groceryRun description:Buy milk"},
+		{"existing tag", Task{Description: "Buy milk +errand", Tags: []string{"errand"}}, "description:Buy milk"},
+		{"project", Task{Description: "Fix sink @home.repairs"}, "project:home.repairs description:Fix sink"},
+		{"project already set", Task{Description: "Email bob@example.com @home", Project: "work"}, ""},
+		{"date", Task{Description: "Call mom !tomorrow+9h"}, "notification_date:20250904T090000Z description:Call mom"},
+		{"iso date", Task{Description: "Renew !2025-09-10T09:00"}, "notification_date:20250910T090000Z description:Renew"},
+		{"not a date", Task{Description: "This is !important !1"}, ""},
+		{"duration", Task{Description: "Stretch !PT30M"}, "notification_date:20250903T150000Z description:Stretch"},
+		{"all", Task{Description: "Pay rent @home +bills !eom"}, "+bills project:home notification_date:20250930T235959Z description:Pay rent"},
+		{"only shorthands", Task{Description: "+bills"}, "+bills description:untitled"},
+		{"only a date", Task{Description: "!now+1h"}, "notification_date:20250903T153000Z description:untitled"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := strings.Join(p.Change(tc.task).Args(), " ")
+			if got != tc.want {
+				t.Errorf("args for %q:\n got: %s\nwant: %s", tc.task.Description, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPipeline_SecondPassNoChange(t *testing.T) {
+	util.SetLocation(time.UTC)
+	defer util.SetLocation(nil)
+	p, err := NewPipeline(config.TransformConfig{Steps: []string{"tags", "project", "dates"}}, "notification_date")
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+	now := time.Date(2025, 9, 3, 14, 30, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	for _, desc := range []string{"!now+1h", "+bills @home", "Call mom !tomorrow+9h"} {
+		task := Task{UUID: "u1", Description: desc}
+		var calls [][]string
+		p.modify = func(uuid string, args ...string) bool {
+			calls = append(calls, args)
+			// Apply the modification like task modify would
+			for _, arg := range args {
+				switch {
+				case strings.HasPrefix(arg, "+"):
+					task.Tags = append(task.Tags, arg[1:])
+				case strings.HasPrefix(arg, "project:"):
+					task.Project = strings.TrimPrefix(arg, "project:")
+				case strings.HasPrefix(arg, "description:"):
+					task.Description = strings.TrimPrefix(arg, "description:")
+				case strings.HasPrefix(arg, "notification_date:"):
+					task.NotificationDate = strings.TrimPrefix(arg, "notification_date:")
+				}
+			}
+			return true
+		}
+		p.Apply([]Task{task})
+		// The next poll resolves relative dates against a later time
+		now = now.Add(5 * time.Minute)
+		p.Apply([]Task{task})
+		if len(calls) != 1 {
+			t.Errorf("%q: expected one modify, got %v", desc, calls)
+		}
+	}
+}
+
+func TestPipeline_OnlyConfiguredSteps(t *testing.T) {
+	p, err := NewPipeline(config.TransformConfig{Steps: []string{"project"}}, "notification_date")
+	if err != nil {
+		t.Fatalf("NewPipeline: %v", err)
+	}
+	got := strings.Join(p.Change(Task{Description: "Pay rent @home +bills !eom"}).Args(), " ")
+	if want := "project:home description:Pay rent +bills !eom"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNewPipeline(t *testing.T) {
+	p, err := NewPipeline(config.TransformConfig{}, "notification_date")
+	if err != nil || p != nil {
+		t.Fatalf("expected no pipeline by default, got %v, %v", p, err)
+	}
+	if _, err := NewPipeline(config.TransformConfig{Steps: []string{"tags", "spelling"}}, "notification_date"); err == nil {
+		t.Fatal("expected error for unknown transform")
+	}
+}
+
+func TestPipeline_ApplyBatchesModify(t *testing.T) {
+	p, _ := NewPipeline(config.TransformConfig{Steps: []string{"tags", "project"}}, "notification_date")
+	var calls [][]string
+	p.modify = func(uuid string, args ...string) bool {
+		calls = append(calls, append([]string{uuid}, args...))
+		return true
+	}
+	p.Apply([]Task{
+		{UUID: "a", Description: "Pay rent @home +bills +urgent"},
+		{UUID: "b", Description: "Nothing to do"},
+	})
+	if len(calls) != 1 {
+		t.Fatalf("expected one modify, got %v", calls)
+	}
+	if got := strings.Join(calls[0], " "); got != "a +bills +urgent project:home description:Pay rent" {
+		t.Errorf("unexpected modify: %s", got)
+	}
+}
+
+func TestPipeline_DryRun(t *testing.T) {
+	origExec := execCommand
+	defer func() { execCommand = origExec }()
+	execCommand = func(name string, args ...string) *exec.Cmd {
+		t.Fatalf("dry run ran task %v", args)
+		return nil
+	}
+	p, _ := NewPipeline(config.TransformConfig{Steps: []string{"tags"}, DryRun: true}, "notification_date")
+	tasks := []Task{{UUID: "a", Description: "Pay rent +bills"}}
+	p.Apply(tasks)
+	p.Apply(tasks)
+	if len(p.logged) != 1 {
+		t.Errorf("expected the dry-run change to be remembered, got %v", p.logged)
+	}
+}
+
+func TestTransformSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	data := `[{"uuid":"a","description":"Pay rent +bills","status":"pending"},{"uuid":"b","description":"Plain","status":"pending"}]`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p, _ := NewPipeline(config.TransformConfig{Steps: []string{"tags"}}, "notification_date")
+	var modified []string
+	p.modify = func(uuid string, args ...string) bool {
+		modified = append(modified, uuid)
+		return true
+	}
+	tasks, err := TransformSource{TaskSource: FileSource{Path: path}, Pipeline: p}.Tasks()
+	if err != nil {
+		t.Fatalf("Tasks: %v", err)
+	}
+	if len(tasks) != 2 || tasks[0].Description != "Pay rent +bills" {
+		t.Fatalf("expected the source tasks unchanged, got %+v", tasks)
+	}
+	if len(modified) != 1 || modified[0] != "a" {
+		t.Errorf("expected task a to be modified, got %v", modified)
+	}
+}
@@ -0,0 +1,183 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression with the five standard fields
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields accept *, numbers, ranges (1-5), lists (1,15) and steps (*/15,
+// 8-18/2); months and weekdays also accept names (jan, mon). Day of week 0
+// and 7 are Sunday. If both day fields are restricted, a day matching
+// either runs, as in cron. @hourly, @daily, @weekly, @monthly and @yearly
+// are accepted as well.
+type Schedule struct {
+	expr                         string
+	minute, hour, dom, month     uint64 // bit n set if value n matches
+	dow                          uint64
+	domRestricted, dowRestricted bool
+}
+
+var scheduleMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+var cronMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var cronWeekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// ParseSchedule parses a cron expression such as "0 8 * * mon-fri"
+func ParseSchedule(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if m, ok := scheduleMacros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: want 5 fields (minute hour day month weekday)", expr)
+	}
+	s := &Schedule{expr: expr}
+	var err error
+	for _, f := range []struct {
+		bits     *uint64
+		field    string
+		min, max int
+		names    map[string]int
+	}{
+		{&s.minute, fields[0], 0, 59, nil},
+		{&s.hour, fields[1], 0, 23, nil},
+		{&s.dom, fields[2], 1, 31, nil},
+		{&s.month, fields[3], 1, 12, cronMonths},
+		{&s.dow, fields[4], 0, 7, cronWeekdays},
+	} {
+		if *f.bits, err = parseCronField(f.field, f.min, f.max, f.names); err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %v", expr, err)
+		}
+	}
+	// 7 is Sunday too
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domRestricted = !strings.HasPrefix(fields[2], "*")
+	s.dowRestricted = !strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseCronField parses one comma-separated field into a bit set
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := min, max
+		if rng != "*" {
+			var err error
+			bounds := strings.SplitN(rng, "-", 2)
+			if lo, err = cronValue(bounds[0], min, max, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], min, max, names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// 5/15 means 5-max/15
+				hi = max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses a number or name within [min, max]
+func cronValue(s string, min, max int, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < min || v > max {
+		return 0, fmt.Errorf("invalid value %q (want %d-%d)", s, min, max)
+	}
+	return v, nil
+}
+
+// String returns the source expression
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t the schedule runs. Times are wall
+// clock times in loc (the configured timezone if nil): a time skipped by a
+// DST change runs when the clocks have moved on, a repeated one runs once.
+// It returns the zero time if the schedule never runs, e.g. on February 30.
+func (s *Schedule) Next(t time.Time, loc *time.Location) time.Time {
+	if loc == nil {
+		loc = Location()
+	}
+	local := t.In(loc)
+	y, m, d := local.Date()
+	// Leap days may be up to 8 years apart
+	for i := 0; i < 8*366; i++ {
+		day := time.Date(y, m, d+i, 0, 0, 0, 0, time.UTC)
+		if !s.matchesDay(day) {
+			continue
+		}
+		for h := 0; h < 24; h++ {
+			if s.hour&(1<<uint(h)) == 0 {
+				continue
+			}
+			for min := 0; min < 60; min++ {
+				if s.minute&(1<<uint(min)) == 0 {
+					continue
+				}
+				at := LocalTime(day.Year(), day.Month(), day.Day(), h, min, 0, loc)
+				if at.After(t) {
+					return at
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the schedule runs on the given date
+func (s *Schedule) matchesDay(day time.Time) bool {
+	if s.month&(1<<uint(day.Month())) == 0 {
+		return false
+	}
+	domOK := s.dom&(1<<uint(day.Day())) != 0
+	dowOK := s.dow&(1<<uint(day.Weekday())) != 0
+	if s.domRestricted && s.dowRestricted {
+		return domOK || dowOK
+	}
+	return domOK && dowOK
+}
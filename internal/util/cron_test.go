@@ -0,0 +1,81 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	// Wednesday
+	now := time.Date(2025, 9, 3, 14, 30, 0, 0, time.UTC)
+	at := func(m time.Month, d, h, min int) time.Time { return time.Date(2025, m, d, h, min, 0, 0, time.UTC) }
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 8 * * *", at(9, 4, 8, 0)},
+		{"30 14 * * *", at(9, 4, 14, 30)}, // strictly after now
+		{"*/15 * * * *", at(9, 3, 14, 45)},
+		{"0 8 * * mon-fri", at(9, 4, 8, 0)},
+		{"0 9 * * 1", at(9, 8, 9, 0)},
+		{"0 9 * * sun", at(9, 7, 9, 0)},
+		{"0 9 * * 7", at(9, 7, 9, 0)},
+		{"0 0 1 * *", at(10, 1, 0, 0)},
+		{"0 8-18/2 * * *", at(9, 3, 16, 0)},
+		{"5,35 * * * *", at(9, 3, 14, 35)},
+		{"0 0 1 jan *", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Day of month or day of week, as in cron
+		{"0 7 13 * fri", at(9, 5, 7, 0)},
+		{"@daily", at(9, 4, 0, 0)},
+		{"@weekly", at(9, 7, 0, 0)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		s, err := ParseSchedule(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q): %v", tc.expr, err)
+		}
+		if got := s.Next(now, time.UTC); !got.Equal(tc.want) {
+			t.Errorf("%q: Next = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+	never, _ := ParseSchedule("0 0 30 2 *")
+	if got := never.Next(now, time.UTC); !got.IsZero() {
+		t.Errorf("expected February 30 to never run, got %v", got)
+	}
+}
+
+func TestSchedule_NextDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := ParseSchedule("0 8 * * *")
+	// 08:00 stays 08:00 local time across the change to summer time
+	got := s.Next(time.Date(2025, 3, 29, 8, 0, 0, 0, berlin), berlin)
+	if want := time.Date(2025, 3, 30, 8, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	// 02:30 does not exist on 2025-03-30 and runs at 03:30
+	s, _ = ParseSchedule("30 2 * * *")
+	got = s.Next(time.Date(2025, 3, 29, 12, 0, 0, 0, berlin), berlin)
+	if want := time.Date(2025, 3, 30, 3, 30, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("gap: got %v, want %v", got, want)
+	}
+	// 02:30 happens twice on 2025-10-26 and runs once
+	first := s.Next(time.Date(2025, 10, 25, 12, 0, 0, 0, berlin), berlin)
+	if want := time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC); !first.Equal(want) {
+		t.Errorf("overlap: got %v, want %v", first, want)
+	}
+	if next := s.Next(first, berlin); !next.Equal(time.Date(2025, 10, 27, 2, 30, 0, 0, berlin)) {
+		t.Errorf("expected the repeated 02:30 to be skipped, got %v", next)
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "0 8 * *", "60 * * * *", "* 24 * * *", "0 0 0 * *", "0 0 * 13 *", "0 0 * * 8", "*/0 * * * *", "5-1 * * * *", "0 8 * * someday", "@often"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q): expected error", expr)
+		}
+	}
+}
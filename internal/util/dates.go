@@ -0,0 +1,246 @@
+package util
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDate parses a date the way Taskwarrior accepts it on the command line.
+// Besides the absolute layouts of ParseNotificationDate it understands:
+//
+//	date     = expr [clock] | clock [expr]
+//	expr     = [base] {("+" | "-") duration}
+//	base     = absolute | named | weekday | month | duration
+//	named    = now | today | sod | eod | tomorrow | yesterday | sow | eow |
+//	           soww | eoww | som | eom | soq | eoq | soy | eoy | later | someday
+//	weekday  = monday | mon | ... | sunday | sun   (the next one after today)
+//	month    = january | jan | ... | december | dec (the 1st, after this month)
+//	duration = 2h | 30m | 1d12h | 2w | PT30M | P1D  (see ParseDuration)
+//	clock    = 9:00 | 09:00:30 | 9am | 9:30pm
+//
+// Examples: now+2h, tomorrow 09:00, eow, monday 9am, PT30M, 2025-09-01.
+// A base that is a duration, or no base, is relative to now. Weeks start on
+// Monday; "end of" dates are the last second of the period. Named dates,
+// clocks and absolute dates without a zone are taken in loc. Days, weeks,
+// months and years keep the wall clock time across DST changes (see Offset).
+func ParseDate(s string, now time.Time, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = Location()
+	}
+	in := strings.TrimSpace(s)
+	if in == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	if t, err := parseAbsolute(in, loc); err == nil {
+		return t, nil
+	}
+	// Allow spaces around offsets ("now + 2h")
+	expr := strings.ToLower(offsetSpacePattern.ReplaceAllString(in, "$1"))
+	var base, clock string
+	for _, f := range strings.Fields(expr) {
+		switch {
+		case isClock(f) && clock == "":
+			clock = f
+		case base == "":
+			base = f
+		default:
+			return time.Time{}, fmt.Errorf("could not parse date %q", s)
+		}
+	}
+	t := now.In(loc)
+	if base != "" {
+		var err error
+		if t, err = parseExpr(base, now.In(loc), loc); err != nil {
+			return time.Time{}, fmt.Errorf("could not parse date %q: %v", s, err)
+		}
+	}
+	if clock != "" {
+		h, m, sec, err := parseClock(clock)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("could not parse date %q: %v", s, err)
+		}
+		t = LocalTime(t.Year(), t.Month(), t.Day(), h, m, sec, loc)
+	}
+	return t, nil
+}
+
+var (
+	offsetSpacePattern = regexp.MustCompile(`\s*([+-])\s*`)
+	clockPattern       = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?(am|pm)?$`)
+	// datePrefixPattern matches an absolute date at the start of an expression,
+	// so its dashes are not taken for offsets
+	datePrefixPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}(?:t\d{2}:\d{2}(?::\d{2})?)?`)
+)
+
+// parseExpr evaluates base{(+|-)duration}
+func parseExpr(expr string, now time.Time, loc *time.Location) (time.Time, error) {
+	base := datePrefixPattern.FindString(expr)
+	if base == "" {
+		base = expr
+		if i := strings.IndexAny(expr[1:], "+-"); i >= 0 {
+			base = expr[:i+1]
+		}
+		if base[0] == '+' || base[0] == '-' {
+			base = ""
+		}
+	}
+	rest := expr[len(base):]
+	var t time.Time
+	switch {
+	case base == "":
+		t = now
+	case datePrefixPattern.MatchString(base):
+		var err error
+		if t, err = parseAbsolute(strings.ToUpper(base), loc); err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", base)
+		}
+	default:
+		var ok bool
+		if t, ok = namedDate(base, now, loc); !ok {
+			o, err := ParseOffset(base)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unknown date %q", base)
+			}
+			t = o.AddTo(now)
+		}
+	}
+	for rest != "" {
+		sign := rest[0]
+		rest = rest[1:]
+		end := strings.IndexAny(rest, "+-")
+		if end < 0 {
+			end = len(rest)
+		}
+		o, err := ParseOffset(rest[:end])
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid offset %q", rest[:end])
+		}
+		if sign == '-' {
+			o = o.Neg()
+		}
+		t = o.AddTo(t)
+		rest = rest[end:]
+	}
+	return t, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"january": time.January, "jan": time.January,
+	"february": time.February, "feb": time.February,
+	"march": time.March, "mar": time.March,
+	"april": time.April, "apr": time.April,
+	"may":  time.May,
+	"june": time.June, "jun": time.June,
+	"july": time.July, "jul": time.July,
+	"august": time.August, "aug": time.August,
+	"september": time.September, "sep": time.September,
+	"october": time.October, "oct": time.October,
+	"november": time.November, "nov": time.November,
+	"december": time.December, "dec": time.December,
+}
+
+// namedDate resolves a date name relative to now
+func namedDate(name string, now time.Time, loc *time.Location) (time.Time, bool) {
+	y, m, d := now.Date()
+	day := func(dd int) time.Time { return LocalTime(y, m, d+dd, 0, 0, 0, loc) }
+	// Days since Monday
+	sinceMonday := (int(now.Weekday()) + 6) % 7
+	endOf := func(t time.Time) time.Time { return t.Add(-time.Second) }
+	qStart := time.Month((int(m)-1)/3*3 + 1)
+	switch name {
+	case "now":
+		return now, true
+	case "today", "sod":
+		return day(0), true
+	case "eod":
+		return endOf(day(1)), true
+	case "tomorrow":
+		return day(1), true
+	case "yesterday":
+		return day(-1), true
+	case "sow", "soww":
+		return day(-sinceMonday), true
+	case "eow":
+		return endOf(day(7 - sinceMonday)), true
+	case "eoww":
+		return endOf(day(5 - sinceMonday)), true
+	case "som":
+		return LocalTime(y, m, 1, 0, 0, 0, loc), true
+	case "eom":
+		return endOf(LocalTime(y, m+1, 1, 0, 0, 0, loc)), true
+	case "soq":
+		return LocalTime(y, qStart, 1, 0, 0, 0, loc), true
+	case "eoq":
+		return endOf(LocalTime(y, qStart+3, 1, 0, 0, 0, loc)), true
+	case "soy":
+		return LocalTime(y, time.January, 1, 0, 0, 0, loc), true
+	case "eoy":
+		return endOf(LocalTime(y+1, time.January, 1, 0, 0, 0, loc)), true
+	case "later", "someday":
+		return LocalTime(9999, time.December, 30, 0, 0, 0, loc), true
+	}
+	if wd, ok := weekdays[name]; ok {
+		ahead := (int(wd) - int(now.Weekday()) + 7) % 7
+		if ahead == 0 {
+			ahead = 7
+		}
+		return day(ahead), true
+	}
+	if mon, ok := months[name]; ok {
+		year := y
+		if mon <= m {
+			year++
+		}
+		return LocalTime(year, mon, 1, 0, 0, 0, loc), true
+	}
+	return time.Time{}, false
+}
+
+// isClock reports whether s is a time of day. A bare number is only a clock
+// with am/pm.
+func isClock(s string) bool {
+	p := clockPattern.FindStringSubmatch(s)
+	return p != nil && (p[2] != "" || p[4] != "")
+}
+
+// parseClock parses 9:00, 09:00:30, 9am or 9:30pm
+func parseClock(s string) (h, m, sec int, err error) {
+	if !isClock(s) {
+		return 0, 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+	p := clockPattern.FindStringSubmatch(s)
+	h, _ = strconv.Atoi(p[1])
+	if p[2] != "" {
+		m, _ = strconv.Atoi(p[2])
+	}
+	if p[3] != "" {
+		sec, _ = strconv.Atoi(p[3])
+	}
+	switch p[4] {
+	case "am", "pm":
+		if h < 1 || h > 12 {
+			return 0, 0, 0, fmt.Errorf("invalid time %q", s)
+		}
+		h %= 12
+		if p[4] == "pm" {
+			h += 12
+		}
+	}
+	if h > 23 || m > 59 || sec > 59 {
+		return 0, 0, 0, fmt.Errorf("invalid time %q", s)
+	}
+	return h, m, sec, nil
+}
@@ -0,0 +1,145 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	// Wednesday
+	now := time.Date(2025, 9, 3, 14, 30, 15, 0, loc)
+	at := func(y int, m time.Month, d, h, min, s int) time.Time { return time.Date(y, m, d, h, min, s, 0, loc) }
+	cases := []struct {
+		input string
+		want  time.Time
+	}{
+		// Absolute
+		{"2025-09-10", at(2025, 9, 10, 0, 0, 0)},
+		{"2025-09-10 08:15", at(2025, 9, 10, 8, 15, 0)},
+		{"2025-09-10T08:15:30", at(2025, 9, 10, 8, 15, 30)},
+		{"20250910T061530Z", time.Date(2025, 9, 10, 6, 15, 30, 0, time.UTC)},
+		{"2025-09-10T08:15:30Z", time.Date(2025, 9, 10, 8, 15, 30, 0, time.UTC)},
+		{"2025-09-10 9am", at(2025, 9, 10, 9, 0, 0)},
+		{"2025-09-10+1d", at(2025, 9, 11, 0, 0, 0)},
+		// Named
+		{"now", now},
+		{"today", at(2025, 9, 3, 0, 0, 0)},
+		{"sod", at(2025, 9, 3, 0, 0, 0)},
+		{"eod", at(2025, 9, 3, 23, 59, 59)},
+		{"tomorrow", at(2025, 9, 4, 0, 0, 0)},
+		{"Tomorrow", at(2025, 9, 4, 0, 0, 0)},
+		{"yesterday", at(2025, 9, 2, 0, 0, 0)},
+		{"sow", at(2025, 9, 1, 0, 0, 0)},
+		{"eow", at(2025, 9, 7, 23, 59, 59)},
+		{"eoww", at(2025, 9, 5, 23, 59, 59)},
+		{"som", at(2025, 9, 1, 0, 0, 0)},
+		{"eom", at(2025, 9, 30, 23, 59, 59)},
+		{"soq", at(2025, 7, 1, 0, 0, 0)},
+		{"eoq", at(2025, 9, 30, 23, 59, 59)},
+		{"soy", at(2025, 1, 1, 0, 0, 0)},
+		{"eoy", at(2025, 12, 31, 23, 59, 59)},
+		{"someday", at(9999, 12, 30, 0, 0, 0)},
+		// Weekdays are always after today, months after this month
+		{"monday", at(2025, 9, 8, 0, 0, 0)},
+		{"fri", at(2025, 9, 5, 0, 0, 0)},
+		{"wednesday", at(2025, 9, 10, 0, 0, 0)},
+		{"october", at(2025, 10, 1, 0, 0, 0)},
+		{"sep", at(2026, 9, 1, 0, 0, 0)},
+		{"jan", at(2026, 1, 1, 0, 0, 0)},
+		// Relative
+		{"now+2h", now.Add(2 * time.Hour)},
+		{"now + 2h", now.Add(2 * time.Hour)},
+		{"now-30m", now.Add(-30 * time.Minute)},
+		{"+90s", now.Add(90 * time.Second)},
+		{"tomorrow+9h", at(2025, 9, 4, 9, 0, 0)},
+		{"eow-1d", at(2025, 9, 6, 23, 59, 59)},
+		{"monday+1w+8h", at(2025, 9, 15, 8, 0, 0)},
+		{"PT30M", now.Add(30 * time.Minute)},
+		{"P1D", now.Add(24 * time.Hour)},
+		{"2h", now.Add(2 * time.Hour)},
+		{"1d12h", now.Add(36 * time.Hour)},
+		// Clock
+		{"tomorrow 09:00", at(2025, 9, 4, 9, 0, 0)},
+		{"09:00 tomorrow", at(2025, 9, 4, 9, 0, 0)},
+		{"monday 9am", at(2025, 9, 8, 9, 0, 0)},
+		{"friday 5:30pm", at(2025, 9, 5, 17, 30, 0)},
+		{"12am", at(2025, 9, 3, 0, 0, 0)},
+		{"12pm", at(2025, 9, 3, 12, 0, 0)},
+		{"18:45:10", at(2025, 9, 3, 18, 45, 10)},
+		{"tomorrow+1d 08:00", at(2025, 9, 5, 8, 0, 0)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseDate(tc.input, now, loc)
+			if err != nil {
+				t.Fatalf("ParseDate(%q) error: %v", tc.input, err)
+			}
+			if !got.Equal(tc.want) {
+				t.Fatalf("ParseDate(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDate_UsesLocation(t *testing.T) {
+	now := time.Date(2025, 9, 3, 23, 30, 0, 0, time.UTC)
+	tokyo := time.FixedZone("JST", 9*60*60)
+	// 23:30 UTC is already Thursday 08:30 in Tokyo
+	got, err := ParseDate("tomorrow 09:00", now, tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 9, 5, 9, 0, 0, 0, tokyo); !got.Equal(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	got, err = ParseDate("2025-09-10 08:00", now, tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 9, 9, 23, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("naive date not taken in location: got %v, want %v", got, want)
+	}
+}
+
+func TestParseDate_Invalid(t *testing.T) {
+	now := time.Date(2025, 9, 3, 14, 30, 0, 0, time.UTC)
+	for _, input := range []string{
+		"", "soon", "now+", "now+soon", "tomorrow monday", "13pm", "25:00", "9:75",
+		"tomorrow 9", "2025-13-01", "2025-09-10+", "fortnight",
+	} {
+		if got, err := ParseDate(input, now, time.UTC); err == nil {
+			t.Errorf("ParseDate(%q) = %v, expected error", input, got)
+		}
+	}
+}
+
+func TestParseDate_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday before clocks go forward at 02:00 on Sunday
+	now := time.Date(2025, 3, 29, 20, 0, 0, 0, berlin)
+	cases := []struct {
+		input string
+		want  time.Time
+	}{
+		{"tomorrow 09:00", time.Date(2025, 3, 30, 9, 0, 0, 0, berlin)},
+		{"tomorrow+9h", time.Date(2025, 3, 30, 10, 0, 0, 0, berlin)},
+		{"now+1d", time.Date(2025, 3, 30, 20, 0, 0, 0, berlin)},
+		{"now+24h", time.Date(2025, 3, 30, 21, 0, 0, 0, berlin)},
+		{"tomorrow 02:30", time.Date(2025, 3, 30, 3, 30, 0, 0, berlin)},
+		{"2025-03-30 02:30", time.Date(2025, 3, 30, 3, 30, 0, 0, berlin)},
+		{"eow", time.Date(2025, 3, 30, 23, 59, 59, 0, berlin)},
+	}
+	for _, tc := range cases {
+		got, err := ParseDate(tc.input, now, berlin)
+		if err != nil {
+			t.Fatalf("ParseDate(%q): %v", tc.input, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tc.input, got, tc.want)
+		}
+	}
+}
@@ -2,26 +2,243 @@ package util
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
-// ParseNotificationDate parses a date string in common Taskwarrior formats and returns it in local time
-func ParseNotificationDate(s string) (time.Time, error) {
-	layouts := []string{
-		time.RFC3339,
+// location is the timezone for dates without one; see SetLocation
+var (
+	locMu    sync.RWMutex
+	location = time.Local
+)
+
+// SetLocation sets the timezone in which dates without a zone are
+// interpreted and shown (the timezone config option). nil means time.Local.
+func SetLocation(loc *time.Location) {
+	if loc == nil {
+		loc = time.Local
+	}
+	locMu.Lock()
+	defer locMu.Unlock()
+	location = loc
+}
+
+// Location returns the timezone set with SetLocation
+func Location() *time.Location {
+	locMu.RLock()
+	defer locMu.RUnlock()
+	return location
+}
+
+// absoluteLayouts are the absolute date formats accepted everywhere. Dates
+// with a zone keep it; Taskwarrior's compact format ending in Z is UTC; the
+// others are wall clock times in the configured timezone.
+var absoluteLayouts = []string{
+	time.RFC3339,
+	"20060102T150405Z",
 	"2006-01-02 15:04:05",
-		"2006-01-02T15:04:05",
-		"2006-01-02 15:04",
-		"20060102T150405Z", // Taskwarrior compact UTC format
-	}
-	for _, layout := range layouts {
-		if t, err := time.Parse(layout, s); err == nil {
-			// Only convert to local time if the parsed time is in Local
-			if t.Location() == time.UTC || t.Location().String() != "Local" {
-				return t, nil
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"20060102T150405",
+	"2006-01-02",
+}
+
+// ParseNotificationDate parses an absolute date as exported by Taskwarrior
+// or written by hand. Dates without a zone are taken in Location().
+func ParseNotificationDate(s string) (time.Time, error) {
+	t, err := parseAbsolute(strings.TrimSpace(s), Location())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse notification date: %s", s)
+	}
+	return t, nil
+}
+
+func parseAbsolute(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range absoluteLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if layout == time.RFC3339 || layout == "20060102T150405Z" {
+			return t, nil
+		}
+		return LocalTime(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), loc), nil
+	}
+	return time.Time{}, fmt.Errorf("not an absolute date: %s", s)
+}
+
+// LocalTime returns the instant at which the wall clock in loc shows the
+// given time. A time skipped when clocks go forward (02:30 on a night that
+// jumps from 02:00 to 03:00) is moved forward by the length of the gap; a
+// time that occurs twice when clocks go back resolves to the first one.
+func LocalTime(year int, month time.Month, day, hour, min, sec int, loc *time.Location) time.Time {
+	wall := time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+	_, before := wall.Add(-24 * time.Hour).In(loc).Zone()
+	_, after := wall.Add(24 * time.Hour).In(loc).Zone()
+	var first time.Time
+	for _, offset := range []int{before, after} {
+		t := wall.Add(-time.Duration(offset) * time.Second).In(loc)
+		if sameWallClock(t, wall) && (first.IsZero() || t.Before(first)) {
+			first = t
+		}
+	}
+	if !first.IsZero() {
+		return first
+	}
+	// Skipped wall clock time: use the offset in effect before the change
+	return wall.Add(-time.Duration(before) * time.Second).In(loc)
+}
+
+func sameWallClock(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd && a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
+
+// isoDurationPattern matches ISO 8601 durations as exported by Taskwarrior
+// for duration UDAs (e.g. PT10M, P1DT2H, P2W)
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// ParseDuration parses a Go duration ("90s", "1h30m"), a duration with day or
+// week units ("2d", "1w") or an ISO 8601 duration ("PT10M", "P1D").
+// Years and months in ISO durations are approximated as 365 and 30 days.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	if m := isoDurationPattern.FindStringSubmatch(strings.ToUpper(s)); m != nil && s != "P" && !strings.HasSuffix(strings.ToUpper(s), "T") {
+		units := []time.Duration{365 * 24 * time.Hour, 30 * 24 * time.Hour, 7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+		var total time.Duration
+		for i, unit := range units {
+			if m[i+1] == "" {
+				continue
+			}
+			n, err := strconv.Atoi(m[i+1])
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+			}
+			total += time.Duration(n) * unit
+		}
+		return total, nil
+	}
+	// Day and week suffixes, optionally followed by a Go duration (e.g. 1d12h)
+	var total time.Duration
+	rest := s
+	for _, u := range []struct {
+		suffix string
+		unit   time.Duration
+	}{{"w", 7 * 24 * time.Hour}, {"d", 24 * time.Hour}} {
+		i := strings.Index(rest, u.suffix)
+		if i <= 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += time.Duration(n) * u.unit
+		rest = rest[i+1:]
+	}
+	if total == 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		total += d
+	}
+	return total, nil
+}
+
+// Offset is a calendar-aware duration. Years, months, weeks and days move the
+// date and keep the wall clock time, so 1d after 09:00 is 09:00 the next day
+// even across a DST change; hours, minutes and seconds are elapsed time.
+type Offset struct {
+	Years, Months, Days int
+	Clock               time.Duration
+}
+
+// ParseOffset parses the duration forms of ParseDuration into an Offset.
+// ISO months and years are calendar months and years.
+func ParseOffset(s string) (Offset, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Offset{}, fmt.Errorf("empty duration")
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return Offset{Clock: d}, nil
+	}
+	upper := strings.ToUpper(s)
+	if m := isoDurationPattern.FindStringSubmatch(upper); m != nil && upper != "P" && !strings.HasSuffix(upper, "T") {
+		var n [7]int
+		for i := range n {
+			if m[i+1] != "" {
+				v, err := strconv.Atoi(m[i+1])
+				if err != nil {
+					return Offset{}, fmt.Errorf("invalid duration %q: %v", s, err)
+				}
+				n[i] = v
 			}
-			return t.In(time.Local), nil
 		}
+		return Offset{
+			Years:  n[0],
+			Months: n[1],
+			Days:   7*n[2] + n[3],
+			Clock:  time.Duration(n[4])*time.Hour + time.Duration(n[5])*time.Minute + time.Duration(n[6])*time.Second,
+		}, nil
+	}
+	var o Offset
+	rest := s
+	for _, u := range []struct {
+		suffix string
+		days   int
+	}{{"w", 7}, {"d", 1}} {
+		i := strings.Index(rest, u.suffix)
+		if i <= 0 {
+			continue
+		}
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil {
+			return Offset{}, fmt.Errorf("invalid duration %q", s)
+		}
+		o.Days += n * u.days
+		rest = rest[i+1:]
+	}
+	if o.Days == 0 {
+		return Offset{}, fmt.Errorf("invalid duration %q", s)
+	}
+	if rest != "" {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return Offset{}, fmt.Errorf("invalid duration %q", s)
+		}
+		o.Clock = d
+	}
+	return o, nil
+}
+
+// Neg returns the offset going back in time
+func (o Offset) Neg() Offset {
+	return Offset{Years: -o.Years, Months: -o.Months, Days: -o.Days, Clock: -o.Clock}
+}
+
+// AddTo returns t moved by the offset, with calendar units applied to the
+// wall clock in t's location
+func (o Offset) AddTo(t time.Time) time.Time {
+	if o.Years != 0 || o.Months != 0 || o.Days != 0 {
+		y, m, d := t.Date()
+		t = LocalTime(y+o.Years, m+time.Month(o.Months), d+o.Days, t.Hour(), t.Minute(), t.Second(), t.Location()).
+			Add(time.Duration(t.Nanosecond()))
 	}
-	return time.Time{}, fmt.Errorf("could not parse notification date: %s", s)
+	return t.Add(o.Clock)
 }
@@ -3,6 +3,7 @@ package util
 import (
 	"testing"
 	"time"
+	_ "time/tzdata"
 )
 
 func TestParseNotificationDate_Formats(t *testing.T) {
@@ -80,3 +81,136 @@ func TestParseNotificationDate_TimezoneMatrix(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"1h30m", 90 * time.Minute},
+		{"2d", 48 * time.Hour},
+		{"1w", 7 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"PT10M", 10 * time.Minute},
+		{"P1DT2H", 26 * time.Hour},
+		{"P2W", 14 * 24 * time.Hour},
+		{"PT1H30M15S", time.Hour + 30*time.Minute + 15*time.Second},
+		{"pt5m", 5 * time.Minute},
+	}
+	for _, tc := range cases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := ParseDuration(tc.input)
+			if err != nil {
+				t.Fatalf("ParseDuration(%q) error: %v", tc.input, err)
+			}
+			if got != tc.want {
+				t.Fatalf("ParseDuration(%q) = %v, want %v", tc.input, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseDuration_Invalid(t *testing.T) {
+	for _, input := range []string{"", "P", "PT", "soon", "d", "xd"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("ParseDuration(%q) expected error, got nil", input)
+		}
+	}
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestParseNotificationDate_ConfiguredZone(t *testing.T) {
+	SetLocation(mustLoad(t, "America/New_York"))
+	defer SetLocation(nil)
+	cases := []struct {
+		input string
+		want  time.Time
+	}{
+		// Naive dates are wall clock times in the configured zone (EDT, -4)
+		{"2025-08-31 14:30:00", time.Date(2025, 8, 31, 18, 30, 0, 0, time.UTC)},
+		{"2025-08-31T14:30", time.Date(2025, 8, 31, 18, 30, 0, 0, time.UTC)},
+		{"2025-12-31", time.Date(2025, 12, 31, 5, 0, 0, 0, time.UTC)},
+		// Taskwarrior's compact format is UTC, explicit offsets are kept
+		{"20250831T143000Z", time.Date(2025, 8, 31, 14, 30, 0, 0, time.UTC)},
+		{"2025-08-31T14:30:00+02:00", time.Date(2025, 8, 31, 12, 30, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := ParseNotificationDate(tc.input)
+		if err != nil {
+			t.Fatalf("ParseNotificationDate(%q): %v", tc.input, err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("ParseNotificationDate(%q) = %v, want %v", tc.input, got.UTC(), tc.want)
+		}
+	}
+}
+
+func TestLocalTime_DST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	cases := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"normal", LocalTime(2025, 7, 1, 9, 0, 0, berlin), time.Date(2025, 7, 1, 7, 0, 0, 0, time.UTC)},
+		// 2025-03-30 02:00 CET jumps to 03:00 CEST: 02:30 does not exist
+		{"gap", LocalTime(2025, 3, 30, 2, 30, 0, berlin), time.Date(2025, 3, 30, 1, 30, 0, 0, time.UTC)},
+		{"after gap", LocalTime(2025, 3, 30, 3, 0, 0, berlin), time.Date(2025, 3, 30, 1, 0, 0, 0, time.UTC)},
+		// 2025-10-26 03:00 CEST goes back to 02:00 CET: 02:30 happens twice
+		{"overlap", LocalTime(2025, 10, 26, 2, 30, 0, berlin), time.Date(2025, 10, 26, 0, 30, 0, 0, time.UTC)},
+		{"after overlap", LocalTime(2025, 10, 26, 3, 0, 0, berlin), time.Date(2025, 10, 26, 2, 0, 0, 0, time.UTC)},
+		{"normalizes", LocalTime(2025, 12, 32, 0, 0, 0, berlin), time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		if !tc.got.Equal(tc.want) {
+			t.Errorf("%s: got %v (%v), want %v", tc.name, tc.got, tc.got.UTC(), tc.want)
+		}
+	}
+	if got := LocalTime(2025, 3, 30, 2, 30, 0, berlin); got.Hour() != 3 || got.Minute() != 30 {
+		t.Errorf("gap time should move forward to 03:30, got %v", got)
+	}
+}
+
+func TestOffset_AcrossDST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	nineAM := time.Date(2025, 3, 29, 9, 0, 0, 0, berlin)
+	for _, tc := range []struct {
+		offset string
+		want   time.Time
+	}{
+		// Days keep the wall clock time although the day is only 23h long
+		{"1d", time.Date(2025, 3, 30, 9, 0, 0, 0, berlin)},
+		{"P1D", time.Date(2025, 3, 30, 9, 0, 0, 0, berlin)},
+		{"1w", time.Date(2025, 4, 5, 9, 0, 0, 0, berlin)},
+		{"P1M", time.Date(2025, 4, 29, 9, 0, 0, 0, berlin)},
+		// Hours are elapsed time
+		{"24h", time.Date(2025, 3, 30, 10, 0, 0, 0, berlin)},
+		{"1d2h", time.Date(2025, 3, 30, 11, 0, 0, 0, berlin)},
+	} {
+		o, err := ParseOffset(tc.offset)
+		if err != nil {
+			t.Fatalf("ParseOffset(%q): %v", tc.offset, err)
+		}
+		if got := o.AddTo(nineAM); !got.Equal(tc.want) {
+			t.Errorf("%s after %v = %v, want %v", tc.offset, nineAM, got, tc.want)
+		}
+	}
+	o, _ := ParseOffset("1d")
+	if got, want := o.Neg().AddTo(time.Date(2025, 10, 27, 9, 0, 0, 0, berlin)), time.Date(2025, 10, 26, 9, 0, 0, 0, berlin); !got.Equal(want) {
+		t.Errorf("1d before: got %v, want %v", got, want)
+	}
+	for _, bad := range []string{"", "soon", "P", "xd"} {
+		if _, err := ParseOffset(bad); err == nil {
+			t.Errorf("ParseOffset(%q): expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package web
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ActionPathPrefix is the route prefix for signed notification actions.
+// Requests below it carry their own per-task signature instead of the bearer token.
+const ActionPathPrefix = "/api/action/"
+
+// Notification actions
+const (
+	ActionAcknowledge = "acknowledge"
+	ActionComplete    = "complete"
+	ActionSnooze      = "snooze"
+	// ActionView opens a page with the task; it is the only GET action
+	ActionView = "view"
+)
+
+// TaskView is what the view action shows of a task
+type TaskView struct {
+	UUID             string
+	Description      string
+	Project          string
+	Priority         string
+	Status           string
+	Tags             []string
+	Due              string
+	NotificationDate string
+	Annotations      []string
+}
+
+var (
+	// ActionSecret is the HMAC key used to sign and verify action URLs
+	ActionSecret []byte
+	// CompleteTaskFunc marks a task as done; overridable like the other hooks
+	CompleteTaskFunc = func(uuid string) error {
+		return errors.New("not implemented")
+	}
+	// ViewTaskFunc looks up a task for the view action
+	ViewTaskFunc = func(uuid string) (TaskView, bool) {
+		return TaskView{}, false
+	}
+)
+
+// signAction returns the hex HMAC-SHA256 signature for an action on a task
+func signAction(secret []byte, action, uuid, delay string, expires int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(action + "|" + uuid + "|" + delay + "|" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ActionURL builds a signed URL below baseURL that performs action on the
+// task with the given UUID. delay is only used for snooze.
+func ActionURL(baseURL string, secret []byte, action, uuid, delay string, expires time.Time) string {
+	exp := expires.Unix()
+	q := url.Values{}
+	q.Set("uuid", uuid)
+	if delay != "" {
+		q.Set("delay", delay)
+	}
+	q.Set("exp", strconv.FormatInt(exp, 10))
+	q.Set("sig", signAction(secret, action, uuid, delay, exp))
+	return strings.TrimRight(baseURL, "/") + ActionPathPrefix + action + "?" + q.Encode()
+}
+
+// verifyAction checks the signature and expiry of an action request
+func verifyAction(secret []byte, action string, q url.Values, now time.Time) error {
+	if len(secret) == 0 {
+		return errors.New("actions are not configured")
+	}
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if err != nil {
+		return errors.New("invalid expiry")
+	}
+	if now.Unix() > exp {
+		return errors.New("action link expired")
+	}
+	want := signAction(secret, action, q.Get("uuid"), q.Get("delay"), exp)
+	if !hmac.Equal([]byte(want), []byte(q.Get("sig"))) {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func actionHandler(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, ActionPathPrefix)
+	method := http.MethodPost
+	if action == ActionView {
+		method = http.MethodGet
+	}
+	if r.Method != method {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	if err := verifyAction(ActionSecret, action, q, time.Now()); err != nil {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	uuid := q.Get("uuid")
+	if uuid == "" {
+		http.Error(w, "uuid required", http.StatusBadRequest)
+		return
+	}
+	var err error
+	switch action {
+	case ActionAcknowledge:
+		err = AcknowledgeFunc(uuid, "")
+	case ActionSnooze:
+		if q.Get("delay") == "" {
+			http.Error(w, "delay required", http.StatusBadRequest)
+			return
+		}
+		err = AcknowledgeFunc(uuid, q.Get("delay"))
+	case ActionComplete:
+		err = CompleteTaskFunc(uuid)
+	case ActionView:
+		viewTask(w, r, uuid, q.Get("exp"))
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if badRequest(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to "+action, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok", "action": action})
+}
+
+var viewTemplate = template.Must(template.New("view").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Task.Description}}</title>
+</head>
+<body>
+<h1>{{.Task.Description}}</h1>
+<dl>
+{{with .Task.Project}}<dt>Project</dt><dd>{{.}}</dd>
+{{end}}{{with .Task.Priority}}<dt>Priority</dt><dd>{{.}}</dd>
+{{end}}{{with .Task.Status}}<dt>Status</dt><dd>{{.}}</dd>
+{{end}}{{with .Task.Due}}<dt>Due</dt><dd>{{.}}</dd>
+{{end}}{{with .Task.NotificationDate}}<dt>Notification</dt><dd>{{.}}</dd>
+{{end}}{{if .Task.Tags}}<dt>Tags</dt><dd>{{range $i, $t := .Task.Tags}}{{if $i}}, {{end}}{{$t}}{{end}}</dd>
+{{end}}<dt>UUID</dt><dd>{{.Task.UUID}}</dd>
+</dl>
+{{if .Task.Annotations}}<ul>
+{{range .Task.Annotations}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}<form method="post" action="{{.Acknowledge}}"><button>Acknowledge</button></form>
+<form method="post" action="{{.Complete}}"><button>Done</button></form>
+</body>
+</html>
+`))
+
+// viewTask renders the page of the view action, with Acknowledge and Done
+// buttons signed to expire with the view link
+func viewTask(w http.ResponseWriter, r *http.Request, uuid, exp string) {
+	task, ok := ViewTaskFunc(uuid)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	expires, _ := strconv.ParseInt(exp, 10, 64)
+	data := struct {
+		Task                  TaskView
+		Acknowledge, Complete string
+	}{
+		Task:        task,
+		Acknowledge: ActionURL("", ActionSecret, ActionAcknowledge, uuid, "", time.Unix(expires, 0)),
+		Complete:    ActionURL("", ActionSecret, ActionComplete, uuid, "", time.Unix(expires, 0)),
+	}
+	var buf bytes.Buffer
+	if err := viewTemplate.Execute(&buf, data); err != nil {
+		http.Error(w, "failed to render task", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
@@ -0,0 +1,164 @@
+package web
+
+import (
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActionHandler_SignedURLs(t *testing.T) {
+	origSecret := ActionSecret
+	origAck := AcknowledgeFunc
+	origComplete := CompleteTaskFunc
+	defer func() {
+		ActionSecret = origSecret
+		AcknowledgeFunc = origAck
+		CompleteTaskFunc = origComplete
+	}()
+	ActionSecret = []byte("k3y")
+
+	var acked, snoozed, completed string
+	AcknowledgeFunc = func(uuid string, repeatDelay string) error {
+		if repeatDelay == "" {
+			acked = uuid
+		} else {
+			snoozed = uuid + "/" + repeatDelay
+		}
+		return nil
+	}
+	CompleteTaskFunc = func(uuid string) error {
+		completed = uuid
+		return nil
+	}
+
+	// bearer auth must not be required for signed action links
+	srv := httptest.NewServer(AuthMiddleware(NewRouter(), "s3cr3t"))
+	defer srv.Close()
+	exp := time.Now().Add(time.Hour)
+
+	for _, tc := range []struct {
+		action string
+		delay  string
+	}{{ActionAcknowledge, ""}, {ActionSnooze, "15m"}, {ActionComplete, ""}} {
+		u := ActionURL(srv.URL, ActionSecret, tc.action, "u1", tc.delay, exp)
+		resp, err := http.Post(u, "text/plain", nil)
+		if err != nil {
+			t.Fatalf("post %s: %v", tc.action, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", tc.action, resp.StatusCode)
+		}
+	}
+	if acked != "u1" || snoozed != "u1/15m" || completed != "u1" {
+		t.Fatalf("unexpected hook calls: ack=%q snooze=%q complete=%q", acked, snoozed, completed)
+	}
+}
+
+func TestActionHandler_RejectsBadSignatures(t *testing.T) {
+	origSecret := ActionSecret
+	defer func() { ActionSecret = origSecret }()
+	ActionSecret = []byte("k3y")
+
+	srv := httptest.NewServer(NewRouter())
+	defer srv.Close()
+
+	cases := map[string]string{
+		"wrong secret": ActionURL(srv.URL, []byte("other"), ActionComplete, "u1", "", time.Now().Add(time.Hour)),
+		"expired":      ActionURL(srv.URL, ActionSecret, ActionComplete, "u1", "", time.Now().Add(-time.Minute)),
+		"tampered uuid": strings.Replace(
+			ActionURL(srv.URL, ActionSecret, ActionComplete, "u1", "", time.Now().Add(time.Hour)), "uuid=u1", "uuid=u2", 1),
+		"other action": strings.Replace(
+			ActionURL(srv.URL, ActionSecret, ActionAcknowledge, "u1", "", time.Now().Add(time.Hour)), ActionAcknowledge, ActionComplete, 1),
+	}
+	for name, u := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := http.Post(u, "text/plain", nil)
+			if err != nil {
+				t.Fatalf("post: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusForbidden {
+				t.Fatalf("expected 403, got %d", resp.StatusCode)
+			}
+		})
+	}
+
+	// GET is not allowed
+	resp, err := http.Get(ActionURL(srv.URL, ActionSecret, ActionComplete, "u1", "", time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestActionHandler_View(t *testing.T) {
+	origSecret := ActionSecret
+	origView := ViewTaskFunc
+	defer func() {
+		ActionSecret = origSecret
+		ViewTaskFunc = origView
+	}()
+	ActionSecret = []byte("k3y")
+	ViewTaskFunc = func(uuid string) (TaskView, bool) {
+		return TaskView{UUID: uuid, Description: "water <plants>", Project: "home", Tags: []string{"a", "b"}}, uuid == "u1"
+	}
+
+	srv := httptest.NewServer(AuthMiddleware(NewRouter(), "s3cr3t"))
+	defer srv.Close()
+	exp := time.Now().Add(time.Hour)
+
+	resp, err := http.Get(ActionURL(srv.URL, ActionSecret, ActionView, "u1", "", exp))
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Fatalf("unexpected response %d %v", resp.StatusCode, resp.Header)
+	}
+	page := string(body)
+	for _, want := range []string{"<h1>water &lt;plants&gt;</h1>", "<dd>home</dd>", "<dd>a, b</dd>", ActionPathPrefix + ActionAcknowledge + "?", ActionPathPrefix + ActionComplete + "?"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+
+	// The buttons on the page are signed actions
+	i := strings.Index(page, ActionPathPrefix+ActionComplete)
+	link := srv.URL + html.UnescapeString(page[i:i+strings.Index(page[i:], `"`)])
+	completed := ""
+	origComplete := CompleteTaskFunc
+	defer func() { CompleteTaskFunc = origComplete }()
+	CompleteTaskFunc = func(uuid string) error {
+		completed = uuid
+		return nil
+	}
+	if resp, err := http.Post(link, "text/plain", nil); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("post %s: %v %v", link, err, resp)
+	}
+	if completed != "u1" {
+		t.Errorf("expected u1 to be completed, got %q", completed)
+	}
+
+	for name, u := range map[string]string{
+		"unknown task": ActionURL(srv.URL, ActionSecret, ActionView, "u2", "", exp),
+		"bad sig":      ActionURL(srv.URL, []byte("other"), ActionView, "u1", "", exp),
+	} {
+		resp, err := http.Get(u)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		resp.Body.Close()
+		if want := map[string]int{"unknown task": http.StatusNotFound, "bad sig": http.StatusForbidden}[name]; resp.StatusCode != want {
+			t.Errorf("%s: expected %d, got %d", name, want, resp.StatusCode)
+		}
+	}
+}
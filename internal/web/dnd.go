@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DNDRequest turns do-not-disturb on or off
+type DNDRequest struct {
+	Enabled bool   `json:"enabled"`
+	Until   string `json:"until,omitempty"` // duration or date, e.g. "2h" or "tomorrow 07:00"
+}
+
+// DNDStatus reports do-not-disturb and whether quiet hours are active
+type DNDStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Since      *time.Time `json:"since,omitempty"`
+	Until      *time.Time `json:"until,omitempty"` // unset until turned off
+	QuietHours bool       `json:"quiet_hours"`
+	QuietUntil *time.Time `json:"quiet_until,omitempty"`
+}
+
+var (
+	// DNDStatusFunc reports the do-not-disturb status; overridable like the other hooks
+	DNDStatusFunc = func() (DNDStatus, error) {
+		return DNDStatus{}, errors.New("not implemented")
+	}
+	// SetDNDFunc turns do-not-disturb on or off and returns the new status
+	SetDNDFunc = func(req DNDRequest) (DNDStatus, error) {
+		return DNDStatus{}, errors.New("not implemented")
+	}
+)
+
+// dndHandler reports do-not-disturb on GET and changes it on POST
+func dndHandler(w http.ResponseWriter, r *http.Request) {
+	var st DNDStatus
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		st, err = DNDStatusFunc()
+	case http.MethodPost:
+		var req DNDRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		st, err = SetDNDFunc(req)
+		if badRequest(w, err) {
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to update do-not-disturb", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(st)
+}
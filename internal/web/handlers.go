@@ -4,6 +4,9 @@ import (
     "encoding/json"
     "errors"
     "net/http"
+    "strings"
+
+    "task-herald/internal/metrics"
 )
 
 // HTTP payloads
@@ -18,6 +21,7 @@ type CreateTaskRequest struct {
 type CreateTaskResponse struct {
     UUID    string `json:"uuid"`
     Message string `json:"message"`
+    Warning string `json:"warning,omitempty"`
 }
 
 type AcknowledgeRequest struct {
@@ -29,6 +33,15 @@ type AcknowledgeResponse struct {
     Acknowledged bool `json:"acknowledged"`
 }
 
+// ErrBadRequest marks errors caused by invalid input, such as a date that
+// cannot be parsed. Handlers answer them with 400 and the error message.
+var ErrBadRequest = errors.New("invalid request")
+
+// ErrPartiallyCreated marks errors of CreateTaskFunc after the task was
+// created, e.g. a failed annotation. The handler answers 201 with the UUID
+// and the error as a warning, so a client retry does not duplicate the task.
+var ErrPartiallyCreated = errors.New("task created with errors")
+
 // Package-level hooks so tests can override behavior
 var (
     CreateTaskFunc = func(req CreateTaskRequest) (string, error) {
@@ -45,7 +58,10 @@ func NewRouter() http.Handler {
     mux.HandleFunc("/api/health", healthHandler)
     mux.HandleFunc("/api/create-task", createTaskHandler)
     mux.HandleFunc("/api/acknowledge", acknowledgeHandler)
+    mux.HandleFunc("/api/dnd", dndHandler)
     mux.HandleFunc("/api/debug", debugHandler)
+    mux.HandleFunc(ActionPathPrefix, actionHandler)
+    mux.Handle("/metrics", metrics.Handler())
     return mux
 }
 
@@ -74,13 +90,20 @@ func createTaskHandler(w http.ResponseWriter, r *http.Request) {
         return
     }
     uuid, err := CreateTaskFunc(req)
+    if badRequest(w, err) {
+        return
+    }
+    resp := CreateTaskResponse{UUID: uuid, Message: "created"}
+    if errors.Is(err, ErrPartiallyCreated) && uuid != "" {
+        resp.Warning, err = err.Error(), nil
+    }
     if err != nil {
         http.Error(w, "failed to create task", http.StatusInternalServerError)
         return
     }
     w.Header().Set("Content-Type", "application/json")
     w.WriteHeader(http.StatusCreated)
-    _ = json.NewEncoder(w).Encode(CreateTaskResponse{UUID: uuid, Message: "created"})
+    _ = json.NewEncoder(w).Encode(resp)
 }
 
 func acknowledgeHandler(w http.ResponseWriter, r *http.Request) {
@@ -97,7 +120,11 @@ func acknowledgeHandler(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "uuid required", http.StatusBadRequest)
         return
     }
-    if err := AcknowledgeFunc(req.UUID, req.RepeatDelay); err != nil {
+    err := AcknowledgeFunc(req.UUID, req.RepeatDelay)
+    if badRequest(w, err) {
+        return
+    }
+    if err != nil {
         http.Error(w, "failed to acknowledge", http.StatusInternalServerError)
         return
     }
@@ -106,6 +133,15 @@ func acknowledgeHandler(w http.ResponseWriter, r *http.Request) {
     _ = json.NewEncoder(w).Encode(AcknowledgeResponse{Acknowledged: true})
 }
 
+// badRequest answers 400 if err is an ErrBadRequest
+func badRequest(w http.ResponseWriter, err error) bool {
+    if !errors.Is(err, ErrBadRequest) {
+        return false
+    }
+    http.Error(w, err.Error(), http.StatusBadRequest)
+    return true
+}
+
 func debugHandler(w http.ResponseWriter, r *http.Request) {
     // simple debug endpoint
     if r.Method != http.MethodGet {
@@ -117,12 +153,17 @@ func debugHandler(w http.ResponseWriter, r *http.Request) {
     _ = json.NewEncoder(w).Encode(map[string]string{"debug": "ok"})
 }
 
-// AuthMiddleware enforces an Authorization: Bearer <token> header when token is non-empty
+// AuthMiddleware enforces an Authorization: Bearer <token> header when token is non-empty.
+// Signed action URLs are exempt since they are verified by their own signature.
 func AuthMiddleware(handler http.Handler, token string) http.Handler {
     if token == "" {
         return handler
     }
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if strings.HasPrefix(r.URL.Path, ActionPathPrefix) {
+            handler.ServeHTTP(w, r)
+            return
+        }
         auth := r.Header.Get("Authorization")
         if auth != "Bearer "+token {
             http.Error(w, "unauthorized", http.StatusUnauthorized)
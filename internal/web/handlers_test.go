@@ -3,9 +3,11 @@ package web
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -94,6 +96,25 @@ func TestCreateTaskHandler_BadRequest(t *testing.T) {
 	}
 }
 
+func TestCreateTaskHandler_InvalidDate(t *testing.T) {
+	orig := CreateTaskFunc
+	defer func() { CreateTaskFunc = orig }()
+	CreateTaskFunc = func(req CreateTaskRequest) (string, error) {
+		return "", fmt.Errorf("%w: notification_date: could not parse date %q", ErrBadRequest, req.NotificationDate)
+	}
+	r := httptest.NewServer(NewRouter())
+	defer r.Close()
+	resp, err := http.Post(r.URL+"/api/create-task", "application/json", bytes.NewReader([]byte(`{"description":"x","notification_date":"soonish"}`)))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "soonish") {
+		t.Fatalf("expected 400 naming the date, got %d %q", resp.StatusCode, body)
+	}
+}
+
 func TestAcknowledgeHandler(t *testing.T) {
 	orig := AcknowledgeFunc
 	defer func() { AcknowledgeFunc = orig }()
@@ -146,6 +167,19 @@ func TestAcknowledgeHandler(t *testing.T) {
 	}
 }
 
+func TestMetricsHandler(t *testing.T) {
+	r := httptest.NewServer(NewRouter())
+	defer r.Close()
+	resp, err := http.Get(r.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("http get failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Fatalf("expected 200 OK with text, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
+
 func TestAuthMiddleware(t *testing.T) {
 	handler := NewRouter()
 	// wrap with token
@@ -175,3 +209,76 @@ func TestAuthMiddleware(t *testing.T) {
 		t.Fatalf("expected 200 OK with auth, got %d", resp2.StatusCode)
 	}
 }
+
+func TestDNDHandler(t *testing.T) {
+	origStatus, origSet := DNDStatusFunc, SetDNDFunc
+	defer func() { DNDStatusFunc, SetDNDFunc = origStatus, origSet }()
+	DNDStatusFunc = func() (DNDStatus, error) {
+		return DNDStatus{QuietHours: true}, nil
+	}
+	var got DNDRequest
+	SetDNDFunc = func(req DNDRequest) (DNDStatus, error) {
+		if req.Until == "soon" {
+			return DNDStatus{}, fmt.Errorf("%w: until: could not parse date", ErrBadRequest)
+		}
+		got = req
+		return DNDStatus{Enabled: req.Enabled}, nil
+	}
+	r := httptest.NewServer(NewRouter())
+	defer r.Close()
+
+	resp, err := http.Get(r.URL + "/api/dnd")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	var st DNDStatus
+	_ = json.NewDecoder(resp.Body).Decode(&st)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !st.QuietHours || st.Enabled {
+		t.Fatalf("unexpected status %d: %+v", resp.StatusCode, st)
+	}
+
+	resp, err = http.Post(r.URL+"/api/dnd", "application/json", strings.NewReader(`{"enabled":true,"until":"tomorrow 07:00"}`))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&st)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !st.Enabled || got.Until != "tomorrow 07:00" {
+		t.Fatalf("unexpected response %d: %+v (request %+v)", resp.StatusCode, st, got)
+	}
+
+	resp, err = http.Post(r.URL+"/api/dnd", "application/json", strings.NewReader(`{"enabled":true,"until":"soon"}`))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid date, got %d", resp.StatusCode)
+	}
+}
+
+func TestCreateTaskHandler_PartiallyCreated(t *testing.T) {
+	orig := CreateTaskFunc
+	defer func() { CreateTaskFunc = orig }()
+	CreateTaskFunc = func(req CreateTaskRequest) (string, error) {
+		return "uuid-123", fmt.Errorf("%w: annotation failed", ErrPartiallyCreated)
+	}
+	r := httptest.NewServer(NewRouter())
+	defer r.Close()
+	resp, err := http.Post(r.URL+"/api/create-task", "application/json", bytes.NewReader([]byte(`{"description":"x","annotations":["a"]}`)))
+	if err != nil {
+		t.Fatalf("post failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 so the client does not retry, got %d", resp.StatusCode)
+	}
+	var body CreateTaskResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.UUID != "uuid-123" || !strings.Contains(body.Warning, "annotation failed") {
+		t.Fatalf("expected the uuid and a warning, got %+v", body)
+	}
+}